@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"secure-server/backend/pkg/crypto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// messageFlags şifreli mesajın bağlamını (AAD) ve biçimini belirleyen bayraklardır
+type messageFlags struct {
+	keyFlags
+	method string
+	path   string
+	legacy bool
+}
+
+func newFlagSet(name string, mf *messageFlags, defaultMethod string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	mf.register(fs)
+	fs.StringVar(&mf.method, "method", defaultMethod, "isteğin HTTP metodu (AAD)")
+	fs.StringVar(&mf.path, "path", "/api/data", "isteğin yolu, query hariç (AAD)")
+	fs.BoolVar(&mf.legacy, "legacy", false, "sürümsüz eski nonce||ciphertext formatını da çöz")
+	return fs
+}
+
+func (mf *messageFlags) codec() *crypto.Codec {
+	return &crypto.Codec{AcceptLegacyFormat: crypto.LegacyFormatOf(mf.legacy)}
+}
+
+// encryptResult encrypt ve encrypt-query çıktısıdır
+type encryptResult struct {
+	Ciphertext string                 `json:"ciphertext"`
+	KeyID      string                 `json:"key_id"`
+	Method     string                 `json:"method"`
+	Path       string                 `json:"path"`
+	Query      string                 `json:"query,omitempty"`
+	Headers    map[string]string      `json:"headers"`
+	Payload    map[string]interface{} `json:"payload"`
+}
+
+// decryptResult decrypt ve decrypt-query çıktısıdır
+type decryptResult struct {
+	KeyID     string      `json:"key_id"`
+	Direction string      `json:"direction"`
+	Timestamp string      `json:"timestamp,omitempty"`
+	Age       string      `json:"age,omitempty"`
+	Payload   interface{} `json:"payload"`
+}
+
+func runEncrypt(args []string) (interface{}, error) {
+	return encrypt("encrypt", args, false)
+}
+
+func runEncryptQuery(args []string) (interface{}, error) {
+	return encrypt("encrypt-query", args, true)
+}
+
+// encrypt JSON nesnesini istemcinin yaptığı gibi _timestamp ve _nonce
+// ekleyerek şifreler
+func encrypt(name string, args []string, query bool) (interface{}, error) {
+	var mf messageFlags
+	defaultMethod := "POST"
+	if query {
+		defaultMethod = "GET"
+	}
+	fs := newFlagSet(name, &mf, defaultMethod)
+	noReplay := fs.Bool("no-replay-fields", false, "_timestamp ve _nonce ekleme")
+	seq := fs.Uint64("seq", 0, "sıra numarası modundaki sunucular için _seq (0: ekleme)")
+	algName := fs.String("alg", "aes-256-gcm", "el sıkışmada seçilen AEAD algoritması (örn. xchacha20-poly1305)")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	alg, ok := crypto.AlgorithmByName(*algName)
+	if !ok {
+		return nil, fmt.Errorf("bilinmeyen algoritma: %s", *algName)
+	}
+
+	input, err := readInput(fs)
+	if err != nil {
+		return nil, err
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(input), &payload); err != nil || payload == nil {
+		return nil, errors.New("girdi bir JSON nesnesi olmalı")
+	}
+	if !*noReplay {
+		if err := addReplayFields(payload); err != nil {
+			return nil, err
+		}
+	}
+	if *seq > 0 {
+		payload["_seq"] = *seq
+	}
+
+	sk, err := mf.resolve("")
+	if err != nil {
+		return nil, err
+	}
+	sk.Algorithm = alg.ID
+	binding := crypto.RequestBinding(mf.method, mf.path, mf.sessionID)
+
+	result := &encryptResult{
+		KeyID:   sk.ID,
+		Method:  strings.ToUpper(mf.method),
+		Path:    mf.path,
+		Headers: map[string]string{"X-Session-ID": mf.sessionID},
+		Payload: payload,
+	}
+	if query {
+		result.Ciphertext, err = mf.codec().EncryptQueryParams(payload, sk, binding)
+		result.Query = "encrypted=" + result.Ciphertext
+	} else {
+		result.Ciphertext, err = mf.codec().EncryptData(payload, sk, binding)
+		result.Headers["X-Encrypted"] = "true"
+		result.Headers["Content-Type"] = "text/plain"
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func runDecrypt(args []string) (interface{}, error) {
+	return decrypt("decrypt", args, false)
+}
+
+func runDecryptQuery(args []string) (interface{}, error) {
+	return decrypt("decrypt-query", args, true)
+}
+
+// decrypt şifreli gövdeyi, yanıtı veya query parametresini çözer. Yakalanmış
+// eski trafik de çözülebilsin diye replay kontrolü varsayılan olarak kapalıdır.
+func decrypt(name string, args []string, query bool) (interface{}, error) {
+	var mf messageFlags
+	defaultMethod := "POST"
+	if query {
+		defaultMethod = "GET"
+	}
+	fs := newFlagSet(name, &mf, defaultMethod)
+	response := false
+	if !query {
+		fs.BoolVar(&response, "response", false, "sunucu yanıtını çöz (yanıt bağlamı)")
+	}
+	checkReplay := fs.Bool("check-replay", false, "sunucu gibi _timestamp penceresini ve _nonce'u doğrula")
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+
+	input, err := readInput(fs)
+	if err != nil {
+		return nil, err
+	}
+	if query {
+		input = queryValue(input)
+	}
+	data, err := decodeCiphertext(input)
+	if err != nil {
+		return nil, err
+	}
+
+	envelopeKeyID := ""
+	var envelopeAlgorithm byte
+	if env, err := crypto.ParseEnvelope(data); err == nil {
+		envelopeKeyID, envelopeAlgorithm = env.KeyID, env.Algorithm
+	}
+	sk, err := mf.resolve(envelopeKeyID)
+	if err != nil {
+		return nil, err
+	}
+	// Anahtar yalnızca kendi algoritmasıyla çözer; hata ayıklama aracında
+	// el sıkışmada seçilen algoritma zarftan alınır
+	if envelopeAlgorithm != 0 {
+		sk.Algorithm = envelopeAlgorithm
+	}
+
+	result := &decryptResult{KeyID: envelopeKeyID, Direction: string(crypto.DirectionRequest)}
+	binding := crypto.RequestBinding(mf.method, mf.path, mf.sessionID)
+	if response {
+		result.Direction = string(crypto.DirectionResponse)
+		binding = crypto.ResponseBinding(mf.method, mf.path, mf.sessionID)
+	}
+
+	encryptedBase64 := base64.StdEncoding.EncodeToString(data)
+	if *checkReplay && !response {
+		result.Payload, err = mf.codec().DecryptData(encryptedBase64, sk, binding)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		plaintext, err := mf.codec().DecryptResponse(encryptedBase64, sk, binding)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(plaintext, &result.Payload); err != nil {
+			// JSON olmayan yanıtlar metin olarak gösterilir
+			result.Payload = string(plaintext)
+		}
+	}
+
+	if payload, ok := result.Payload.(map[string]interface{}); ok {
+		if ms, ok := payload["_timestamp"].(float64); ok {
+			t := time.UnixMilli(int64(ms))
+			result.Timestamp = t.UTC().Format(time.RFC3339Nano)
+			result.Age = time.Since(t).Round(time.Millisecond).String()
+		}
+	}
+	return result, nil
+}
+
+// deriveKeyResult derive-key çıktısıdır
+type deriveKeyResult struct {
+	Source    string `json:"source"`
+	SessionID string `json:"session_id,omitempty"`
+	KeyID     string `json:"key_id"`
+	Epoch     uint32 `json:"epoch"`
+	Key       string `json:"key"`
+}
+
+// runDeriveKey şifrelemede kullanılacak anahtarı gösterir. Anahtar gizli
+// bilgidir; çıktı yalnızca hata ayıklama ortamında kullanılmalıdır.
+func runDeriveKey(args []string) (interface{}, error) {
+	var kf keyFlags
+	fs := flag.NewFlagSet("derive-key", flag.ContinueOnError)
+	kf.register(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, errors.New("derive-key girdi almaz")
+	}
+
+	sk, err := kf.resolve("")
+	if err != nil {
+		return nil, err
+	}
+	return &deriveKeyResult{
+		Source:    kf.source(),
+		SessionID: kf.sessionID,
+		KeyID:     sk.ID,
+		Epoch:     sk.Epoch,
+		Key:       base64.StdEncoding.EncodeToString(sk.Key),
+	}, nil
+}
+
+// inspectResult inspect çıktısıdır
+type inspectResult struct {
+	Format          string  `json:"format"`
+	Version         byte    `json:"version,omitempty"`
+	Algorithm       string  `json:"algorithm,omitempty"`
+	KeyID           *string `json:"key_id,omitempty"`
+	Epoch           *uint32 `json:"epoch,omitempty"`
+	Nonce           string  `json:"nonce"`
+	Timestamp       string  `json:"timestamp,omitempty"`
+	Sequence        uint64  `json:"sequence,omitempty"`
+	Segments        int     `json:"segments,omitempty"`
+	CiphertextBytes int     `json:"ciphertext_bytes"`
+	TagBytes        int     `json:"tag_bytes"`
+	TotalBytes      int     `json:"total_bytes"`
+}
+
+// gcmTagSize AES-GCM doğrulama etiketinin boyutudur; kayıtlı diğer
+// algoritmaların etiketi de 16 bayttır
+const gcmTagSize = 16
+
+// runInspect zarf başlığını çözmeden gösterir. Gövde, yanıt ("..." JSON string),
+// URL güvenli query değeri ve base64 kodlanmış parçalı akış kabul edilir.
+func runInspect(args []string) (interface{}, error) {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return nil, err
+	}
+
+	input, err := readInput(fs)
+	if err != nil {
+		return nil, err
+	}
+	data, err := decodeCiphertext(queryValue(input))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > 0 && data[0] == crypto.EnvelopeStreamV1 {
+		return inspectStream(data)
+	}
+
+	env, err := crypto.ParseEnvelope(data)
+	if err != nil {
+		// Sürüm baytı bilinen bir zarf bozuksa (örn. bilinmeyen algoritma,
+		// hatalı anahtar kimliği uzunluğu) eski format tahmini yanıltıcı olur
+		if len(data) > 0 && data[0] == crypto.EnvelopeV1 {
+			return nil, fmt.Errorf("v1 zarfı ayrıştırılamadı (nonce'u 0x01 ile başlayan eski format ise decrypt -legacy ile çözün): %w", err)
+		}
+		// Sürüm baytı olmayan eski format: nonce(12) | ciphertext+tag
+		if len(data) < 12+gcmTagSize {
+			return nil, err
+		}
+		return &inspectResult{
+			Format:          "legacy",
+			Algorithm:       algorithmName(crypto.AlgAES256GCM),
+			Nonce:           hex.EncodeToString(data[:12]),
+			CiphertextBytes: len(data) - 12 - gcmTagSize,
+			TagBytes:        gcmTagSize,
+			TotalBytes:      len(data),
+		}, nil
+	}
+
+	result := &inspectResult{
+		Format:          "v1",
+		Version:         env.Version,
+		Algorithm:       algorithmName(env.Algorithm),
+		KeyID:           &env.KeyID,
+		Nonce:           hex.EncodeToString(env.Nonce),
+		CiphertextBytes: len(env.Ciphertext) - gcmTagSize,
+		TagBytes:        gcmTagSize,
+		TotalBytes:      len(data),
+		Epoch:           keyIDEpoch(env.KeyID),
+	}
+	return result, nil
+}
+
+// inspectStream parçalı akışın başlığını ve parça sayısını çözmeden gösterir.
+// Parçalar yalnızca uzunluk alanlarından sayılır.
+func inspectStream(data []byte) (interface{}, error) {
+	r := bytes.NewReader(data)
+	h, err := crypto.ReadStreamHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &inspectResult{
+		Format:     "stream",
+		Version:    h.Version,
+		Algorithm:  algorithmName(h.Algorithm),
+		KeyID:      &h.KeyID,
+		Epoch:      keyIDEpoch(h.KeyID),
+		Nonce:      h.Nonce(),
+		Timestamp:  h.Timestamp.UTC().Format(time.RFC3339Nano),
+		Sequence:   h.Sequence,
+		TotalBytes: len(data),
+	}
+
+	var length [4]byte
+	for r.Len() > 0 {
+		if _, err := io.ReadFull(r, length[:]); err != nil {
+			return nil, fmt.Errorf("%w: akış parça uzunluğu kesilmiş", crypto.ErrBadEncoding)
+		}
+		segLen := int(binary.BigEndian.Uint32(length[:]))
+		if segLen < gcmTagSize || segLen > r.Len() {
+			return nil, fmt.Errorf("%w: %d. akış parçası kesilmiş veya geçersiz", crypto.ErrBadEncoding, result.Segments+1)
+		}
+		r.Seek(int64(segLen), io.SeekCurrent)
+		result.Segments++
+		result.CiphertextBytes += segLen - gcmTagSize
+		result.TagBytes += gcmTagSize
+	}
+	return result, nil
+}
+
+// keyIDEpoch "baseID.epoch" biçimindeki anahtar kimliğinden epoch'u okur
+func keyIDEpoch(keyID string) *uint32 {
+	i := strings.LastIndexByte(keyID, '.')
+	if i < 0 {
+		return nil
+	}
+	epoch, err := strconv.ParseUint(keyID[i+1:], 10, 32)
+	if err != nil {
+		return nil
+	}
+	e := uint32(epoch)
+	return &e
+}
+
+func algorithmName(id byte) string {
+	if alg, err := crypto.LookupAlgorithm(id); err == nil {
+		return alg.Name
+	}
+	return fmt.Sprintf("bilinmeyen (%d)", id)
+}
+
+// queryValue tam URL veya "encrypted=..." verildiyse encrypted parametresini döndürür
+func queryValue(input string) string {
+	rawQuery := input
+	if _, after, ok := strings.Cut(input, "?"); ok {
+		rawQuery = after
+	}
+	if !strings.Contains(rawQuery, "encrypted=") {
+		return input
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil || values.Get("encrypted") == "" {
+		return input
+	}
+	return values.Get("encrypted")
+}
+
+// decodeCiphertext standart veya URL güvenli base64'ü çözer. Yanıt gövdesi
+// gibi JSON string olarak verilen değerin tırnakları kaldırılır.
+func decodeCiphertext(input string) ([]byte, error) {
+	if strings.HasPrefix(input, `"`) {
+		var s string
+		if err := json.Unmarshal([]byte(input), &s); err != nil {
+			return nil, fmt.Errorf("%w: JSON string çözülemedi", crypto.ErrBadEncoding)
+		}
+		input = s
+	}
+
+	if data, err := base64.StdEncoding.DecodeString(input); err == nil {
+		return data, nil
+	}
+	if data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(input, "=")); err == nil {
+		return data, nil
+	}
+	return nil, fmt.Errorf("%w: base64 decode başarısız", crypto.ErrBadEncoding)
+}
+
+// addReplayFields istemci gibi _timestamp (ms) ve _nonce ekler; girdide
+// zaten varsa korunur
+func addReplayFields(payload map[string]interface{}) error {
+	if _, ok := payload["_timestamp"]; !ok {
+		payload["_timestamp"] = time.Now().UnixMilli()
+	}
+	if _, ok := payload["_nonce"]; !ok {
+		nonce := make([]byte, 8)
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("nonce oluşturma hatası: %w", err)
+		}
+		payload["_nonce"] = hex.EncodeToString(nonce)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"secure-server/backend/pkg/crypto"
+	"strconv"
+	"strings"
+)
+
+// Ortam değişkenleri (bayrak verilmezse kullanılır)
+const (
+	envToken     = "UCTANUCA_TOKEN"
+	envSessionID = "UCTANUCA_SESSION_ID"
+	envKey       = "UCTANUCA_KEY"
+	envKeyID     = "UCTANUCA_KEY_ID"
+	envSecret    = "UCTANUCA_SECRET"
+)
+
+// keyFlags anahtarın nereden geldiğini belirleyen bayraklardır. Öncelik sırası:
+//
+//  1. -key: hazır 32 byte anahtar (base64), zarfa -key-id yazılır
+//  2. -secret: el sıkışma oturum sırrı (base64); epoch anahtarı -key-id
+//     (temel kimlik) ve -epoch ile türetilir
+//  3. -token ve -session: eski istemcilerin token'dan türettiği anahtar
+type keyFlags struct {
+	token     string
+	sessionID string
+	key       string
+	keyID     string
+	secret    string
+	epoch     uint
+}
+
+func (k *keyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&k.token, "token", os.Getenv(envToken), "JWT token ($"+envToken+")")
+	fs.StringVar(&k.sessionID, "session", os.Getenv(envSessionID), "oturum ID'si ($"+envSessionID+")")
+	fs.StringVar(&k.key, "key", os.Getenv(envKey), "base64 32 byte anahtar ($"+envKey+")")
+	fs.StringVar(&k.keyID, "key-id", os.Getenv(envKeyID), "zarftaki anahtar kimliği; -secret ile temel kimlik ($"+envKeyID+")")
+	fs.StringVar(&k.secret, "secret", os.Getenv(envSecret), "base64 el sıkışma oturum sırrı ($"+envSecret+")")
+	fs.UintVar(&k.epoch, "epoch", 0, "-secret ile kullanılacak anahtar epoch'u")
+}
+
+// source anahtarın kaynağını döndürür
+func (k *keyFlags) source() string {
+	switch {
+	case k.key != "":
+		return "key"
+	case k.secret != "":
+		return "secret"
+	default:
+		return "token"
+	}
+}
+
+// resolve bayraklardan oturum anahtarını oluşturur. envelopeKeyID boş değilse
+// (çözme) ve anahtar oturum sırrından türetiliyorsa epoch zarftaki kimlikten alınır.
+func (k *keyFlags) resolve(envelopeKeyID string) (*crypto.SessionKey, error) {
+	switch k.source() {
+	case "key":
+		key, err := base64.StdEncoding.DecodeString(k.key)
+		if err != nil || len(key) != 32 {
+			return nil, errors.New("-key base64 kodlu 32 byte olmalı")
+		}
+		return &crypto.SessionKey{ID: k.keyID, Key: key}, nil
+
+	case "secret":
+		if k.sessionID == "" || k.keyID == "" {
+			return nil, errors.New("-secret ile -session ve -key-id gerekli")
+		}
+		secret, err := base64.StdEncoding.DecodeString(k.secret)
+		if err != nil || len(secret) == 0 {
+			return nil, errors.New("-secret base64 kodlu olmalı")
+		}
+
+		epoch := uint32(k.epoch)
+		if envelopeKeyID != "" {
+			if epoch, err = parseEpoch(k.keyID, envelopeKeyID); err != nil {
+				return nil, err
+			}
+		}
+		key, err := crypto.DeriveEpochKey(secret, k.sessionID, epoch)
+		if err != nil {
+			return nil, err
+		}
+		return &crypto.SessionKey{ID: crypto.EpochKeyID(k.keyID, epoch), Key: key, Epoch: epoch}, nil
+
+	default:
+		key, err := crypto.DeriveKeys(k.token, k.sessionID)
+		if err != nil {
+			return nil, err
+		}
+		// Eski istemcilerin anahtar kimliği yoktur
+		return &crypto.SessionKey{Key: key}, nil
+	}
+}
+
+// parseEpoch "baseID.epoch" biçimindeki zarf kimliğinden epoch'u ayrıştırır
+func parseEpoch(baseID, keyID string) (uint32, error) {
+	rawEpoch, ok := strings.CutPrefix(keyID, baseID+".")
+	if !ok {
+		return 0, fmt.Errorf("zarftaki anahtar kimliği (%s) -key-id ile eşleşmiyor", keyID)
+	}
+	epoch, err := strconv.ParseUint(rawEpoch, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("zarftaki anahtar kimliğinde geçersiz epoch: %s", keyID)
+	}
+	return uint32(epoch), nil
+}
@@ -0,0 +1,132 @@
+// Command uctanuca şifreli API trafiğini elle üretmek ve çözmek için bir hata
+// ayıklama aracıdır. pkg/crypto fonksiyonlarını kullanır; böylece destek
+// ekipleri istemcinin gönderdiği gövde ve query parametrelerini birebir
+// yeniden üretebilir.
+//
+//	uctanuca encrypt       -token T -session S -path /api/data '{"action":"x"}'
+//	uctanuca decrypt       -token T -session S -path /api/data <base64>
+//	uctanuca encrypt-query -token T -session S -method GET -path /api/data '{"page":1}'
+//	uctanuca decrypt-query -token T -session S -method GET -path /api/data <urlsafe>
+//	uctanuca derive-key    -token T -session S
+//	uctanuca inspect       <base64>
+//
+// Token, oturum ve anahtar bayrakları verilmezse UCTANUCA_TOKEN,
+// UCTANUCA_SESSION_ID, UCTANUCA_KEY, UCTANUCA_KEY_ID ve UCTANUCA_SECRET ortam
+// değişkenlerinden okunur. Çıktı her zaman JSON'dur.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// command bir alt komuttur
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) (interface{}, error)
+}
+
+var commands = []command{
+	{"encrypt", "JSON gövdeyi şifreler (X-Encrypted: true istek gövdesi)", runEncrypt},
+	{"decrypt", "base64 şifreli gövdeyi veya yanıtı çözer", runDecrypt},
+	{"encrypt-query", "query parametrelerini şifreler (?encrypted=...)", runEncryptQuery},
+	{"decrypt-query", "URL güvenli base64 query parametresini çözer", runDecryptQuery},
+	{"derive-key", "oturum anahtarını türetir ve gösterir", runDeriveKey},
+	{"inspect", "zarf başlığını anahtar olmadan gösterir", runInspect},
+}
+
+// errUsage bayraklar hatalıysa döner; kullanım bilgisi zaten yazılmıştır
+var errUsage = errors.New("kullanım hatası")
+
+func main() {
+	if len(os.Args) < 2 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(os.Stdout)
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		result, err := cmd.run(os.Args[2:])
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		if err != nil {
+			writeJSON(os.Stderr, map[string]string{"error": err.Error()})
+			os.Exit(1)
+		}
+		writeJSON(os.Stdout, result)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "bilinmeyen komut: %s\n\n", name)
+	usage(os.Stderr)
+	os.Exit(2)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Kullanım: uctanuca <komut> [bayraklar] [girdi]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Komutlar:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Girdi argüman olarak verilmezse standart girdiden okunur.")
+	fmt.Fprintln(w, "Komut bayrakları için: uctanuca <komut> -h")
+}
+
+func writeJSON(w io.Writer, v interface{}) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+}
+
+// readInput girdiyi ilk argümandan, yoksa standart girdiden okur
+func readInput(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() > 1 {
+		return "", fmt.Errorf("tek girdi bekleniyordu, %d verildi", fs.NArg())
+	}
+	if fs.NArg() == 1 {
+		return strings.TrimSpace(fs.Arg(0)), nil
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("standart girdi okunamadı: %w", err)
+	}
+	input := strings.TrimSpace(string(data))
+	if input == "" {
+		return "", errors.New("girdi boş")
+	}
+	return input, nil
+}
+
+// parseFlags bayrakları ayrıştırır; hata durumunda flag paketi kullanımı yazmıştır
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	return nil
+}
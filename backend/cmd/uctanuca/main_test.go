@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"secure-server/backend/pkg/crypto"
+	"strings"
+	"testing"
+)
+
+// vectors pkg/crypto'nun paylaşılan test vektörlerinden kullanılan kısımdır
+type vectors struct {
+	KeyDerivation []struct {
+		Token     string `json:"token"`
+		SessionID string `json:"session_id"`
+		Key       string `json:"key"`
+	} `json:"key_derivation"`
+	Handshake []struct {
+		SessionID string `json:"session_id"`
+		Secret    string `json:"secret"`
+		KeyID     string `json:"key_id"`
+		Epochs    []struct {
+			Epoch uint32 `json:"epoch"`
+			KeyID string `json:"key_id"`
+			Key   string `json:"key"`
+		} `json:"epochs"`
+	} `json:"handshake"`
+	Envelopes []struct {
+		Name      string `json:"name"`
+		Format    string `json:"format"`
+		Key       string `json:"key"`
+		KeyID     string `json:"key_id"`
+		Nonce     string `json:"nonce"`
+		Direction string `json:"direction"`
+		Method    string `json:"method"`
+		Path      string `json:"path"`
+		SessionID string `json:"session_id"`
+		Plaintext string `json:"plaintext"`
+		Envelope  string `json:"envelope"`
+		Query     string `json:"query"`
+	} `json:"envelopes"`
+}
+
+func loadVectors(t *testing.T) vectors {
+	t.Helper()
+	data, err := os.ReadFile("../../pkg/crypto/testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v vectors
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+// run komutu çalıştırır ve stdout'a yazılacak JSON çıktıyı çözülmüş olarak
+// döndürür. Ortamdaki UCTANUCA_* değişkenleri test süresince boşaltılır.
+func run(t *testing.T, name string, args ...string) map[string]interface{} {
+	t.Helper()
+	out, err := runErr(t, name, args...)
+	if err != nil {
+		t.Fatalf("%s %v: %v", name, args, err)
+	}
+	return out
+}
+
+func runErr(t *testing.T, name string, args ...string) (map[string]interface{}, error) {
+	t.Helper()
+	for _, env := range []string{envToken, envSessionID, envKey, envKeyID, envSecret} {
+		t.Setenv(env, "")
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		result, err := cmd.run(args)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		writeJSON(&buf, result)
+		var out map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+			t.Fatalf("%s çıktısı JSON nesnesi değil: %s", name, buf.Bytes())
+		}
+		return out, nil
+	}
+	t.Fatalf("bilinmeyen komut: %s", name)
+	return nil, nil
+}
+
+// requireFields çıktıdaki alanları beklenen değerlerle karşılaştırır
+func requireFields(t *testing.T, out map[string]interface{}, want map[string]interface{}) {
+	t.Helper()
+	for field, v := range want {
+		got, _ := json.Marshal(out[field])
+		expected, _ := json.Marshal(v)
+		if !bytes.Equal(got, expected) {
+			t.Errorf("%s = %s, beklenen %s (%v)", field, got, expected, out)
+		}
+	}
+}
+
+func b64(t *testing.T, hexValue string) string {
+	t.Helper()
+	data, err := hex.DecodeString(hexValue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+const (
+	testToken   = "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJjbGkifQ.c2ln"
+	testSession = "cli-session"
+	testKey     = "MTExMTExMTExMTExMTExMTExMTExMTExMTExMTExMTE=" // 32 x "1"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	cases := []struct {
+		name    string
+		keyArgs []string
+		keyID   string
+	}{
+		{"token", []string{"-token", testToken, "-session", testSession}, ""},
+		{"key", []string{"-key", testKey, "-key-id", "abc.0", "-session", testSession}, "abc.0"},
+		{"secret", []string{"-secret", testKey, "-key-id", "abc", "-epoch", "2", "-session", testSession}, "abc.2"},
+	}
+	payload := `{"action":"create","user":{"name":"Ayşe"}}`
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := append([]string{"-path", "/api/data"}, tc.keyArgs...)
+			enc := run(t, "encrypt", append(args, payload)...)
+			requireFields(t, enc, map[string]interface{}{
+				"key_id":  tc.keyID,
+				"method":  "POST",
+				"path":    "/api/data",
+				"headers": map[string]string{"X-Session-ID": testSession, "X-Encrypted": "true", "Content-Type": "text/plain"},
+			})
+
+			// Sunucu gibi replay kontrolüyle çözülür; bağlam farklıysa çözülmez
+			dec := run(t, "decrypt", append(args, "-check-replay", enc["ciphertext"].(string))...)
+			requireFields(t, dec, map[string]interface{}{"key_id": tc.keyID, "direction": "request"})
+			got := dec["payload"].(map[string]interface{})
+			if got["action"] != "create" || got["_nonce"] == nil || dec["timestamp"] == nil {
+				t.Fatalf("çözülen gövde: %v", dec)
+			}
+			if _, err := runErr(t, "decrypt", append(args, "-method", "PUT", enc["ciphertext"].(string))...); err == nil {
+				t.Fatal("başka metoda bağlı gövde çözüldü")
+			}
+
+			// Query biçimi URL güvenli base64'tür ve tam URL olarak da verilebilir
+			qargs := append([]string{"-path", "/api/data"}, tc.keyArgs...)
+			encQuery := run(t, "encrypt-query", append(qargs, "-seq", "7", `{"page":1}`)...)
+			requireFields(t, encQuery, map[string]interface{}{"key_id": tc.keyID, "method": "GET", "query": "encrypted=" + encQuery["ciphertext"].(string)})
+			if strings.ContainsAny(encQuery["ciphertext"].(string), "+/=") {
+				t.Fatalf("query URL güvenli değil: %s", encQuery["ciphertext"])
+			}
+
+			decQuery := run(t, "decrypt-query", append(qargs, "https://api.example/api/data?"+encQuery["query"].(string))...)
+			requireFields(t, decQuery, map[string]interface{}{"key_id": tc.keyID, "direction": "request"})
+			params := decQuery["payload"].(map[string]interface{})
+			if params["page"] != float64(1) || params["_seq"] != float64(7) {
+				t.Fatalf("çözülen query: %v", decQuery)
+			}
+		})
+	}
+}
+
+// TestDecryptVectors paylaşılan zarf vektörlerinin CLI ile çözüldüğünü doğrular
+func TestDecryptVectors(t *testing.T) {
+	for _, v := range loadVectors(t).Envelopes {
+		t.Run(v.Name, func(t *testing.T) {
+			args := []string{"-key", b64(t, v.Key), "-key-id", v.KeyID, "-session", v.SessionID, "-method", v.Method, "-path", v.Path}
+			if v.Direction == "response" {
+				args = append(args, "-response")
+			}
+			if v.Format == "legacy" {
+				args = append(args, "-legacy")
+			}
+
+			var want interface{}
+			if err := json.Unmarshal([]byte(v.Plaintext), &want); err != nil {
+				t.Fatal(err)
+			}
+			for _, input := range []string{v.Envelope, `"` + v.Envelope + `"`} {
+				out := run(t, "decrypt", append(args, input)...)
+				requireFields(t, out, map[string]interface{}{"direction": v.Direction, "payload": want})
+			}
+			if v.Direction == "request" {
+				out := run(t, "decrypt-query", append(args, "encrypted="+v.Query)...)
+				requireFields(t, out, map[string]interface{}{"payload": want})
+			}
+		})
+	}
+}
+
+func TestDeriveKeyVectors(t *testing.T) {
+	v := loadVectors(t)
+
+	for _, kd := range v.KeyDerivation {
+		out := run(t, "derive-key", "-token", kd.Token, "-session", kd.SessionID)
+		requireFields(t, out, map[string]interface{}{"source": "token", "session_id": kd.SessionID, "key_id": "", "epoch": 0, "key": b64(t, kd.Key)})
+	}
+
+	for _, hs := range v.Handshake {
+		for _, e := range hs.Epochs {
+			out := run(t, "derive-key", "-secret", b64(t, hs.Secret), "-session", hs.SessionID, "-key-id", hs.KeyID, "-epoch", fmt.Sprint(e.Epoch))
+			requireFields(t, out, map[string]interface{}{"source": "secret", "key_id": e.KeyID, "epoch": e.Epoch, "key": b64(t, e.Key)})
+		}
+	}
+
+	if _, err := runErr(t, "derive-key", "-secret", testKey, "-session", testSession); err == nil {
+		t.Fatal("-key-id olmadan sırdan anahtar türetildi")
+	}
+}
+
+// streamInput base64 kodlanmış, iki parçalı bir şifreli akış üretir
+func streamInput(t *testing.T, seq uint64) string {
+	t.Helper()
+	key, _ := base64.StdEncoding.DecodeString(testKey)
+	sk := &crypto.SessionKey{ID: "abc.3", Key: key, Algorithm: crypto.AlgXChaCha20Poly1305}
+
+	var buf bytes.Buffer
+	w, err := crypto.NewSequencedStreamWriter(&buf, sk, crypto.RequestBinding("POST", "/api/upload", testSession), 16, seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(strings.Repeat("x", 20))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestInspect(t *testing.T) {
+	v := loadVectors(t)
+	envelope := func(format string) (envelope, keyID, nonce string) {
+		for _, e := range v.Envelopes {
+			if e.Format == format && (format != "v1" || e.KeyID != "") {
+				return e.Envelope, e.KeyID, e.Nonce
+			}
+		}
+		t.Fatalf("%s vektörü yok", format)
+		return
+	}
+
+	v1, keyID, nonce := envelope("v1")
+	epoch := keyIDEpoch(keyID)
+	want := map[string]interface{}{"format": "v1", "version": 1, "algorithm": "aes-256-gcm", "key_id": keyID, "nonce": nonce, "tag_bytes": 16, "epoch": epoch}
+	requireFields(t, run(t, "inspect", v1), want)
+	// Query biçimi de aynı başlığı gösterir
+	requireFields(t, run(t, "inspect", "encrypted="+strings.TrimRight(strings.NewReplacer("+", "-", "/", "_").Replace(v1), "=")), want)
+
+	legacy, _, legacyNonce := envelope("legacy")
+	out := run(t, "inspect", legacy)
+	requireFields(t, out, map[string]interface{}{"format": "legacy", "algorithm": "aes-256-gcm", "nonce": legacyNonce, "tag_bytes": 16})
+	if _, ok := out["key_id"]; ok {
+		t.Fatalf("eski formatta anahtar kimliği gösterildi: %v", out)
+	}
+
+	stream := streamInput(t, 9)
+	raw, _ := base64.StdEncoding.DecodeString(stream)
+	requireFields(t, run(t, "inspect", stream), map[string]interface{}{
+		"format": "stream", "version": 2, "algorithm": "xchacha20-poly1305", "key_id": "abc.3", "epoch": 3,
+		"sequence": 9, "segments": 2, "ciphertext_bytes": 20, "tag_bytes": 32, "total_bytes": len(raw),
+	})
+	if _, err := runErr(t, "inspect", base64.StdEncoding.EncodeToString(raw[:len(raw)-1])); err == nil {
+		t.Fatal("kesilmiş akış gösterildi")
+	}
+}
+
+// TestInspectBrokenV1 sürüm baytı v1 olan bozuk zarfın eski format sanılmadığını doğrular
+func TestInspectBrokenV1(t *testing.T) {
+	cases := map[string][]byte{
+		"bilinmeyen algoritma":        append([]byte{crypto.EnvelopeV1, 0x7f, 0}, make([]byte, 40)...),
+		"anahtar kimliği uzunluğu":    append([]byte{crypto.EnvelopeV1, crypto.AlgAES256GCM, 200}, make([]byte, 40)...),
+		"bilinmeyen akış algoritması": append([]byte{crypto.EnvelopeStreamV1, 0x7f, 0}, make([]byte, 40)...),
+	}
+	for name, data := range cases {
+		if out, err := runErr(t, "inspect", base64.StdEncoding.EncodeToString(data)); err == nil {
+			t.Errorf("%s: hata beklenirken %v", name, out)
+		}
+	}
+
+	// Bilinen bir sürüm baytıyla başlamayan girdi eski format olarak gösterilir
+	legacy := append([]byte{0x7f}, make([]byte, 40)...)
+	requireFields(t, run(t, "inspect", base64.StdEncoding.EncodeToString(legacy)), map[string]interface{}{"format": "legacy", "ciphertext_bytes": 13})
+}
@@ -2,112 +2,152 @@ package main
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"secure-server/backend/middleware"
+	"secure-server/backend/pkg/auth"
+	"secure-server/backend/pkg/crypto"
+	"secure-server/backend/pkg/session"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Demo istek tipleri. Middleware'ın çözdüğü veri BindDecrypted ile bu
+// tiplere dönüştürülür ve `binding` etiketleriyle doğrulanır.
+type userInfo struct {
+	Name  string `json:"name" binding:"required"`
+	Email string `json:"email" binding:"omitempty,email"`
+	Age   int    `json:"age" binding:"omitempty,gte=0"`
+}
+
+type createRequest struct {
+	Action string   `json:"action" binding:"required"`
+	User   userInfo `json:"user" binding:"required"`
+}
+
+type searchQuery struct {
+	Search   string `json:"search"`
+	Category string `json:"category"`
+	Page     int    `json:"page" binding:"omitempty,gte=1"`
+	Limit    int    `json:"limit" binding:"omitempty,gte=1,lte=100"`
+	Sort     string `json:"sort"`
+}
+
+type updateRequest struct {
+	Action  string            `json:"action"`
+	ID      string            `json:"id" binding:"required"`
+	User    userInfo          `json:"user" binding:"required"`
+	Changes map[string]string `json:"changes"`
+}
+
+type patchRequest struct {
+	Action  string                 `json:"action"`
+	ID      string                 `json:"id" binding:"required"`
+	Updates map[string]interface{} `json:"updates" binding:"required"`
+}
+
+type deleteRequest struct {
+	Action    string `json:"action"`
+	ID        string `json:"id" binding:"required"`
+	Reason    string `json:"reason"`
+	Confirmed bool   `json:"confirmed"`
+}
+
 // Demo Handler'lar
 func handlePost(c *gin.Context) {
-	// Middleware sayesinde body zaten çözülmüş ve c.Request.Body'ye yerleştirilmiştir.
-	var receivedData map[string]interface{}
-	if err := c.ShouldBindJSON(&receivedData); err != nil {
+	// Middleware sayesinde body zaten çözülmüş ve context'e yerleştirilmiştir.
+	req, err := middleware.BindDecrypted[createRequest](c)
+	if err != nil {
 		// Şifre çözme middleware'da başarısız olursa buraya gelmez
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz JSON formatı"})
 		return
 	}
 
-	// Ek güvenlik kontrolü: Eğer body'yi middleware'dan almak isteniyorsa
-	decryptedBody, _ := middleware.GetDecryptedBody(c)
-
-	fmt.Printf("POST /data: Çözülmüş Veri: %+v\n", decryptedBody)
+	fmt.Printf("POST /data: Çözülmüş Veri: %+v\n", req)
 
 	// Şifrelenmiş yanıt dönecek
 	c.JSON(http.StatusOK, gin.H{
 		"message":               "Veri başarıyla alındı ve işlendi.",
-		"received_data_summary": fmt.Sprintf("Kullanıcı Adı: %s", decryptedBody["user"].(map[string]interface{})["name"]),
-		"action":                decryptedBody["action"],
+		"received_data_summary": fmt.Sprintf("Kullanıcı Adı: %s", req.User.Name),
+		"action":                req.Action,
 	})
 }
 
 func handleGet(c *gin.Context) {
 	// Middleware query parametrelerini çözmüş ve context'e koymuştur
-	decryptedParams, ok := middleware.GetDecryptedQueryParams(c)
-	if !ok {
+	query, err := middleware.BindDecryptedQuery[searchQuery](c)
+	if errors.Is(err, middleware.ErrNoDecryptedData) {
 		// Query parametresi bekleniyorsa ve yoksa
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Şifreli query parametresi bekleniyor"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz query parametreleri"})
+		return
+	}
 
-	fmt.Printf("GET /data: Çözülmüş Query Parametreleri: %+v\n", decryptedParams)
+	fmt.Printf("GET /data: Çözülmüş Query Parametreleri: %+v\n", query)
 
 	// Şifrelenmiş yanıt dönecek
 	c.JSON(http.StatusOK, gin.H{
 		"message":       "Sorgu başarıyla işlendi.",
 		"results_count": 42,
-		"search_term":   decryptedParams["search"],
-		"category":      decryptedParams["category"],
+		"search_term":   query.Search,
+		"category":      query.Category,
 	})
 }
 
 func handlePut(c *gin.Context) {
-	var receivedData map[string]interface{}
-	if err := c.ShouldBindJSON(&receivedData); err != nil {
+	req, err := middleware.BindDecrypted[updateRequest](c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz JSON formatı"})
 		return
 	}
 
-	decryptedBody, _ := middleware.GetDecryptedBody(c)
-
-	resourceID := decryptedBody["id"]
-	fmt.Printf("PUT /data/%s: Çözülmüş Veri: %+v\n", resourceID, decryptedBody)
+	fmt.Printf("PUT /data/%s: Çözülmüş Veri: %+v\n", req.ID, req)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "Kaynak başarıyla güncellendi (PUT).",
-		"resource_id": resourceID,
-		"updated_by":  decryptedBody["user"].(map[string]interface{})["email"],
+		"resource_id": req.ID,
+		"updated_by":  req.User.Email,
 	})
 }
 
 func handlePatch(c *gin.Context) {
-	var receivedData map[string]interface{}
-	if err := c.ShouldBindJSON(&receivedData); err != nil {
+	req, err := middleware.BindDecrypted[patchRequest](c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz JSON formatı"})
 		return
 	}
 
-	decryptedBody, _ := middleware.GetDecryptedBody(c)
-
-	resourceID := decryptedBody["id"]
-	fmt.Printf("PATCH /data/%s: Çözülmüş Veri: %+v\n", resourceID, decryptedBody)
+	fmt.Printf("PATCH /data/%s: Çözülmüş Veri: %+v\n", req.ID, req)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":         "Kaynak kısmen güncellendi (PATCH).",
-		"resource_id":     resourceID,
-		"updates_applied": decryptedBody["updates"],
+		"resource_id":     req.ID,
+		"updates_applied": req.Updates,
 	})
 }
 
 func handleDelete(c *gin.Context) {
 	// DELETE body'si de şifrelenmiştir ve buraya çözülmüş olarak gelir
-	var receivedData map[string]interface{}
-	if err := c.ShouldBindJSON(&receivedData); err != nil {
+	req, err := middleware.BindDecrypted[deleteRequest](c)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz JSON formatı"})
 		return
 	}
 
-	decryptedBody, _ := middleware.GetDecryptedBody(c)
-
-	resourceID := decryptedBody["id"]
-	fmt.Printf("DELETE /data/%s: Çözülmüş Veri: %+v\n", resourceID, decryptedBody)
+	fmt.Printf("DELETE /data/%s: Çözülmüş Veri: %+v\n", req.ID, req)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "Kaynak başarıyla silindi.",
-		"resource_id": resourceID,
-		"reason":      decryptedBody["reason"],
+		"resource_id": req.ID,
+		"reason":      req.Reason,
 	})
 }
 
@@ -120,6 +160,11 @@ func handleOptions(c *gin.Context) {
 	})
 }
 
+func handleHealth(c *gin.Context) {
+	// Sağlık kontrolü şifrelemesiz izin listesindedir; hassas veri döndürmemelidir
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
 // fileExists helper fonksiyonu (Sertifika kontrolü için)
 func fileExists(filename string) bool {
 	info, err := os.Stat(filename)
@@ -129,6 +174,50 @@ func fileExists(filename string) bool {
 	return !info.IsDir()
 }
 
+// envInt tamsayı ortam değişkenini okur; tanımsız veya geçersizse def döner
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// envDuration süre ortam değişkenini (örn. "30m") okur; tanımsız veya geçersizse def döner
+func envDuration(name string, def time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// buildTokenVerifier JWT doğrulayıcısını ortam değişkenlerinden oluşturur.
+// Anahtar kaynağı önceliği: JWT_JWKS_FILE, JWT_JWKS_URL, JWT_HS256_SECRET.
+func buildTokenVerifier() (auth.Verifier, error) {
+	var keys auth.KeyProvider
+
+	switch {
+	case os.Getenv("JWT_JWKS_FILE") != "":
+		ks, err := auth.LoadJWKSFile(os.Getenv("JWT_JWKS_FILE"))
+		if err != nil {
+			return nil, err
+		}
+		keys = ks
+	case os.Getenv("JWT_JWKS_URL") != "":
+		// Yerel JWKS sunucusu (veya test amaçlı HTTP stand-in)
+		keys = auth.NewRemoteKeySet(os.Getenv("JWT_JWKS_URL"), 15*time.Minute)
+	case os.Getenv("JWT_HS256_SECRET") != "":
+		keys = auth.NewHMACKeySet([]byte(os.Getenv("JWT_HS256_SECRET")))
+	default:
+		return nil, fmt.Errorf("JWT anahtar kaynağı tanımlı değil (JWT_JWKS_FILE, JWT_JWKS_URL veya JWT_HS256_SECRET)")
+	}
+
+	return auth.NewJWTVerifier(keys, auth.VerifierConfig{
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+		Leeway:   30 * time.Second,
+	}), nil
+}
+
 // corsMiddleware tüm CORS başlıklarını ayarlar
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -140,7 +229,7 @@ func corsMiddleware() gin.HandlerFunc {
 		// X-Session-ID, X-Encrypted gibi özel başlıklar eklenmeli
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Session-ID, X-Encrypted")
 		// İstemcinin okuyabilmesi için özel başlıkları ifşa et
-		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Encrypted")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Encrypted, X-Request-ID, X-Key-Next-Epoch, X-Signature, X-Signature-Key-ID, X-Server-Time")
 		c.Writer.Header().Set("Access-Control-Max-Age", "86400") // 24 saat
 
 		if c.Request.Method == "OPTIONS" {
@@ -153,15 +242,55 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
-func main() {
-	// Gin modunu release olarak ayarlayın
-	gin.SetMode(gin.ReleaseMode)
+// plaintextRoutes şifrelemesiz çalışan rotalardır: oturum açma, el sıkışma,
+// sağlık kontrolü, imza anahtarları ve sunucu saati
+var plaintextRoutes = []string{"POST /api/session", "POST /api/handshake", "GET /api/health", "GET /api/signing-keys", "GET /api/time"}
+
+// newRouter API rotalarını verilen şifreleme politikasıyla kurar
+func newRouter(encryptionOptions ...middleware.Option) *gin.Engine {
 	router := gin.New()
 
 	// Recovery ve Logger'ı ekle
 	router.Use(gin.Recovery())
 	// router.Use(gin.Logger()) // Production'da loglama ayarlarını kontrol edin
 
+	apiGroup := router.Group("/api")
+	{
+		apiGroup.Use(corsMiddleware())                                      // CORS (Preflight dahil)
+		apiGroup.Use(middleware.EncryptionMiddleware(encryptionOptions...)) // Uçtan uca şifreleme
+
+		apiGroup.GET("/health", handleHealth)
+		apiGroup.GET("/signing-keys", middleware.SigningKeysHandler(encryptionOptions...))
+		apiGroup.GET("/time", middleware.TimeHandler(encryptionOptions...))
+		apiGroup.POST("/session", middleware.SessionCreateHandler(encryptionOptions...))
+		apiGroup.POST("/session/refresh", middleware.SessionRefreshHandler(encryptionOptions...))
+		apiGroup.POST("/session/revoke", middleware.SessionRevokeHandler(encryptionOptions...))
+		apiGroup.POST("/handshake", middleware.HandshakeHandler(encryptionOptions...))
+		// Preflight istekleri corsMiddleware'da yanıtlanır
+		apiGroup.OPTIONS("/session", func(c *gin.Context) {})
+		apiGroup.OPTIONS("/session/refresh", func(c *gin.Context) {})
+		apiGroup.OPTIONS("/session/revoke", func(c *gin.Context) {})
+		apiGroup.OPTIONS("/handshake", func(c *gin.Context) {})
+		apiGroup.OPTIONS("/logout", func(c *gin.Context) {})
+
+		// Çıkış oturumu iptal eder: kayıttan düşer ve anahtarları silinir
+		apiGroup.POST("/logout", middleware.SessionRevokeHandler(encryptionOptions...))
+
+		apiGroup.POST("/data", handlePost)
+		apiGroup.GET("/data", handleGet)
+		apiGroup.PUT("/data", handlePut)
+		apiGroup.PATCH("/data", handlePatch)
+		apiGroup.DELETE("/data", handleDelete)
+		apiGroup.OPTIONS("/data", handleOptions) // OPTIONS isteği, middleware'da özel olarak ele alınır
+	}
+
+	return router
+}
+
+func main() {
+	// Gin modunu release olarak ayarlayın
+	gin.SetMode(gin.ReleaseMode)
+
 	// Gerekli dosyaları kontrol et
 	certFile := "server.crt"
 	keyFile := "server.key"
@@ -173,19 +302,97 @@ func main() {
 		// return
 	}
 
-	apiGroup := router.Group("/api")
-	{
-		apiGroup.Use(corsMiddleware())                  // CORS (Preflight dahil)
-		apiGroup.Use(middleware.EncryptionMiddleware()) // Uçtan uca şifreleme
+	// JWT doğrulayıcısı: yapılandırılmazsa kimlikli istekler reddedilir
+	verifier, err := buildTokenVerifier()
+	if err != nil {
+		fmt.Printf("!!! UYARI: JWT doğrulayıcı oluşturulamadı: %v\n", err)
+		fmt.Println("!!! Authorization başlığı taşıyan tüm istekler 401 ile reddedilecek.")
+	} else {
+		middleware.SetTokenVerifier(verifier)
+	}
 
-		apiGroup.POST("/data", handlePost)
-		apiGroup.GET("/data", handleGet)
-		apiGroup.PUT("/data", handlePut)
-		apiGroup.PATCH("/data", handlePatch)
-		apiGroup.DELETE("/data", handleDelete)
-		apiGroup.OPTIONS("/data", handleOptions) // OPTIONS isteği, middleware'da özel olarak ele alınır
+	// Sürümsüz (eski) şifreli veri formatı yalnızca açıkça istenirse kabul edilir
+	if os.Getenv("ACCEPT_LEGACY_CIPHERTEXT") == "true" {
+		crypto.SetAcceptLegacyFormat(true)
 	}
 
+	// Türetilmiş anahtar önbelleği: LRU, süresi dolan kayıtlar arka planda temizlenir
+	keyLifetime := envDuration("KEY_LIFETIME", crypto.DefaultKeyLifetime)
+	keyCache := crypto.NewLRUKeyCache(envInt("KEY_CACHE_SIZE", 1000), keyLifetime)
+	stopSweeper := keyCache.StartSweeper(time.Minute)
+	defer stopSweeper()
+	crypto.SetKeyCache(keyCache)
+
+	// El sıkışma anahtarları: oturum başına bir anahtar, kullanıcı başına sınırlı
+	crypto.SetSessionKeyStore(crypto.NewSessionKeyStore(envInt("MAX_SESSIONS", 100000), envInt("MAX_KEYS_PER_SUBJECT", crypto.DefaultMaxKeysPerSubject)))
+
+	// Sunucu tarafı oturumlar: X-Session-ID yalnızca /api/session ile alınabilir
+	sessions := session.NewRegistry(
+		envDuration("SESSION_IDLE_TIMEOUT", session.DefaultIdleTimeout),
+		envDuration("SESSION_ABSOLUTE_TIMEOUT", session.DefaultAbsoluteTimeout),
+		envInt("MAX_SESSIONS", 100000),
+		envInt("MAX_SESSIONS_PER_SUBJECT", session.DefaultMaxSessionsPerSubject),
+	)
+	stopSessionSweeper := sessions.StartSweeper(time.Minute)
+	defer stopSessionSweeper()
+
+	// Oturum anahtarı rotasyonu: süre, mesaj veya bayt sınırında yeni epoch duyurulur
+	keyRotation := crypto.RotationPolicy{
+		Interval:    envDuration("KEY_ROTATION_INTERVAL", crypto.DefaultRotationPolicy.Interval),
+		GracePeriod: envDuration("KEY_ROTATION_GRACE", crypto.DefaultRotationPolicy.GracePeriod),
+		MaxMessages: uint64(envInt("KEY_ROTATION_MAX_MESSAGES", int(crypto.DefaultRotationPolicy.MaxMessages))),
+		MaxBytes:    uint64(envInt("KEY_ROTATION_MAX_BYTES", int(crypto.DefaultRotationPolicy.MaxBytes))),
+	}
+
+	// Korumalı rotalar için ortak politika: kimliksiz veya şifresiz istekler reddedilir.
+	// Oturum açma, el sıkışma, sağlık kontrolü, imza anahtarları ve saat düz metin kalabilir.
+	encryptionOptions := []middleware.Option{
+		middleware.WithEnforceEncryption(true),
+		middleware.WithSessionRegistry(sessions),
+		middleware.WithKeyLifetime(keyLifetime),
+		middleware.WithKeyRotation(keyRotation),
+		// Replay penceresi ve saat kayması: saati bilinen ölçüde sapan istemciler için genişletilebilir
+		middleware.WithReplayWindow(envDuration("REPLAY_WINDOW", crypto.ReplayWindow)),
+		middleware.WithClockSkew(envDuration("MAX_CLOCK_SKEW", crypto.MaxClockSkew)),
+		middleware.WithPlaintextRoutes(plaintextRoutes...),
+	}
+
+	// Sıra numarası modu: istekler _timestamp yerine oturum başına artan _seq ile
+	// korunur; pencere içinde tekrar ve saat farkı sorunu ortadan kalkar
+	if os.Getenv("REPLAY_PROTECTION") == "sequence" {
+		sequences := crypto.NewMemorySequenceStore(envInt("MAX_SESSIONS", 100000), envInt("SEQUENCE_WINDOW", crypto.DefaultSequenceWindow))
+		encryptionOptions = append(encryptionOptions, middleware.WithSequenceNumbers(sequences))
+	}
+
+	// Şifreleme algoritmaları: istemcinin tercihine göre bu listeden biri seçilir
+	// (örn. "xchacha20-poly1305,aes-256-gcm")
+	if v := os.Getenv("ENCRYPTION_ALGORITHMS"); v != "" {
+		var names []string
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if _, ok := crypto.AlgorithmByName(name); !ok {
+				fmt.Printf("!!! UYARI: Bilinmeyen şifreleme algoritması yok sayıldı: %q\n", name)
+				continue
+			}
+			names = append(names, name)
+		}
+		if len(names) > 0 {
+			encryptionOptions = append(encryptionOptions, middleware.WithAlgorithms(names...))
+		}
+	}
+
+	// Yanıt imzası: denetim araçları yanıtları /api/signing-keys'teki public key ile doğrular
+	if path := os.Getenv("RESPONSE_SIGNING_KEY_FILE"); path != "" {
+		signer, err := crypto.LoadResponseSignerFile(path)
+		if err != nil {
+			fmt.Printf("!!! UYARI: Yanıt imza anahtarı yüklenemedi, yanıtlar imzalanmayacak: %v\n", err)
+		} else {
+			encryptionOptions = append(encryptionOptions, middleware.WithResponseSigner(signer))
+		}
+	}
+
+	router := newRouter(encryptionOptions...)
+
 	// TLS yapılandırması
 	tlsConfig := &tls.Config{
 		MinVersion: tls.VersionTLS12,
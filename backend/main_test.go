@@ -0,0 +1,736 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"secure-server/backend/middleware"
+	"secure-server/backend/pkg/auth"
+	"secure-server/backend/pkg/client"
+	"secure-server/backend/pkg/crypto"
+	"secure-server/backend/pkg/session"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// main.go'daki rotaların uçtan uca testleri: istemci tarafı şifreleme çoğunlukla
+// pkg/crypto ile elle yapılır, böylece middleware'ın kabul ve ret kararları
+// istemci SDK'sından bağımsız olarak doğrulanır. TestClient* testleri aynı
+// rotaları pkg/client üzerinden sürer.
+
+var testJWTSecret = []byte("main-test-secret")
+
+// newTestServer main() ile aynı rotaları ve şifreleme politikasını kurar.
+// Rotasyon kapalıdır; anahtar kimliği testler boyunca sabit kalır. opts
+// varsayılanlardan sonra uygulanır ve onları geçersiz kılar.
+func newTestServer(t *testing.T, opts ...middleware.Option) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := newRouter(append([]middleware.Option{
+		middleware.WithTokenVerifier(auth.NewJWTVerifier(auth.NewHMACKeySet(testJWTSecret), auth.VerifierConfig{})),
+		middleware.WithEnforceEncryption(true),
+		middleware.WithSessionRegistry(session.NewRegistry(time.Minute, time.Hour, 100, 0)),
+		middleware.WithKeyRotation(crypto.RotationPolicy{}),
+		middleware.WithReplayCache(crypto.NewMemoryReplayCache(1000, 0)),
+		middleware.WithPlaintextRoutes(plaintextRoutes...),
+	}, opts...)...)
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testToken subject için HS256 imzalı bir JWT üretir
+func testToken(subject string) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":%q,"exp":%d}`, subject, time.Now().Add(time.Hour).Unix())))
+
+	mac := hmac.New(sha256.New, testJWTSecret)
+	mac.Write([]byte(header + "." + claims))
+	return header + "." + claims + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// apiResponse çözülmüş test yanıtıdır
+type apiResponse struct {
+	Status    int
+	Header    http.Header
+	Encrypted bool
+	Body      map[string]interface{}
+}
+
+// apiSession oturum açmış ve el sıkışma yapmış bir test istemcisidir
+type apiSession struct {
+	t         *testing.T
+	srv       *httptest.Server
+	token     string
+	sessionID string
+	key       *crypto.SessionKey
+	// algorithms boş değilse el sıkışmada tercih sırasıyla sunulur
+	algorithms []string
+}
+
+// newAPISession POST /api/session ve POST /api/handshake ile anahtar kurar
+func newAPISession(t *testing.T, srv *httptest.Server, subject string) *apiSession {
+	t.Helper()
+	s := &apiSession{t: t, srv: srv, token: testToken(subject)}
+
+	resp := s.plain(http.MethodPost, "/api/session", nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("oturum açılamadı: %d %v", resp.Status, resp.Body)
+	}
+	s.sessionID = resp.Body["session_id"].(string)
+	s.handshake()
+	return s
+}
+
+func (s *apiSession) handshake() {
+	s.t.Helper()
+	clientPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	req := map[string]interface{}{
+		"client_public_key": base64.StdEncoding.EncodeToString(clientPriv.PublicKey().Bytes()),
+	}
+	if len(s.algorithms) > 0 {
+		req["algorithms"] = s.algorithms
+	}
+	resp := s.plain(http.MethodPost, "/api/handshake", req)
+	if resp.Status != http.StatusOK {
+		s.t.Fatalf("el sıkışma başarısız: %d %v", resp.Status, resp.Body)
+	}
+	alg, ok := crypto.AlgorithmByName(fmt.Sprint(resp.Body["algorithm"]))
+	if !ok {
+		s.t.Fatalf("el sıkışma algoritması: %v", resp.Body["algorithm"])
+	}
+
+	secret, err := crypto.CompleteHandshake(clientPriv, resp.Body["server_public_key"].(string), s.token, s.sessionID)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	epoch := uint32(resp.Body["epoch"].(float64))
+	key, err := crypto.DeriveEpochKey(secret, s.sessionID, epoch)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.key = &crypto.SessionKey{ID: crypto.EpochKeyID(resp.Body["key_id"].(string), epoch), Key: key, Epoch: epoch, Algorithm: alg.ID}
+}
+
+// plain şifresiz JSON isteği gönderir (oturum ve el sıkışma rotaları)
+func (s *apiSession) plain(method, path string, body map[string]interface{}) *apiResponse {
+	s.t.Helper()
+	var reqBody io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reqBody = bytes.NewReader(data)
+	}
+	req := s.newRequest(method, path, reqBody)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return s.send(req)
+}
+
+// encrypted gövdeyi veya query parametrelerini istemci gibi şifreler
+func (s *apiSession) encrypted(method, path string, query, body map[string]interface{}) *apiResponse {
+	s.t.Helper()
+	binding := crypto.RequestBinding(method, path, s.sessionID)
+
+	target := path
+	if query != nil {
+		encryptedQuery, err := crypto.EncryptQueryParamsWithKey(withReplayFields(query), s.key, binding)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		target += "?" + url.Values{"encrypted": {encryptedQuery}}.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		encryptedBody, err := crypto.EncryptDataWithKey(withReplayFields(body), s.key, binding)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reqBody = strings.NewReader(encryptedBody)
+	}
+
+	req := s.newRequest(method, target, reqBody)
+	if body != nil {
+		req.Header.Set(middleware.HeaderEncrypted, "true")
+		req.Header.Set("Content-Type", "text/plain")
+	}
+	return s.send(req)
+}
+
+func (s *apiSession) newRequest(method, target string, body io.Reader) *http.Request {
+	s.t.Helper()
+	req, err := http.NewRequest(method, s.srv.URL+target, body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	if s.sessionID != "" {
+		req.Header.Set(middleware.HeaderSessionID, s.sessionID)
+	}
+	return req
+}
+
+// send isteği gönderir ve şifreli yanıtı yanıt bağlamıyla çözer
+func (s *apiSession) send(req *http.Request) *apiResponse {
+	s.t.Helper()
+	httpResp, err := s.srv.Client().Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer httpResp.Body.Close()
+
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	resp := &apiResponse{Status: httpResp.StatusCode, Header: httpResp.Header}
+	if len(data) == 0 {
+		return resp
+	}
+
+	if httpResp.Header.Get(middleware.HeaderEncrypted) == "true" {
+		resp.Encrypted = true
+		var encryptedBase64 string
+		if err := json.Unmarshal(data, &encryptedBase64); err != nil {
+			s.t.Fatalf("şifreli yanıt JSON string değil: %s", data)
+		}
+		binding := crypto.ResponseBinding(req.Method, req.URL.Path, s.sessionID)
+		if data, err = crypto.DecryptResponseWithKey(encryptedBase64, s.key, binding); err != nil {
+			s.t.Fatalf("yanıt çözülemedi: %v", err)
+		}
+	}
+
+	if err := json.Unmarshal(data, &resp.Body); err != nil {
+		s.t.Fatalf("yanıt JSON nesnesi değil: %s", data)
+	}
+	return resp
+}
+
+func withReplayFields(payload map[string]interface{}) map[string]interface{} {
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+
+	out := map[string]interface{}{"_timestamp": time.Now().UnixMilli(), "_nonce": hex.EncodeToString(nonce)}
+	for k, v := range payload {
+		out[k] = v
+	}
+	return out
+}
+
+func requireStatus(t *testing.T, resp *apiResponse, status int, code string) {
+	t.Helper()
+	if resp.Status != status {
+		t.Fatalf("durum %d, beklenen %d (%v)", resp.Status, status, resp.Body)
+	}
+	if code != "" && resp.Body["code"] != code {
+		t.Fatalf("hata kodu %v, beklenen %s (%v)", resp.Body["code"], code, resp.Body)
+	}
+}
+
+func TestPlaintextRoutes(t *testing.T) {
+	srv := newTestServer(t)
+	anon := &apiSession{t: t, srv: srv}
+
+	health := anon.plain(http.MethodGet, "/api/health", nil)
+	requireStatus(t, health, http.StatusOK, "")
+	if health.Encrypted || health.Body["status"] != "ok" {
+		t.Fatalf("sağlık kontrolü: %+v", health)
+	}
+
+	if health.Header.Get(middleware.HeaderServerTime) == "" {
+		t.Fatal("X-Server-Time başlığı yok")
+	}
+
+	keys := anon.plain(http.MethodGet, "/api/signing-keys", nil)
+	requireStatus(t, keys, http.StatusOK, "")
+	if list, ok := keys.Body["keys"].([]interface{}); !ok || len(list) != 0 {
+		t.Fatalf("imza anahtarları: %v", keys.Body)
+	}
+
+	// Kimliksiz oturum açma ve el sıkışma reddedilir (düz JSON)
+	requireStatus(t, anon.plain(http.MethodPost, "/api/session", nil), http.StatusUnauthorized, "unauthorized")
+	requireStatus(t, anon.plain(http.MethodPost, "/api/handshake", map[string]interface{}{"client_public_key": "x"}), http.StatusUnauthorized, "unauthorized")
+}
+
+func TestPreflightRoutes(t *testing.T) {
+	srv := newTestServer(t)
+	anon := &apiSession{t: t, srv: srv}
+
+	for _, path := range []string{"/api/session", "/api/session/refresh", "/api/session/revoke", "/api/handshake", "/api/logout", "/api/data"} {
+		resp := anon.plain(http.MethodOptions, path, nil)
+		if resp.Status != http.StatusNoContent {
+			t.Errorf("OPTIONS %s: durum %d", path, resp.Status)
+		}
+		if !strings.Contains(resp.Header.Get("Access-Control-Allow-Headers"), middleware.HeaderSessionID) {
+			t.Errorf("OPTIONS %s: CORS başlıkları eksik", path)
+		}
+	}
+}
+
+func TestDataRoutes(t *testing.T) {
+	srv := newTestServer(t)
+	s := newAPISession(t, srv, "alice")
+	user := map[string]interface{}{"name": "Ayşe", "email": "ayse@example.com", "age": 30}
+
+	cases := []struct {
+		method string
+		query  map[string]interface{}
+		body   map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{
+			method: http.MethodPost,
+			body:   map[string]interface{}{"action": "create", "user": user},
+			want:   map[string]interface{}{"action": "create", "received_data_summary": "Kullanıcı Adı: Ayşe"},
+		},
+		{
+			method: http.MethodGet,
+			query:  map[string]interface{}{"search": "çiçek", "category": "kitap", "page": 1, "limit": 10},
+			want:   map[string]interface{}{"search_term": "çiçek", "category": "kitap", "results_count": float64(42)},
+		},
+		{
+			method: http.MethodPut,
+			body:   map[string]interface{}{"id": "42", "user": user},
+			want:   map[string]interface{}{"resource_id": "42", "updated_by": "ayse@example.com"},
+		},
+		{
+			method: http.MethodPatch,
+			body:   map[string]interface{}{"id": "42", "updates": map[string]interface{}{"age": 31}},
+			want:   map[string]interface{}{"resource_id": "42", "updates_applied": map[string]interface{}{"age": float64(31)}},
+		},
+		{
+			method: http.MethodDelete,
+			body:   map[string]interface{}{"id": "42", "reason": "test", "confirmed": true},
+			want:   map[string]interface{}{"resource_id": "42", "reason": "test"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			s.t = t
+			resp := s.encrypted(tc.method, "/api/data", tc.query, tc.body)
+			requireStatus(t, resp, http.StatusOK, "")
+			if !resp.Encrypted {
+				t.Fatal("yanıt şifreli değil")
+			}
+			for k, v := range tc.want {
+				if got, _ := json.Marshal(resp.Body[k]); string(got) != mustJSON(t, v) {
+					t.Errorf("%s = %s, beklenen %s", k, got, mustJSON(t, v))
+				}
+			}
+		})
+	}
+}
+
+func TestDataRoutesValidation(t *testing.T) {
+	srv := newTestServer(t)
+	s := newAPISession(t, srv, "alice")
+
+	// Handler doğrulama hataları şifreli döner
+	cases := []struct {
+		method string
+		query  map[string]interface{}
+		body   map[string]interface{}
+	}{
+		{http.MethodPost, nil, map[string]interface{}{"action": "create"}},
+		{http.MethodGet, map[string]interface{}{"limit": 1000}, nil},
+		{http.MethodGet, nil, nil},
+		{http.MethodPut, nil, map[string]interface{}{"user": map[string]interface{}{"name": "x"}}},
+		{http.MethodPatch, nil, map[string]interface{}{"id": "1"}},
+		{http.MethodDelete, nil, map[string]interface{}{"reason": "id yok"}},
+	}
+	for _, tc := range cases {
+		resp := s.encrypted(tc.method, "/api/data", tc.query, tc.body)
+		if resp.Status != http.StatusBadRequest || !resp.Encrypted {
+			t.Errorf("%s %v %v: durum %d, şifreli %v", tc.method, tc.query, tc.body, resp.Status, resp.Encrypted)
+		}
+	}
+}
+
+func TestEncryptionEnforcement(t *testing.T) {
+	srv := newTestServer(t)
+	s := newAPISession(t, srv, "alice")
+
+	// Düz metin gövde ve query parametresi
+	req := s.newRequest(http.MethodPost, "/api/data", strings.NewReader(`{"action":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	requireStatus(t, s.send(req), http.StatusForbidden, "encryption_required")
+	requireStatus(t, s.send(s.newRequest(http.MethodGet, "/api/data?search=x", nil)), http.StatusForbidden, "encryption_required")
+
+	// Geçerli şifreli query ile düz metin gövde: gövde hiç çözülmeyeceğinden reddedilir
+	binding := crypto.RequestBinding(http.MethodPost, "/api/data", s.sessionID)
+	for _, header := range []string{"true", middleware.EncryptedStreamValue} {
+		encryptedQuery, err := crypto.EncryptQueryParamsWithKey(withReplayFields(map[string]interface{}{"search": "x"}), s.key, binding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := s.newRequest(http.MethodPost, "/api/data?"+url.Values{"encrypted": {encryptedQuery}}.Encode(), strings.NewReader(`{"action":"create"}`))
+		req.Header.Set(middleware.HeaderEncrypted, header)
+		req.Header.Set("Content-Type", "application/json")
+		requireStatus(t, s.send(req), http.StatusForbidden, "encryption_required")
+	}
+
+	// Kimlik bilgisi olmadan
+	anon := &apiSession{t: t, srv: srv}
+	requireStatus(t, anon.plain(http.MethodGet, "/api/data", nil), http.StatusForbidden, "encryption_required")
+
+	// Geçersiz token
+	forged := &apiSession{t: t, srv: srv, token: s.token + "x", sessionID: s.sessionID, key: s.key}
+	requireStatus(t, forged.encrypted(http.MethodPost, "/api/data", nil, map[string]interface{}{"action": "x"}), http.StatusUnauthorized, "unauthorized")
+
+	// Başka kullanıcının oturumu
+	mallory := &apiSession{t: t, srv: srv, token: testToken("mallory"), sessionID: s.sessionID, key: s.key}
+	requireStatus(t, mallory.encrypted(http.MethodPost, "/api/data", nil, map[string]interface{}{"action": "x"}), http.StatusUnauthorized, "session_invalid")
+}
+
+func TestTamperedAndReplayedRequests(t *testing.T) {
+	srv := newTestServer(t)
+	s := newAPISession(t, srv, "alice")
+	body := map[string]interface{}{"action": "create", "user": map[string]interface{}{"name": "Ayşe"}}
+
+	encryptedBody, err := crypto.EncryptDataWithKey(withReplayFields(body), s.key, crypto.RequestBinding(http.MethodPost, "/api/data", s.sessionID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(payload, path string) *apiResponse {
+		req := s.newRequest(http.MethodPost, path, strings.NewReader(payload))
+		req.Header.Set(middleware.HeaderEncrypted, "true")
+		return s.send(req)
+	}
+
+	// Tek byte değişikliği ve başka rotaya taşıma doğrulamayı bozar
+	raw, _ := base64.StdEncoding.DecodeString(encryptedBody)
+	raw[len(raw)-1] ^= 0x01
+	requireStatus(t, post(base64.StdEncoding.EncodeToString(raw), "/api/data"), http.StatusBadRequest, "bad_request")
+	requireStatus(t, post(encryptedBody, "/api/logout"), http.StatusBadRequest, "bad_request")
+
+	// İlk gönderim kabul edilir, aynı gövde ikinci kez reddedilir
+	requireStatus(t, post(encryptedBody, "/api/data"), http.StatusOK, "")
+	requireStatus(t, post(encryptedBody, "/api/data"), http.StatusBadRequest, "bad_request")
+}
+
+func TestServerTime(t *testing.T) {
+	srv := newTestServer(t)
+	anon := &apiSession{t: t, srv: srv}
+
+	resp := anon.plain(http.MethodGet, "/api/time", nil)
+	requireStatus(t, resp, http.StatusOK, "")
+	serverTime, ok := resp.Body["server_time"].(float64)
+	if !ok || time.Since(time.UnixMilli(int64(serverTime))).Abs() > time.Minute {
+		t.Fatalf("sunucu saati: %v", resp.Body)
+	}
+	if resp.Header.Get(middleware.HeaderServerTime) != fmt.Sprint(int64(serverTime)) {
+		t.Fatalf("X-Server-Time %q, gövde %v", resp.Header.Get(middleware.HeaderServerTime), serverTime)
+	}
+
+	// Saati geride veya ileride olan istemci aynı kodu ve mesajı alır; yön sızdırılmaz
+	s := newAPISession(t, srv, "alice")
+	body := map[string]interface{}{"action": "create", "user": map[string]interface{}{"name": "Ayşe"}}
+	var messages []interface{}
+	for _, offset := range []time.Duration{-10 * time.Minute, time.Minute} {
+		skewed := map[string]interface{}{"_timestamp": time.Now().Add(offset).UnixMilli()}
+		for k, v := range body {
+			skewed[k] = v
+		}
+		resp := s.encrypted(http.MethodPost, "/api/data", nil, skewed)
+		requireStatus(t, resp, http.StatusBadRequest, "clock_skew")
+		if !resp.Encrypted || resp.Header.Get(middleware.HeaderServerTime) == "" {
+			t.Fatalf("saat kayması yanıtı: %+v", resp)
+		}
+		messages = append(messages, resp.Body["error"])
+	}
+	if messages[0] != messages[1] {
+		t.Fatalf("saat kayması mesajları farklı: %v", messages)
+	}
+
+	// Düzeltilmiş saatle tekrar deneme kabul edilir
+	requireStatus(t, s.encrypted(http.MethodPost, "/api/data", nil, body), http.StatusOK, "")
+}
+
+func TestAlgorithmNegotiation(t *testing.T) {
+	srv := newTestServer(t)
+	s := newAPISession(t, srv, "alice")
+	if s.key.Algorithm != crypto.AlgAES256GCM {
+		t.Fatalf("algoritma sunmayan istemci için %d seçildi", s.key.Algorithm)
+	}
+
+	// Anahtar seçilen algoritmaya bağlıdır; yanıtlar da aynı algoritmayla çözülmelidir
+	for _, name := range []string{"xchacha20-poly1305", "chacha20-poly1305", "aes-256-gcm-siv"} {
+		s.algorithms = []string{name, "aes-256-gcm"}
+		s.handshake()
+		resp := s.encrypted(http.MethodPost, "/api/data", nil, map[string]interface{}{"action": "create", "user": map[string]interface{}{"name": "Ayşe"}})
+		requireStatus(t, resp, http.StatusOK, "")
+		if !resp.Encrypted {
+			t.Fatalf("%s: yanıt şifrelenmedi", name)
+		}
+	}
+
+	s.algorithms = []string{"bilinmeyen"}
+	clientPub := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	requireStatus(t, s.plain(http.MethodPost, "/api/handshake", map[string]interface{}{"client_public_key": clientPub, "algorithms": s.algorithms}), http.StatusBadRequest, "bad_request")
+}
+
+func TestSessionLifecycleRoutes(t *testing.T) {
+	srv := newTestServer(t)
+	s := newAPISession(t, srv, "alice")
+
+	refresh := s.encrypted(http.MethodPost, "/api/session/refresh", nil, nil)
+	requireStatus(t, refresh, http.StatusOK, "")
+	if !refresh.Encrypted || refresh.Body["session_id"] != s.sessionID {
+		t.Fatalf("oturum yenileme: %+v", refresh)
+	}
+
+	// Çıkış oturumu iptal eder; yeni el sıkışma da oturumu geri getirmez
+	logout := newAPISession(t, srv, "alice")
+	requireStatus(t, logout.encrypted(http.MethodPost, "/api/logout", nil, nil), http.StatusOK, "")
+	requireStatus(t, logout.encrypted(http.MethodGet, "/api/data", map[string]interface{}{"search": "x"}, nil), http.StatusUnauthorized, "session_invalid")
+	requireStatus(t, logout.plain(http.MethodPost, "/api/handshake", map[string]interface{}{"client_public_key": base64.StdEncoding.EncodeToString(make([]byte, 32))}), http.StatusUnauthorized, "session_invalid")
+	requireStatus(t, s.encrypted(http.MethodGet, "/api/data", map[string]interface{}{"search": "x"}, nil), http.StatusOK, "")
+
+	// İptal edilen oturum bir daha kullanılamaz
+	revoke := s.encrypted(http.MethodPost, "/api/session/revoke", nil, nil)
+	requireStatus(t, revoke, http.StatusOK, "")
+	if revoke.Body["revoked"] != true {
+		t.Fatalf("oturum iptali: %v", revoke.Body)
+	}
+	requireStatus(t, s.encrypted(http.MethodGet, "/api/data", map[string]interface{}{"search": "x"}, nil), http.StatusUnauthorized, "session_invalid")
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// clientRequest pkg/client'in gönderdiği şifreli bir isteğin kaydıdır
+type clientRequest struct {
+	Status    int
+	KeyID     string
+	NextEpoch string
+}
+
+// recordingTransport şifreli gövdeli istekleri zarftaki anahtar kimliği ve
+// yanıt durumuyla kaydeder
+type recordingTransport struct {
+	mu       sync.Mutex
+	requests []clientRequest
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var keyID string
+	encrypted := req.Header.Get(middleware.HeaderEncrypted) == "true" && req.Body != nil
+	if encrypted {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		if raw, err := base64.StdEncoding.DecodeString(string(data)); err == nil {
+			if env, err := crypto.ParseEnvelope(raw); err == nil {
+				keyID = env.KeyID
+			}
+		}
+	}
+
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil || !encrypted {
+		return resp, err
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.requests = append(rt.requests, clientRequest{Status: resp.StatusCode, KeyID: keyID, NextEpoch: resp.Header.Get(middleware.HeaderKeyNextEpoch)})
+	return resp, nil
+}
+
+// take kaydedilen istekleri döndürür ve kaydı sıfırlar
+func (rt *recordingTransport) take() []clientRequest {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	requests := rt.requests
+	rt.requests = nil
+	return requests
+}
+
+// newTestClient test sunucusunun /api rotalarına subject adına bağlanan bir
+// pkg/client istemcisi oluşturur
+func newTestClient(t *testing.T, srv *httptest.Server, subject string) (*client.Client, *recordingTransport) {
+	t.Helper()
+	rt := &recordingTransport{}
+	c, err := client.New(srv.URL+"/api", client.WithToken(testToken(subject)), client.WithHTTPClient(&http.Client{Transport: rt}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, rt
+}
+
+// postCreate istemciyle POST /api/data gönderir ve yanıtı doğrular
+func postCreate(t *testing.T, c *client.Client) {
+	t.Helper()
+	var out map[string]interface{}
+	if err := c.Post(context.Background(), "/data", map[string]interface{}{"action": "create", "user": map[string]interface{}{"name": "Ayşe"}}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["received_data_summary"] != "Kullanıcı Adı: Ayşe" {
+		t.Fatalf("yanıt: %v", out)
+	}
+}
+
+// requireStatuses kaydedilen isteklerin durum kodlarını sırasıyla karşılaştırır
+func requireStatuses(t *testing.T, requests []clientRequest, statuses ...int) {
+	t.Helper()
+	got := make([]int, len(requests))
+	for i, r := range requests {
+		got[i] = r.Status
+	}
+	if fmt.Sprint(got) != fmt.Sprint(statuses) {
+		t.Fatalf("istek durumları %v, beklenen %v", got, statuses)
+	}
+}
+
+func TestClientHandshake(t *testing.T) {
+	srv := newTestServer(t)
+	c, rt := newTestClient(t, srv, "alice")
+
+	// İlk istek oturum açar ve el sıkışma yapar
+	var out map[string]interface{}
+	if err := c.Get(context.Background(), "/data", map[string]interface{}{"search": "çiçek"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out["search_term"] != "çiçek" {
+		t.Fatalf("yanıt: %v", out)
+	}
+	sessionID := c.SessionID()
+	if sessionID == "" {
+		t.Fatal("oturum açılmadı")
+	}
+
+	// Sonraki istekler aynı oturum ve anahtarla gider
+	postCreate(t, c)
+	postCreate(t, c)
+	requests := rt.take()
+	requireStatuses(t, requests, http.StatusOK, http.StatusOK)
+	if requests[0].KeyID != requests[1].KeyID || c.SessionID() != sessionID {
+		t.Fatalf("anahtar veya oturum değişti: %+v, %s", requests, c.SessionID())
+	}
+}
+
+func TestClientEpochAdvance(t *testing.T) {
+	srv := newTestServer(t, middleware.WithKeyRotation(crypto.RotationPolicy{GracePeriod: time.Minute, MaxMessages: 2}))
+	c, rt := newTestClient(t, srv, "alice")
+
+	for i := 0; i < 6; i++ {
+		postCreate(t, c)
+	}
+	requests := rt.take()
+	requireStatuses(t, requests, http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK)
+
+	// Her epoch iki mesajdan (istek + yanıt) sonra yenilenir: sunucu sonraki
+	// epoch'u duyurur, istemci bir sonraki istekte ona geçer
+	baseID, _, _ := strings.Cut(requests[0].KeyID, ".")
+	epoch := 0
+	for i, r := range requests {
+		if want := crypto.EpochKeyID(baseID, uint32(epoch)); r.KeyID != want {
+			t.Fatalf("istek %d: anahtar %s, beklenen %s", i+1, r.KeyID, want)
+		}
+		if r.NextEpoch != "" {
+			if r.NextEpoch != fmt.Sprint(epoch+1) {
+				t.Fatalf("istek %d: duyurulan epoch %s, beklenen %d", i+1, r.NextEpoch, epoch+1)
+			}
+			epoch++
+		}
+	}
+	if epoch != 3 {
+		t.Fatalf("%d epoch ilerlendi, beklenen 3", epoch)
+	}
+}
+
+func TestClientClockSkewRetry(t *testing.T) {
+	var skew atomic.Int64
+	clock := crypto.ClockFunc(func() time.Time { return time.Now().Add(time.Duration(skew.Load())) })
+	srv := newTestServer(t, middleware.WithClock(clock))
+	c, rt := newTestClient(t, srv, "alice")
+
+	postCreate(t, c)
+	if offset := c.ClockOffset().Abs(); offset > time.Second {
+		t.Fatalf("saat farkı %v, beklenen ~0", offset)
+	}
+
+	// Sunucu saati ileri kayar: ilk deneme clock_skew alır, istemci X-Server-Time
+	// ile saat farkını düzeltip bir kez tekrar dener
+	skew.Store(int64(10 * time.Minute))
+	postCreate(t, c)
+	requireStatuses(t, rt.take(), http.StatusOK, http.StatusBadRequest, http.StatusOK)
+	if offset := c.ClockOffset(); (offset - 10*time.Minute).Abs() > time.Second {
+		t.Fatalf("saat farkı %v, beklenen ~10m", offset)
+	}
+
+	postCreate(t, c)
+	requireStatuses(t, rt.take(), http.StatusOK)
+}
+
+func TestClientSessionInvalidReset(t *testing.T) {
+	registry := session.NewRegistry(time.Minute, time.Hour, 100, 0)
+	srv := newTestServer(t, middleware.WithSessionRegistry(registry))
+	c, rt := newTestClient(t, srv, "alice")
+
+	postCreate(t, c)
+	revoked := c.SessionID()
+
+	// Oturum sunucuda iptal edilir: istek session_invalid alır, istemci yeni
+	// oturum açıp el sıkışma yapar ve isteği tekrar gönderir
+	if !registry.Revoke(revoked) {
+		t.Fatal("oturum iptal edilemedi")
+	}
+	postCreate(t, c)
+	requireStatuses(t, rt.take(), http.StatusOK, http.StatusUnauthorized, http.StatusOK)
+	if c.SessionID() == "" || c.SessionID() == revoked {
+		t.Fatalf("yeni oturum açılmadı: %q", c.SessionID())
+	}
+	if _, err := registry.Validate(c.SessionID(), "alice"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestClientLegacyKeyDerivation token'dan türetilen anahtarın istek sonunda
+// sıfırlanmasının önbellekteki kopyayı bozmadığını doğrular
+func TestClientLegacyKeyDerivation(t *testing.T) {
+	srv := newTestServer(t, middleware.WithLegacyKeyDerivation(true))
+	rt := &recordingTransport{}
+	c, err := client.New(srv.URL+"/api", client.WithToken(testToken("alice")), client.WithLegacyKeyDerivation(true), client.WithHTTPClient(&http.Client{Transport: rt}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		postCreate(t, c)
+	}
+	requireStatuses(t, rt.take(), http.StatusOK, http.StatusOK, http.StatusOK)
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Şifreli yük içinde taşınan ve handler'lara ait olmayan güvenlik alanları
+var envelopeFields = []string{"_timestamp", "_nonce", "_seq"}
+
+// ErrNoDecryptedData istekte çözülmüş body veya query parametresi yok
+var ErrNoDecryptedData = errors.New("çözülmüş veri bulunamadı")
+
+// BindDecrypted çözülmüş istek body'sini T tipine dönüştürür ve gin'in
+// `binding` etiketleriyle doğrular. _timestamp, _nonce ve _seq alanları atılır.
+//
+//	type createRequest struct {
+//		Action string `json:"action" binding:"required"`
+//	}
+//	req, err := middleware.BindDecrypted[createRequest](c)
+func BindDecrypted[T any](c *gin.Context) (T, error) {
+	data, ok := GetDecryptedBody(c)
+	if !ok {
+		var zero T
+		return zero, ErrNoDecryptedData
+	}
+	return bindDecryptedData[T](data)
+}
+
+// BindDecryptedQuery çözülmüş query parametrelerini T tipine dönüştürür ve
+// doğrular. Alanlar `json` etiketleriyle eşleşir.
+func BindDecryptedQuery[T any](c *gin.Context) (T, error) {
+	data, ok := GetDecryptedQueryParams(c)
+	if !ok {
+		var zero T
+		return zero, ErrNoDecryptedData
+	}
+	return bindDecryptedData[T](data)
+}
+
+// bindDecryptedData güvenlik alanlarını ayıklayıp veriyi T'ye çözer ve doğrular
+func bindDecryptedData[T any](data map[string]interface{}) (T, error) {
+	var result T
+
+	// Context'teki haritayı değiştirmemek için kopya üzerinde çalış
+	payload := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		payload[k] = v
+	}
+	for _, field := range envelopeFields {
+		delete(payload, field)
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return result, fmt.Errorf("çözülmüş veri JSON'a dönüştürülemedi: %w", err)
+	}
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return result, fmt.Errorf("çözülmüş veri hedef tipe uymuyor: %w", err)
+	}
+
+	if binding.Validator != nil {
+		if err := binding.Validator.ValidateStruct(result); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
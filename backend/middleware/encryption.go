@@ -2,12 +2,18 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"secure-server/backend/pkg/auth"
 	"secure-server/backend/pkg/crypto"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,65 +22,340 @@ const (
 	HeaderSessionID = "X-Session-ID"
 	HeaderAuth      = "Authorization"
 	HeaderEncrypted = "X-Encrypted"
+
+	// HeaderKeyNextEpoch sunucunun duyurduğu sonraki anahtar epoch'udur.
+	// İstemci bu epoch'un anahtarını türetip sonraki isteklerde kullanmalıdır.
+	HeaderKeyNextEpoch = "X-Key-Next-Epoch"
+)
+
+// tokenVerifier bearer token'ları anahtar türetmeden önce doğrular.
+// Yapılandırılmamışsa kimlik bilgisi taşıyan tüm istekler reddedilir.
+var (
+	tokenVerifierMu sync.RWMutex
+	tokenVerifier   auth.Verifier
 )
 
-// customResponseWriter yanıtı yakalamak için gin.ResponseWriter'ı sarmalar
+// SetTokenVerifier middleware'ın kullanacağı JWT doğrulayıcısını ayarlar
+func SetTokenVerifier(v auth.Verifier) {
+	tokenVerifierMu.Lock()
+	defer tokenVerifierMu.Unlock()
+	tokenVerifier = v
+}
+
+func currentTokenVerifier() auth.Verifier {
+	tokenVerifierMu.RLock()
+	defer tokenVerifierMu.RUnlock()
+	return tokenVerifier
+}
+
+// legacyKeyDerivation açıksa el sıkışma yapmamış eski istemciler için anahtar
+// token+sessionID'den türetilir. Token'ı gören herkes trafiği çözebileceğinden
+// varsayılan olarak kapalıdır.
+var legacyKeyDerivation bool
+
+// SetLegacyKeyDerivation eski (token'dan türetilen) anahtar modunun varsayılanını
+// açar/kapatır. Middleware oluşturulmadan önce çağrılmalıdır; WithLegacyKeyDerivation
+// ile grup bazında geçersiz kılınabilir.
+func SetLegacyKeyDerivation(enabled bool) {
+	legacyKeyDerivation = enabled
+}
+
+// EncryptedStreamValue X-Encrypted başlığının parçalı akış formatını belirten değeridir
+const EncryptedStreamValue = "stream"
+
+// encryptedResponseWriter yanıtı şifrelemek için gin.ResponseWriter'ı sarmalar.
+// Yanıt varsayılan olarak tamponlanır; imza kapalıyken tampon eşiği aşarsa
+// veya handler Flush çağırırsa parçalı akış olarak şifrelenir.
 type encryptedResponseWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
+
+	o       *Options
+	key     *crypto.SessionKey
+	binding crypto.Binding
+
+	// stream akış moduna geçildiyse dolu olur
+	stream *crypto.StreamWriter
+	// err akışa geçiş veya akış yazımı başarısız olduysa dolu olur. Sonraki
+	// yazmalar reddedilir ve hata handleResponseEncryption'dan döner.
+	err error
+}
+
+func (w *encryptedResponseWriter) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	if w.stream != nil {
+		n, err := w.stream.Write(b)
+		if err != nil {
+			w.err = fmt.Errorf("yanıt akışı yazılamadı: %w", err)
+		}
+		return n, err
+	}
+
+	n, _ := w.body.Write(b)
+	if w.canStream() && w.o.StreamThreshold > 0 && w.body.Len() > w.o.StreamThreshold {
+		if err := w.startStreaming(); err != nil {
+			w.err = err
+			return n, err
+		}
+	}
+	return n, nil
 }
 
-func (w encryptedResponseWriter) Write(b []byte) (int, error) {
-	return w.body.Write(b)
+func (w *encryptedResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
 }
 
-func (w encryptedResponseWriter) WriteString(s string) (int, error) {
-	return w.body.WriteString(s)
+// WriteHeaderNow başlıkları hemen göndermez (örn. c.AbortWithStatus); yanıt
+// başlıkları şifreleme sırasında yazılır. Akış modunda başlıklar zaten gönderilmiştir.
+func (w *encryptedResponseWriter) WriteHeaderNow() {}
+
+// canStream yanıtın parçalı akışa geçip geçemeyeceğini bildirir. İmza tüm
+// düz metni kapsadığından imza açıkken yanıt her zaman tamponlanır.
+func (w *encryptedResponseWriter) canStream() bool {
+	return w.o.Signer == nil
+}
+
+// Flush tamponlanmış veriyi akış olarak hemen gönderir (örn. SSE, büyük dışa
+// aktarımlar). İmza açıksa yanıt tamamlanana kadar tamponda kalır. Hata
+// yazıcıya kaydedilir ve yanıt şifreleme hatası olarak sonlandırılır.
+func (w *encryptedResponseWriter) Flush() {
+	if !w.canStream() || w.err != nil {
+		return
+	}
+
+	if w.stream == nil {
+		if err := w.startStreaming(); err != nil {
+			w.err = err
+			return
+		}
+	}
+
+	if err := w.stream.Flush(); err != nil {
+		w.err = fmt.Errorf("yanıt akışı gönderilemedi: %w", err)
+		return
+	}
+	w.ResponseWriter.Flush()
+}
+
+// startStreaming tamponu boşaltıp akış moduna geçer. Akışın düz metni
+// orijinal Content-Type ile başlar: contentTypeLen(1) | contentType | body.
+// Content-Type veya akış oluşturulamazsa tampon olduğu gibi kalır.
+func (w *encryptedResponseWriter) startStreaming() error {
+	header := w.ResponseWriter.Header()
+	buffered := w.body.Bytes()
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(buffered)
+	}
+	if len(contentType) > 255 {
+		return errors.New("yanıt akışı oluşturulamadı: Content-Type çok uzun")
+	}
+
+	stream, err := crypto.NewStreamWriter(w.ResponseWriter, w.key, w.binding, w.o.StreamSegmentSize)
+	if err != nil {
+		return fmt.Errorf("yanıt akışı oluşturulamadı: %w", err)
+	}
+	w.stream = stream
+	w.body = &bytes.Buffer{}
+
+	header.Set("Content-Type", "application/octet-stream")
+	header.Set(w.o.HeaderEncrypted, EncryptedStreamValue)
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.ResponseWriter.Status())
+
+	if _, err := stream.Write(append([]byte{byte(len(contentType))}, contentType...)); err != nil {
+		return fmt.Errorf("yanıt akışı yazılamadı: %w", err)
+	}
+	if _, err := stream.Write(buffered); err != nil {
+		return fmt.Errorf("yanıt akışı yazılamadı: %w", err)
+	}
+	return nil
 }
 
 // EncryptionMiddleware uçtan uca şifreleme/çözme işlemini yapar.
-func EncryptionMiddleware() gin.HandlerFunc {
+// Seçenek verilmezse varsayılan politika (defaultOptions) kullanılır.
+func EncryptionMiddleware(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts...)
+
 	return func(c *gin.Context) {
-		token, sessionID, err := getAuthAndSession(c)
-		if err != nil {
-			// Token veya SessionID eksikse, işleme devam et
+		// Saati sapan istemciler farkı her yanıttan hesaplayabilir
+		setServerTime(c, o)
+
+		// İzin listesindeki rotalar (sağlık kontrolü, el sıkışma) düz metin kalır
+		if o.isPlaintextRoute(c) {
 			c.Next()
 			return
 		}
 
+		newRequestID(c)
+
+		token, sessionID, err := getAuthAndSession(c, o)
+		if err != nil {
+			if errors.Is(err, errMissingCredentials) {
+				if o.EnforceEncryption {
+					rejectUnencrypted(c, o, nil, "", err)
+					return
+				}
+				if o.FallThrough {
+					// Token veya SessionID eksikse, işleme devam et
+					c.Next()
+					return
+				}
+			}
+
+			// Token eksik/doğrulanamadı veya oturum geçersiz: anahtar türetmeden reddet (anahtar yok, düz metin)
+			code, message := authFailure(o, err)
+			logSecurityError(c, o, "Token Verification Failed", err, slog.String("code", string(code)))
+			abortWithError(c, o, nil, "", http.StatusUnauthorized, code, message)
+			return
+		}
+
+		key, derived, err := resolveSessionKey(token, sessionID, o)
+		if err != nil {
+			// El sıkışma yapılmamış veya oturum anahtarının süresi dolmuş (anahtar yok, düz metin)
+			logSecurityError(c, o, "Session Key Lookup Failed", err)
+			abortWithError(c, o, nil, "", http.StatusUnauthorized, CodeHandshakeRequired, o.Messages.HandshakeRequired)
+			return
+		}
+		// Token'dan türetilen anahtar yalnızca bu istek içindir; yanıt yazıldıktan sonra sıfırlanır
+		if derived {
+			defer crypto.ZeroKey(key.Key)
+		}
+
+		// Zorunlu şifreleme modunda düz metin gövde veya query parametresi kabul edilmez
+		if o.EnforceEncryption {
+			if err := requireEncryptedRequest(c, o); err != nil {
+				rejectUnencrypted(c, o, key, sessionID, err)
+				return
+			}
+		}
+
 		// 1. Request Body/Query Decryption
-		if err := handleRequestDecryption(c, token, sessionID); err != nil {
+		if err := handleRequestDecryption(c, o, key, sessionID); err != nil {
 			// **KRİTİK GÜVENLİK ÖNLEMİ:**
 			// Şifre çözme veya Replay Attack hatalarında detay verme.
 			// Detaylı hata mesajını logla, kullanıcıya genel bir hata dön.
-			fmt.Printf("[SECURITY ERROR] Request Decryption Failed for %s %s: %v\n", c.Request.Method, c.Request.URL.Path, err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Geçersiz İstek: Veri güvenliği kontrolü başarısız."})
-			c.Abort()
+			logSecurityError(c, o, "Request Decryption Failed", err, slog.String("cause", failureCause(err)))
+			code, message := decryptionFailure(o, err)
+			abortWithError(c, o, key, sessionID, http.StatusBadRequest, code, message)
+			return
+		}
+
+		// İstek duyurulan epoch'la geldiyse rotasyon tamamlanmıştır; yanıt
+		// geçerli epoch'la şifrelenir ve bekleyen rotasyon başlıkla duyurulur
+		key = key.Current()
+		if epoch, ok := key.NextEpoch(); ok {
+			c.Header(HeaderKeyNextEpoch, strconv.FormatUint(uint64(epoch), 10))
+		}
+
+		// Şifreleme dışı bırakılan rotalarda yanıt düz metin kalır
+		if o.isPlaintextResponseRoute(c) {
+			c.Next()
 			return
 		}
 
 		// Response'u yakalamak için custom response writer'ı ayarla
-		w := &encryptedResponseWriter{body: &bytes.Buffer{}, ResponseWriter: c.Writer}
+		w := &encryptedResponseWriter{
+			ResponseWriter: c.Writer,
+			body:           &bytes.Buffer{},
+			o:              o,
+			key:            key,
+			binding:        crypto.ResponseBinding(c.Request.Method, c.Request.URL.Path, sessionID),
+		}
 		c.Writer = w
 
 		// İşlem zincirine devam et (API Handler'ı çalıştır)
 		c.Next()
 
 		// 2. Response Encryption
-		if err := handleResponseEncryption(c, w, token, sessionID); err != nil {
-			// Şifreleme hatası (bu genelde sunucu hatasıdır)
-			fmt.Printf("[SECURITY ERROR] Response Encryption Failed for %s %s: %v\n", c.Request.Method, c.Request.URL.Path, err)
-			c.AbortWithStatus(http.StatusInternalServerError)
+		if err := handleResponseEncryption(c, o, w); err != nil {
+			// Şifreleme hatası (bu genelde sunucu hatasıdır). Yanıt henüz
+			// yazılmadıysa asıl içerik sızdırılmadan genel hata döner.
+			logSecurityError(c, o, "Response Encryption Failed", err)
+			if !w.ResponseWriter.Written() {
+				c.Writer = w.ResponseWriter
+				abortWithError(c, o, nil, "", http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError))
+			}
 			return
 		}
 	}
 }
 
+// requireEncryptedRequest isteğin yalnızca şifreli veri taşıdığını doğrular
+func requireEncryptedRequest(c *gin.Context, o *Options) error {
+	for name := range c.Request.URL.Query() {
+		if name != "encrypted" {
+			return fmt.Errorf("şifresiz query parametresi: %q", name)
+		}
+	}
+
+	// ContentLength -1 bilinmeyen uzunluk (chunked) anlamına gelir
+	hasBody := c.Request.ContentLength != 0
+
+	// Şifreli query ile gelen istekte gövde çözülmez; X-Encrypted başlığı
+	// olsa bile gövde handler'a doğrulanmadan ulaşırdı
+	if c.Query("encrypted") != "" && hasBody {
+		return errors.New("şifreli query ile birlikte istek gövdesi")
+	}
+
+	encrypted := c.GetHeader(o.HeaderEncrypted)
+	if hasBody && encrypted != "true" && encrypted != EncryptedStreamValue {
+		return errors.New("şifresiz istek gövdesi")
+	}
+
+	return nil
+}
+
+// rejectUnencrypted zorunlu şifreleme ihlallerini tek tip bir yanıtla reddeder.
+// Hangi kontrolün başarısız olduğu yalnızca loglanır. key nil değilse yanıt şifrelenir.
+func rejectUnencrypted(c *gin.Context, o *Options, key *crypto.SessionKey, sessionID string, cause error) {
+	logSecurityError(c, o, "Encryption Enforcement Failed", cause)
+	abortWithError(c, o, key, sessionID, http.StatusForbidden, CodeEncryptionRequired, o.Messages.EncryptionRequired)
+}
+
+// resolveSessionKey el sıkışma ile kurulmuş oturum anahtarını bulur.
+// Eski anahtar türetme modu açıksa ve el sıkışma yoksa token'dan türetir;
+// derived bu durumda true olur ve anahtar istek bitince sıfırlanmalıdır.
+func resolveSessionKey(token, sessionID string, o *Options) (sk *crypto.SessionKey, derived bool, err error) {
+	sk, err = crypto.LookupSessionKey(token, sessionID)
+	if err == nil {
+		// Grup politikası anahtar ömrünü daha kısa tutabilir
+		if o.now().Sub(sk.CreatedAt) >= o.KeyLifetime {
+			return nil, false, fmt.Errorf("%w: anahtar ömrü aşıldı", crypto.ErrSessionKeyNotFound)
+		}
+		return sk, false, nil
+	}
+
+	if o.LegacyKeyDerivation && errors.Is(err, crypto.ErrSessionKeyNotFound) {
+		key, err := crypto.DeriveKeys(token, sessionID)
+		if err != nil {
+			return nil, false, err
+		}
+		// Eski istemcilerin anahtar kimliği yoktur
+		return &crypto.SessionKey{Key: key}, true, nil
+	}
+
+	return nil, false, err
+}
+
 // handleRequestDecryption gelen isteği şifreler (body ve query)
-func handleRequestDecryption(c *gin.Context, token, sessionID string) error {
+func handleRequestDecryption(c *gin.Context, o *Options, key *crypto.SessionKey, sessionID string) error {
+	// Şifreli veri bu isteğin metoduna, yoluna ve oturumuna bağlıdır (AAD)
+	binding := crypto.RequestBinding(c.Request.Method, c.Request.URL.Path, sessionID)
+
 	// Query Parametrelerini Çözme (GET/OPTIONS/HEAD)
 	if encryptedQuery := c.Query("encrypted"); encryptedQuery != "" {
-		decryptedParams, err := crypto.DecryptQueryParams(encryptedQuery, token, sessionID)
+		// Query ve gövde aynı istekte şifrelenemez; şifreli olduğu bildirilen
+		// gövde çözülmeden handler'a bırakılmaz
+		if header := c.GetHeader(o.HeaderEncrypted); header == "true" || header == EncryptedStreamValue {
+			return fmt.Errorf("%w: şifreli query ile birlikte şifreli gövde", crypto.ErrBadEncoding)
+		}
+
+		decryptedParams, err := o.codec.DecryptQueryParams(encryptedQuery, key, binding)
 		if err != nil {
 			return fmt.Errorf("query decryption failed: %w", err)
 		}
@@ -86,7 +367,22 @@ func handleRequestDecryption(c *gin.Context, token, sessionID string) error {
 	}
 
 	// Body'yi Çözme (POST/PUT/PATCH/DELETE)
-	isEncryptedHeader := c.GetHeader(HeaderEncrypted)
+	isEncryptedHeader := c.GetHeader(o.HeaderEncrypted)
+
+	// Büyük gövdeler parçalı akış olarak gelir; belleğe alınmadan, handler
+	// okudukça çözülür. Akış JSON olarak ayrıştırılmaz.
+	if isEncryptedHeader == EncryptedStreamValue {
+		stream, err := o.codec.OpenStream(c.Request.Body, key, binding)
+		if err != nil {
+			return fmt.Errorf("body stream decryption failed: %w", err)
+		}
+
+		c.Request.Body = &streamBody{Reader: stream, Closer: c.Request.Body}
+		c.Request.ContentLength = -1
+		c.Request.Header.Del("Content-Length")
+		return nil
+	}
+
 	if isEncryptedHeader == "true" {
 		bodyBytes, err := io.ReadAll(c.Request.Body)
 		if err != nil {
@@ -96,7 +392,7 @@ func handleRequestDecryption(c *gin.Context, token, sessionID string) error {
 		encryptedData := string(bodyBytes)
 
 		// Body'yi çöz
-		decryptedData, err := crypto.DecryptData(encryptedData, token, sessionID)
+		decryptedData, err := o.codec.DecryptData(encryptedData, key, binding)
 		if err != nil {
 			return fmt.Errorf("body decryption failed: %w", err)
 		}
@@ -114,68 +410,138 @@ func handleRequestDecryption(c *gin.Context, token, sessionID string) error {
 	return nil
 }
 
-// handleResponseEncryption giden yanıtı şifreler
-func handleResponseEncryption(c *gin.Context, w *encryptedResponseWriter, token, sessionID string) error {
-	// API Handler'ı zaten bir hata döndürdüyse veya yanıt boşsa şifreleme
-	if c.IsAborted() || w.body.Len() == 0 {
-		return nil
+// wrappedResponse JSON nesnesi olmayan yanıtların şifreli zarf içindeki
+// biçimidir. İstemci _content_type alanını görünce yanıtı açar.
+type wrappedResponse struct {
+	ContentType string          `json:"_content_type"`
+	Body        json.RawMessage `json:"_body,omitempty"`
+	BodyBase64  string          `json:"_body_base64,omitempty"`
+}
+
+// responsePayload yanıt gövdesini şifrelenecek JSON değerine dönüştürür.
+// JSON nesneleri olduğu gibi şifrelenir; diziler, skaler değerler ve JSON
+// olmayan içerik orijinal Content-Type ile sarmalanır.
+func responsePayload(contentType string, body []byte) interface{} {
+	if strings.Contains(contentType, "application/json") && json.Valid(body) {
+		var object map[string]json.RawMessage
+		if json.Unmarshal(body, &object) == nil {
+			// Sarmalayıcıyla karıştırılmaması için _content_type taşıyan nesneler de sarmalanır
+			if _, reserved := object["_content_type"]; !reserved {
+				return json.RawMessage(body)
+			}
+		}
+		return wrappedResponse{ContentType: contentType, Body: json.RawMessage(body)}
 	}
 
-	// Yanıt gövdesini al
-	originalBody := w.body.Bytes()
-	var payload map[string]interface{}
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return wrappedResponse{ContentType: contentType, BodyBase64: base64.StdEncoding.EncodeToString(body)}
+}
 
-	// Yanıt JSON değilse (örn: dosya, text), şifreleme
-	if !strings.Contains(c.Writer.Header().Get("Content-Type"), "application/json") {
-		// Orijinal body'yi tekrar yaz
-		w.ResponseWriter.Write(originalBody)
-		return nil
+// handleResponseEncryption giden yanıtı şifreler. Handler'ın abort ettiği
+// yanıtlar da şifrelenir; gövdesiz hata durumları ortak hata biçimine çevrilir.
+func handleResponseEncryption(c *gin.Context, o *Options, w *encryptedResponseWriter) error {
+	// Akışa geçiş veya akış yazımı başarısız olduysa yanıt tamamlanmaz; başlamış
+	// bir akışın son parçası yazılmadığından istemci kesilmiş akışı reddeder
+	if w.err != nil {
+		return w.err
 	}
 
-	// JSON payload'u parse et
-	if err := json.Unmarshal(originalBody, &payload); err != nil {
-		// JSON olmayan veya hatalı JSON yanıtları atla
-		w.ResponseWriter.Write(originalBody)
-		return nil
+	// Akış moduna geçildiyse son parçayı yaz
+	if w.stream != nil {
+		return w.stream.Close()
 	}
 
-	// Payload'u şifrele
-	encryptedString, err := crypto.EncryptData(payload, token, sessionID)
-	if err != nil {
-		return fmt.Errorf("yanıt şifreleme başarısız: %w", err)
+	status := w.ResponseWriter.Status()
+
+	var payload interface{}
+	switch {
+	case w.body.Len() > 0:
+		// Yanıtın biçimi ne olursa olsun (nesne, dizi, metin, dosya) şifrelenir
+		payload = responsePayload(w.Header().Get("Content-Type"), w.body.Bytes())
+	case status >= http.StatusBadRequest:
+		// Örn. c.AbortWithStatus(403): durum kodu dışında bilgi sızdırmadan hata döndür
+		payload = ErrorResponse{Error: http.StatusText(status), Code: codeForStatus(status), RequestID: GetRequestID(c)}
+	default:
+		// Boş başarılı yanıt (örn. 204) şifrelenecek veri taşımaz
+		return nil
 	}
 
-	// Şifreli yanıtı JSON olarak formatla
-	finalResponse := fmt.Sprintf("\"%s\"", encryptedString) // Yanıtın kendisi şifreli string olacak
+	// Payload'u şifrele (yanıt yönü, isteğin metoduna ve yoluna bağlanır)
+	return writeEncrypted(w.ResponseWriter, o, w.key, w.binding, status, payload)
+}
 
-	// Response header'larını güncelle
-	c.Writer.Header().Set("Content-Type", "text/plain")
-	c.Writer.Header().Set(HeaderEncrypted, "true")
-	c.Writer.Header().Set("Content-Length", fmt.Sprintf("%d", len(finalResponse)))
+// streamBody şifreli akışı okuyan ve asıl gövdeyi kapatan istek gövdesidir
+type streamBody struct {
+	io.Reader
+	io.Closer
+}
 
-	// Şifreli yanıtı doğrudan yazarın asıl Write yöntemine yaz
-	w.ResponseWriter.WriteHeader(c.Writer.Status())
-	w.ResponseWriter.Write([]byte(finalResponse))
+var (
+	errMissingCredentials = errors.New("Authorization veya Session ID eksik")
+	errInvalidSession     = errors.New("oturum doğrulama başarısız")
+)
 
-	return nil
-}
+// getAuthAndSession JWT token ve SessionID'yi header'lardan alır ve token'ı doğrular.
+// Oturum kaydı yapılandırılmışsa oturumun token'ın subject'ine ait olduğu da
+// doğrulanır. Doğrulanan claim'ler Gin Context'e eklenir.
+func getAuthAndSession(c *gin.Context, o *Options) (token, sessionID string, err error) {
+	sessionID = c.GetHeader(o.HeaderSessionID)
+	if c.GetHeader(o.HeaderAuth) == "" || sessionID == "" {
+		return "", "", errMissingCredentials
+	}
 
-// getAuthAndSession JWT token ve SessionID'yi header'lardan alır.
-func getAuthAndSession(c *gin.Context) (token, sessionID string, err error) {
-	authHeader := c.GetHeader(HeaderAuth)
-	sessionID = c.GetHeader(HeaderSessionID)
+	token, claims, err := verifyBearer(c, o)
+	if err != nil {
+		return "", "", err
+	}
 
-	if authHeader == "" || sessionID == "" {
-		return "", "", fmt.Errorf("Authorization veya Session ID eksik")
+	if o.Sessions != nil {
+		if _, err := o.Sessions.Validate(sessionID, claims.Subject); err != nil {
+			return "", "", fmt.Errorf("%w: %w", errInvalidSession, err)
+		}
+	}
+
+	return token, sessionID, nil
+}
+
+// verifyBearer Authorization başlığındaki bearer token'ı doğrular ve
+// claim'leri Gin Context'e ekler
+func verifyBearer(c *gin.Context, o *Options) (string, *auth.Claims, error) {
+	authHeader := c.GetHeader(o.HeaderAuth)
+	if authHeader == "" {
+		return "", nil, errMissingCredentials
 	}
 
 	// Bearer Token'ı parse et
 	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return "", "", fmt.Errorf("geçersiz Authorization formatı")
+		return "", nil, fmt.Errorf("geçersiz Authorization formatı")
 	}
-	token = strings.TrimPrefix(authHeader, "Bearer ")
+	token := strings.TrimPrefix(authHeader, "Bearer ")
 
-	return token, sessionID, nil
+	// İmza ve claim doğrulaması anahtar türetmeden önce yapılır
+	verifier := o.verifier()
+	if verifier == nil {
+		return "", nil, fmt.Errorf("JWT doğrulayıcı yapılandırılmamış")
+	}
+	claims, err := verifier.Verify(token)
+	if err != nil {
+		return "", nil, fmt.Errorf("token doğrulama başarısız: %w", err)
+	}
+	c.Set("jwtClaims", claims)
+
+	return token, claims, nil
+}
+
+// GetClaims, API handler'ları içinde doğrulanmış JWT claim'lerine erişim sağlar
+func GetClaims(c *gin.Context) (*auth.Claims, bool) {
+	if val, exists := c.Get("jwtClaims"); exists {
+		if claims, ok := val.(*auth.Claims); ok {
+			return claims, true
+		}
+	}
+	return nil, false
 }
 
 // GetDecryptedBody, API handler'ları içinde çözülmüş body'ye erişim sağlar
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"secure-server/backend/pkg/crypto"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FuzzHandleRequestDecryption middleware'ın gövde, query ve akış çözme yolunu
+// saldırganın kontrol ettiği metot, query, X-Encrypted başlığı ve gövdeyle
+// çalıştırır. Değişmezler: panik yok, her hata log etiketine (failureCause)
+// sınıflandırılabilir ve başarılı bir gövde çözümü context'e yazılır.
+//
+//	go test ./backend/middleware -run '^$' -fuzz FuzzHandleRequestDecryption -fuzztime 30s
+func FuzzHandleRequestDecryption(f *testing.F) {
+	gin.SetMode(gin.TestMode)
+
+	key := &crypto.SessionKey{ID: "fuzz.0", Key: bytes.Repeat([]byte{0x24}, 32)}
+	const sessionID = "fuzz-session"
+	payload := map[string]interface{}{"_timestamp": time.Now().UnixMilli(), "_nonce": "00112233", "id": "1"}
+
+	body, err := crypto.EncryptDataWithKey(payload, key, crypto.RequestBinding("POST", "/api/data", sessionID))
+	if err != nil {
+		f.Fatal(err)
+	}
+	query, err := crypto.EncryptQueryParamsWithKey(payload, key, crypto.RequestBinding("GET", "/api/data", sessionID))
+	if err != nil {
+		f.Fatal(err)
+	}
+	var stream bytes.Buffer
+	sw, err := crypto.NewStreamWriter(&stream, key, crypto.RequestBinding("PUT", "/api/data", sessionID), 16)
+	if err != nil {
+		f.Fatal(err)
+	}
+	sw.Write([]byte(`{"id":"1","payload":"akış gövdesi"}`))
+	if err := sw.Close(); err != nil {
+		f.Fatal(err)
+	}
+
+	f.Add("POST", "", "true", []byte(body))
+	f.Add("GET", query, "", []byte(nil))
+	f.Add("PUT", "", EncryptedStreamValue, stream.Bytes())
+	f.Add("PUT", "", EncryptedStreamValue, stream.Bytes()[:stream.Len()-1])
+	f.Add("DELETE", "", "true", []byte(body[:len(body)-4]))
+	f.Add("PATCH", "", "true", []byte("bm90IGVuY3J5cHRlZA=="))
+	f.Add("GET", "-_-_", "", []byte(nil))
+	f.Add("POST", "", "false", []byte(`{"plain":true}`))
+
+	f.Fuzz(func(t *testing.T, method, encryptedQuery, encryptedHeader string, reqBody []byte) {
+		switch method {
+		case "GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "HEAD":
+		default:
+			method = "POST"
+		}
+
+		target := "/api/data"
+		if encryptedQuery != "" {
+			target += "?" + url.Values{"encrypted": {encryptedQuery}}.Encode()
+		}
+		req := httptest.NewRequest(method, target, bytes.NewReader(reqBody))
+		req.Header.Set(HeaderEncrypted, encryptedHeader)
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+		o := newOptions(WithReplayCache(crypto.NewMemoryReplayCache(16, 0)))
+
+		err := handleRequestDecryption(c, o, key, sessionID)
+		if err != nil {
+			if cause := failureCause(err); cause == "unknown" {
+				t.Fatalf("sınıflandırılmamış hata: %v", err)
+			}
+			return
+		}
+
+		switch {
+		case c.Query("encrypted") != "":
+			if _, ok := GetDecryptedQueryParams(c); !ok {
+				t.Fatal("query çözüldü ama context'e yazılmadı")
+			}
+		case c.GetHeader(HeaderEncrypted) == "true":
+			if _, ok := GetDecryptedBody(c); !ok {
+				t.Fatal("gövde çözüldü ama context'e yazılmadı")
+			}
+			if got := c.Request.Header.Get("Content-Type"); got != "application/json" {
+				t.Fatalf("Content-Type %q", got)
+			}
+		case c.GetHeader(HeaderEncrypted) == EncryptedStreamValue:
+			// Akış handler okudukça çözülür; kesik veya bozuk parçalar okuma hatası verir
+			_, _ = io.Copy(io.Discard, io.LimitReader(c.Request.Body, 1<<20))
+		}
+	})
+}
+
+// TestHandleRequestDecryptionRejectsReplay aynı şifreli gövdenin ikinci kez kabul edilmediğini doğrular
+func TestHandleRequestDecryptionRejectsReplay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	key := &crypto.SessionKey{ID: "replay.0", Key: bytes.Repeat([]byte{0x25}, 32)}
+	payload := map[string]interface{}{"_timestamp": time.Now().UnixMilli(), "_nonce": "replay-nonce"}
+	body, err := crypto.EncryptDataWithKey(payload, key, crypto.RequestBinding(http.MethodPost, "/api/data", "s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := newOptions(WithReplayCache(crypto.NewMemoryReplayCache(16, 0)))
+	for i, want := range []string{"", "replay"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/data", bytes.NewBufferString(body))
+		req.Header.Set(HeaderEncrypted, "true")
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = req
+
+		err := handleRequestDecryption(c, o, key, "s")
+		got := ""
+		if err != nil {
+			got = failureCause(err)
+		}
+		if got != want {
+			t.Errorf("istek %d: sonuç %q, beklenen %q (%v)", i+1, got, want, err)
+		}
+	}
+}
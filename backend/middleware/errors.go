@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"secure-server/backend/pkg/crypto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderRequestID her yanıtta dönen ve loglarla eşleştirmeye yarayan istek kimliği başlığıdır
+const HeaderRequestID = "X-Request-ID"
+
+// ErrorCode istemcinin hatayı mesaja bakmadan ayırt etmesini sağlayan sabit koddur
+type ErrorCode string
+
+const (
+	CodeUnauthorized       ErrorCode = "unauthorized"
+	CodeSessionInvalid     ErrorCode = "session_invalid"
+	CodeHandshakeRequired  ErrorCode = "handshake_required"
+	CodeBadRequest         ErrorCode = "bad_request"
+	CodeEncryptionRequired ErrorCode = "encryption_required"
+	CodeClockSkew          ErrorCode = "clock_skew"
+	CodeForbidden          ErrorCode = "forbidden"
+	CodeNotFound           ErrorCode = "not_found"
+	CodeInternal           ErrorCode = "internal_error"
+	CodeError              ErrorCode = "error"
+)
+
+// ErrorResponse middleware ve handler hatalarının ortak biçimidir. Oturum
+// anahtarı biliniyorsa şifreli yanıt olarak, bilinmiyorsa düz JSON olarak döner.
+type ErrorResponse struct {
+	Error     string    `json:"error"`
+	Code      ErrorCode `json:"code"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// newRequestID rastgele bir istek kimliği üretir, context'e ve yanıt başlığına ekler
+func newRequestID(c *gin.Context) string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+
+	requestID := hex.EncodeToString(id)
+	c.Set("requestID", requestID)
+	c.Header(HeaderRequestID, requestID)
+	return requestID
+}
+
+// GetRequestID, API handler'ları içinde isteğin kimliğine erişim sağlar
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("requestID")
+}
+
+// codeForStatus handler'ın gövdesiz abort durum kodunu hata koduna çevirir
+func codeForStatus(status int) ErrorCode {
+	switch {
+	case status == http.StatusBadRequest:
+		return CodeBadRequest
+	case status == http.StatusUnauthorized:
+		return CodeUnauthorized
+	case status == http.StatusForbidden:
+		return CodeForbidden
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status >= http.StatusInternalServerError:
+		return CodeInternal
+	}
+	return CodeError
+}
+
+// authFailure kimlik doğrulama hatasını istemci koduna ve mesajına çevirir.
+// Geçersiz oturum ayrı bir kodla bildirilir ki istemci yeni oturum açabilsin.
+func authFailure(o *Options, err error) (ErrorCode, string) {
+	if errors.Is(err, errInvalidSession) {
+		return CodeSessionInvalid, o.Messages.SessionInvalid
+	}
+	return CodeUnauthorized, o.Messages.Unauthorized
+}
+
+// decryptionFailure şifre çözme hatasını istemci koduna ve mesajına çevirir.
+// Doğrulanmış bir isteğin _timestamp'ı pencere dışındaysa (geride veya ileride)
+// clock_skew döner; istemci X-Server-Time ile saat farkını düzeltip tekrar
+// deneyebilir. Yön ve fark bildirilmez; diğer tüm hatalar bad_request'tir.
+func decryptionFailure(o *Options, err error) (ErrorCode, string) {
+	if errors.Is(err, crypto.ErrExpired) || errors.Is(err, crypto.ErrClockSkew) {
+		return CodeClockSkew, o.Messages.ClockSkew
+	}
+	return CodeBadRequest, o.Messages.BadRequest
+}
+
+// failureCause şifre çözme hatasını operatör logları için kısa bir etikete
+// çevirir. İstemci hangi kontrolün başarısız olduğunu hiçbir zaman görmez.
+func failureCause(err error) string {
+	switch {
+	case errors.Is(err, crypto.ErrBadEncoding):
+		return "bad_encoding"
+	case errors.Is(err, crypto.ErrAuthFailed):
+		return "auth_failed"
+	case errors.Is(err, crypto.ErrReplay):
+		return "replay"
+	case errors.Is(err, crypto.ErrExpired):
+		return "expired"
+	case errors.Is(err, crypto.ErrClockSkew):
+		return "clock_skew"
+	case errors.Is(err, crypto.ErrMalformedJSON):
+		return "malformed_json"
+	}
+	return "unknown"
+}
+
+// logSecurityError güvenlik hatasını istek bilgileri ve asıl nedeniyle
+// Options.Logger'a yazar; istemciye yalnızca genel mesaj ve kod döner
+func logSecurityError(c *gin.Context, o *Options, event string, err error, attrs ...slog.Attr) {
+	all := []slog.Attr{
+		slog.String("method", c.Request.Method),
+		slog.String("path", c.Request.URL.Path),
+		slog.String("request_id", GetRequestID(c)),
+	}
+	if err != nil {
+		all = append(all, slog.String("error", err.Error()))
+	}
+	all = append(all, attrs...)
+	o.logger().LogAttrs(c.Request.Context(), slog.LevelError, "[SECURITY ERROR] "+event, all...)
+}
+
+// abortWithError isteği ortak hata biçimiyle sonlandırır. key nil değilse hata
+// yanıtı şifrelenir; anahtar yoksa veya şifreleme başarısızsa düz JSON döner.
+func abortWithError(c *gin.Context, o *Options, key *crypto.SessionKey, sessionID string, status int, code ErrorCode, message string) {
+	resp := ErrorResponse{Error: message, Code: code, RequestID: GetRequestID(c)}
+
+	if key != nil {
+		binding := crypto.ResponseBinding(c.Request.Method, c.Request.URL.Path, sessionID)
+		err := writeEncrypted(c.Writer, o, key, binding, status, resp)
+		if err == nil {
+			c.Abort()
+			return
+		}
+		logSecurityError(c, o, "Error Response Encryption Failed", err)
+	}
+
+	c.AbortWithStatusJSON(status, resp)
+}
+
+// writeEncrypted payload'u şifreleyip tek parça şifreli yanıt olarak yazar
+func writeEncrypted(rw gin.ResponseWriter, o *Options, key *crypto.SessionKey, binding crypto.Binding, status int, payload interface{}) error {
+	// Düz metin bir kez üretilir; imza şifrelenen byte'ların aynısına bağlanır
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("yanıt şifreleme başarısız: JSON marshal hatası: %w", err)
+	}
+
+	encryptedString, err := o.codec.EncryptData(json.RawMessage(plaintext), key, binding)
+	if err != nil {
+		return fmt.Errorf("yanıt şifreleme başarısız: %w", err)
+	}
+
+	// Şifreli yanıtı JSON olarak formatla
+	finalResponse := fmt.Sprintf("\"%s\"", encryptedString) // Yanıtın kendisi şifreli string olacak
+
+	// İmza açıksa düz metin bağlamı ve durum koduyla birlikte imzalanır
+	if o.Signer != nil {
+		rw.Header().Set(HeaderSignature, o.Signer.Sign(plaintext, binding, status))
+		rw.Header().Set(HeaderSignatureKeyID, o.Signer.KeyID())
+	}
+
+	// Response header'larını güncelle
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.Header().Set(o.HeaderEncrypted, "true")
+	rw.Header().Set("Content-Length", fmt.Sprintf("%d", len(finalResponse)))
+
+	// Şifreli yanıtı doğrudan yazarın asıl Write yöntemine yaz
+	rw.WriteHeader(status)
+	_, err = rw.Write([]byte(finalResponse))
+	return err
+}
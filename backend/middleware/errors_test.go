@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSecurityErrorLogging güvenlik hatalarının asıl nedeniyle birlikte
+// Options.Logger'a yazıldığını ve istemciye yalnızca genel mesajın döndüğünü doğrular
+func TestSecurityErrorLogging(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	r := gin.New()
+	r.Use(EncryptionMiddleware(WithEnforceEncryption(true), WithLogger(logger)))
+	r.POST("/api/data", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/data", strings.NewReader(`{"a":1}`)))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("durum %d, beklenen %d", rec.Code, http.StatusForbidden)
+	}
+	if strings.Contains(rec.Body.String(), errMissingCredentials.Error()) {
+		t.Fatalf("hata nedeni istemciye sızdı: %s", rec.Body.String())
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("log kaydı okunamadı: %v (%q)", err, logs.String())
+	}
+	want := map[string]interface{}{
+		"level":      "ERROR",
+		"msg":        "[SECURITY ERROR] Encryption Enforcement Failed",
+		"method":     http.MethodPost,
+		"path":       "/api/data",
+		"request_id": rec.Header().Get(HeaderRequestID),
+		"error":      errMissingCredentials.Error(),
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, beklenen %v", k, entry[k], v)
+		}
+	}
+}
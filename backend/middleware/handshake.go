@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"secure-server/backend/pkg/crypto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handshakeRequest istemcinin geçici X25519 public key'ini ve tercih sırasıyla
+// desteklediği AEAD algoritmalarını taşır. Algoritma listesi olmayan eski
+// istemciler AES-256-GCM kullanır.
+type handshakeRequest struct {
+	ClientPublicKey string   `json:"client_public_key" binding:"required"`
+	Algorithms      []string `json:"algorithms"`
+}
+
+// HandshakeHandler ECDH el sıkışmasını yürütür. EncryptionMiddleware'dan önce,
+// düz metin olarak çalışır; token doğrulaması yine de zorunludur. Anahtar ömrü
+// ve başlık adları için EncryptionMiddleware ile aynı seçenekler verilmelidir.
+func HandshakeHandler(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts...)
+
+	return func(c *gin.Context) {
+		// El sıkışma öncesinde anahtar olmadığından hatalar düz JSON döner
+		newRequestID(c)
+
+		token, sessionID, err := getAuthAndSession(c, o)
+		if err != nil {
+			code, message := authFailure(o, err)
+			logSecurityError(c, o, "Handshake Auth Failed", err, slog.String("code", string(code)))
+			abortWithError(c, o, nil, "", http.StatusUnauthorized, code, message)
+			return
+		}
+
+		var req handshakeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortWithError(c, o, nil, "", http.StatusBadRequest, CodeBadRequest, o.Messages.BadHandshake)
+			return
+		}
+
+		alg, err := crypto.NegotiateAlgorithm(req.Algorithms, o.Algorithms)
+		if err != nil {
+			logSecurityError(c, o, "Handshake Failed", err, slog.String("session_id", sessionID))
+			abortWithError(c, o, nil, "", http.StatusBadRequest, CodeBadRequest, o.Messages.BadHandshake)
+			return
+		}
+
+		// Anahtar deposu kullanıcı başına sınırlıdır; claim'ler getAuthAndSession'da doğrulandı
+		var subject string
+		if claims, ok := GetClaims(c); ok {
+			subject = claims.Subject
+		}
+
+		result, err := crypto.PerformHandshake(req.ClientPublicKey, token, sessionID, subject, o.KeyLifetime, o.KeyRotation, alg.ID)
+		if err != nil {
+			logSecurityError(c, o, "Handshake Failed", err, slog.String("session_id", sessionID))
+			abortWithError(c, o, nil, "", http.StatusBadRequest, CodeBadRequest, o.Messages.BadHandshake)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
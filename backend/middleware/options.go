@@ -0,0 +1,375 @@
+package middleware
+
+import (
+	"log/slog"
+	"secure-server/backend/pkg/auth"
+	"secure-server/backend/pkg/crypto"
+	"secure-server/backend/pkg/session"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorMessages istemciye dönen genel hata mesajlarıdır. Ayrıntılar yalnızca loglanır.
+type ErrorMessages struct {
+	// Unauthorized token eksik/geçersiz olduğunda (401)
+	Unauthorized string
+	// HandshakeRequired oturum anahtarı bulunamadığında (401)
+	HandshakeRequired string
+	// BadRequest şifre çözme veya replay kontrolü başarısız olduğunda (400)
+	BadRequest string
+	// ClockSkew _timestamp sunucu saatine göre pencere dışında olduğunda (400)
+	ClockSkew string
+	// BadHandshake el sıkışma isteği geçersiz olduğunda (400)
+	BadHandshake string
+	// EncryptionRequired zorunlu şifreleme modunda kimliksiz veya şifresiz
+	// istekler için tek tip yanıttır (403)
+	EncryptionRequired string
+	// SessionInvalid oturum bulunamadığında, iptal edildiğinde veya süresi dolduğunda (401)
+	SessionInvalid string
+}
+
+// Options EncryptionMiddleware ve HandshakeHandler davranışını belirler.
+// Her route grubu kendi Options değeriyle farklı bir politika kullanabilir.
+type Options struct {
+	// TokenVerifier nil ise SetTokenVerifier ile verilen doğrulayıcı kullanılır
+	TokenVerifier auth.Verifier
+	// Sessions nil değilse X-Session-ID sunucunun verdiği, token'ın subject'ine
+	// ait ve süresi dolmamış bir oturum olmalıdır
+	Sessions session.Store
+
+	// KeyLifetime el sıkışma anahtarlarının ömrü; daha eski anahtarlar reddedilir
+	KeyLifetime time.Duration
+	// KeyRotation el sıkışma anahtarlarının epoch rotasyon politikası
+	KeyRotation crypto.RotationPolicy
+	// Algorithms el sıkışmada kabul edilen AEAD algoritmalarının adlarıdır;
+	// oturumun algoritması istemcinin tercih sırasına göre bunlardan seçilir
+	Algorithms []string
+	// ReplayWindow ve ClockSkew _timestamp kontrolünün sınırlarıdır
+	ReplayWindow time.Duration
+	ClockSkew    time.Duration
+	// Clock _timestamp ve anahtar ömrü kontrollerinin saatidir; nil ise
+	// crypto paketinin saati (crypto.SetClock) kullanılır
+	Clock crypto.Clock
+	// ReplayCache nil ise crypto paketinin varsayılan deposu kullanılır
+	ReplayCache crypto.ReplayCache
+	// Sequences nil değilse şifreli gövde ve query _timestamp/_nonce yerine
+	// oturum başına artan _seq taşımalıdır (bkz. crypto.SequenceStore)
+	Sequences crypto.SequenceStore
+	// AcceptLegacyFormat sürümsüz şifreli veri formatının kabul politikasıdır;
+	// sıfır değerde crypto.SetAcceptLegacyFormat ayarı izlenir
+	AcceptLegacyFormat crypto.LegacyFormat
+	// LegacyKeyDerivation el sıkışma yapmamış istemciler için token'dan anahtar türetir
+	LegacyKeyDerivation bool
+
+	// Başlık adları
+	HeaderSessionID string
+	HeaderAuth      string
+	HeaderEncrypted string
+
+	Messages ErrorMessages
+	// Logger güvenlik hatalarının ayrıntılı nedenini yazar; nil ise
+	// slog.Default() (varsayılan olarak standart log paketi) kullanılır
+	Logger *slog.Logger
+
+	// FallThrough true ise Authorization veya Session ID taşımayan istekler
+	// şifrelemesiz olarak handler'a iletilir; false ise 401 ile reddedilir.
+	FallThrough bool
+
+	// EnforceEncryption açıkken kimlik bilgisi olmayan, şifresiz gövde veya
+	// şifresiz query parametresi taşıyan istekler tek tip hata ile reddedilir
+	EnforceEncryption bool
+	// PlaintextRoutes middleware'ın hiç uygulanmadığı rotalardır ("METHOD /yol"
+	// veya tüm metodlar için "/yol"). Yol, Gin rota kalıbıyla (c.FullPath) eşleşir.
+	PlaintextRoutes map[string]struct{}
+	// PlaintextResponseRoutes isteği çözülen ancak yanıtı şifrelenmeyen rotalardır
+	// (aynı biçim). Bunların dışındaki tüm yanıtlar biçiminden bağımsız şifrelenir.
+	PlaintextResponseRoutes map[string]struct{}
+
+	// StreamThreshold aşılan yanıtlar tamponlanmak yerine parçalı akış
+	// olarak şifrelenir (0: yalnızca handler Flush çağırırsa). Tarayıcı
+	// istemcisi akış okuyamaz; tarayıcıya açık rotalarda 0 kalmalıdır.
+	StreamThreshold int
+	// StreamSegmentSize akış parçalarının düz metin boyutu (0: crypto.DefaultSegmentSize)
+	StreamSegmentSize int
+
+	// Signer nil değilse şifreli yanıtlar Ed25519 ile imzalanır; yanıtlar
+	// tamponlanır ve StreamThreshold ile Flush akışa geçirmez
+	Signer *crypto.ResponseSigner
+
+	codec *crypto.Codec
+}
+
+// Option Options üzerinde tek bir ayarı değiştirir
+type Option func(*Options)
+
+// defaultOptions mevcut (sabit kodlu) davranışı varsayılan olarak döndürür
+func defaultOptions() Options {
+	return Options{
+		KeyLifetime:         crypto.DefaultKeyLifetime,
+		KeyRotation:         crypto.DefaultRotationPolicy,
+		Algorithms:          crypto.DefaultAlgorithms,
+		ReplayWindow:        crypto.ReplayWindow,
+		ClockSkew:           crypto.MaxClockSkew,
+		LegacyKeyDerivation: legacyKeyDerivation,
+		HeaderSessionID:     HeaderSessionID,
+		HeaderAuth:          HeaderAuth,
+		HeaderEncrypted:     HeaderEncrypted,
+		Messages: ErrorMessages{
+			Unauthorized:       "Yetkisiz: Geçersiz veya süresi dolmuş token.",
+			HandshakeRequired:  "Yetkisiz: Oturum anahtarı bulunamadı, el sıkışma gerekli.",
+			BadRequest:         "Geçersiz İstek: Veri güvenliği kontrolü başarısız.",
+			ClockSkew:          "Geçersiz İstek: İstemci saati sunucu saatiyle uyumsuz.",
+			BadHandshake:       "Geçersiz el sıkışma isteği",
+			EncryptionRequired: "Erişim reddedildi: Şifreli ve kimliği doğrulanmış istek gerekli.",
+			SessionInvalid:     "Yetkisiz: Oturum geçersiz veya süresi dolmuş.",
+		},
+		FallThrough: true,
+	}
+}
+
+// newOptions varsayılanların üzerine verilen seçenekleri uygular
+func newOptions(opts ...Option) *Options {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	o.codec = &crypto.Codec{
+		ReplayWindow:       o.ReplayWindow,
+		ClockSkew:          o.ClockSkew,
+		ReplayCache:        o.ReplayCache,
+		AcceptLegacyFormat: o.AcceptLegacyFormat,
+		Clock:              o.Clock,
+		Sequences:          o.Sequences,
+	}
+	return &o
+}
+
+// now middleware'ın saatine göre şimdiki zamanı döndürür
+func (o *Options) now() time.Time {
+	if o.Clock != nil {
+		return o.Clock.Now()
+	}
+	return crypto.Now()
+}
+
+// logger güvenlik hatalarının yazıldığı logger'ı döndürür
+func (o *Options) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return slog.Default()
+}
+
+// isPlaintextRoute isteğin izin listesindeki bir rotaya ait olup olmadığını kontrol eder
+func (o *Options) isPlaintextRoute(c *gin.Context) bool {
+	return matchRoute(o.PlaintextRoutes, c)
+}
+
+// isPlaintextResponseRoute yanıtın şifrelenmeden gönderileceği rotaları kontrol eder
+func (o *Options) isPlaintextResponseRoute(c *gin.Context) bool {
+	return matchRoute(o.PlaintextResponseRoutes, c)
+}
+
+// matchRoute isteği "METHOD /yol" veya "/yol" biçimindeki rota kümesiyle eşleştirir
+func matchRoute(routes map[string]struct{}, c *gin.Context) bool {
+	if len(routes) == 0 {
+		return false
+	}
+
+	path := c.FullPath()
+	if path == "" {
+		// Eşleşen rota yoksa (404) gerçek yol kullanılır
+		path = c.Request.URL.Path
+	}
+
+	if _, ok := routes[c.Request.Method+" "+path]; ok {
+		return true
+	}
+	_, ok := routes[path]
+	return ok
+}
+
+// verifier yapılandırılmış JWT doğrulayıcısını döndürür
+func (o *Options) verifier() auth.Verifier {
+	if o.TokenVerifier != nil {
+		return o.TokenVerifier
+	}
+	return currentTokenVerifier()
+}
+
+// WithTokenVerifier bu middleware için JWT doğrulayıcısını belirler
+func WithTokenVerifier(v auth.Verifier) Option {
+	return func(o *Options) { o.TokenVerifier = v }
+}
+
+// WithSessionRegistry sunucu tarafı oturum doğrulamasını açar
+func WithSessionRegistry(s session.Store) Option {
+	return func(o *Options) { o.Sessions = s }
+}
+
+// WithKeyLifetime oturum anahtarlarının ömrünü belirler
+func WithKeyLifetime(d time.Duration) Option {
+	return func(o *Options) { o.KeyLifetime = d }
+}
+
+// WithKeyRotation oturum anahtarlarının epoch rotasyon politikasını belirler.
+// Sıfır değerli politika rotasyonu kapatır.
+func WithKeyRotation(p crypto.RotationPolicy) Option {
+	return func(o *Options) { o.KeyRotation = p }
+}
+
+// WithAlgorithms el sıkışmada kabul edilen AEAD algoritmalarını belirler
+// (örn. "xchacha20-poly1305"). Adlar crypto paketinde kayıtlı olmalıdır.
+func WithAlgorithms(names ...string) Option {
+	return func(o *Options) { o.Algorithms = names }
+}
+
+// WithReplayWindow _timestamp için kabul edilen en eski zamanı belirler
+func WithReplayWindow(d time.Duration) Option {
+	return func(o *Options) { o.ReplayWindow = d }
+}
+
+// WithClockSkew istemci saatinin ileri olmasına izin verilen payı belirler
+func WithClockSkew(d time.Duration) Option {
+	return func(o *Options) { o.ClockSkew = d }
+}
+
+// WithClock _timestamp penceresi ve anahtar ömrü kontrollerinde kullanılacak
+// saati belirler (örn. testlerde sabit saat, bilinen sapma için kaydırılmış saat)
+func WithClock(c crypto.Clock) Option {
+	return func(o *Options) { o.Clock = c }
+}
+
+// WithReplayCache nonce tekrarını izleyen depoyu belirler
+func WithReplayCache(rc crypto.ReplayCache) Option {
+	return func(o *Options) { o.ReplayCache = rc }
+}
+
+// WithSequenceNumbers zaman damgası yerine sıra numarasıyla replay korumasını
+// açar (örn. crypto.NewMemorySequenceStore). nil verilirse zaman damgası ve
+// nonce kontrolüne dönülür.
+func WithSequenceNumbers(store crypto.SequenceStore) Option {
+	return func(o *Options) { o.Sequences = store }
+}
+
+// WithAcceptLegacyFormat sürümsüz şifreli veri formatının kabulünü bu
+// middleware için açar/kapatır; crypto.SetAcceptLegacyFormat ayarının önüne geçer
+func WithAcceptLegacyFormat(enabled bool) Option {
+	return func(o *Options) { o.AcceptLegacyFormat = crypto.LegacyFormatOf(enabled) }
+}
+
+// WithLegacyKeyDerivation token'dan anahtar türetmeyi açar/kapatır
+func WithLegacyKeyDerivation(enabled bool) Option {
+	return func(o *Options) { o.LegacyKeyDerivation = enabled }
+}
+
+// WithHeaderNames özel başlık adlarını belirler; boş değerler varsayılanı korur
+func WithHeaderNames(sessionID, authorization, encrypted string) Option {
+	return func(o *Options) {
+		if sessionID != "" {
+			o.HeaderSessionID = sessionID
+		}
+		if authorization != "" {
+			o.HeaderAuth = authorization
+		}
+		if encrypted != "" {
+			o.HeaderEncrypted = encrypted
+		}
+	}
+}
+
+// WithErrorMessages istemciye dönen hata mesajlarını belirler; boş değerler varsayılanı korur
+func WithErrorMessages(m ErrorMessages) Option {
+	return func(o *Options) {
+		if m.Unauthorized != "" {
+			o.Messages.Unauthorized = m.Unauthorized
+		}
+		if m.HandshakeRequired != "" {
+			o.Messages.HandshakeRequired = m.HandshakeRequired
+		}
+		if m.BadRequest != "" {
+			o.Messages.BadRequest = m.BadRequest
+		}
+		if m.ClockSkew != "" {
+			o.Messages.ClockSkew = m.ClockSkew
+		}
+		if m.BadHandshake != "" {
+			o.Messages.BadHandshake = m.BadHandshake
+		}
+		if m.EncryptionRequired != "" {
+			o.Messages.EncryptionRequired = m.EncryptionRequired
+		}
+		if m.SessionInvalid != "" {
+			o.Messages.SessionInvalid = m.SessionInvalid
+		}
+	}
+}
+
+// WithFallThrough kimlik bilgisi olmayan isteklerin handler'a iletilip iletilmeyeceğini belirler
+func WithFallThrough(enabled bool) Option {
+	return func(o *Options) { o.FallThrough = enabled }
+}
+
+// WithEnforceEncryption zorunlu şifreleme modunu açar/kapatır. Açıkken kimlik
+// bilgisi olmayan istekler artık handler'a iletilmez (FallThrough kapanır).
+func WithEnforceEncryption(enabled bool) Option {
+	return func(o *Options) {
+		o.EnforceEncryption = enabled
+		if enabled {
+			o.FallThrough = false
+		}
+	}
+}
+
+// WithPlaintextRoutes şifrelemesiz kalabilecek rotaları ekler (örn. sağlık
+// kontrolü, el sıkışma). Biçim: "GET /api/health" veya "/api/health".
+func WithPlaintextRoutes(routes ...string) Option {
+	return func(o *Options) {
+		if o.PlaintextRoutes == nil {
+			o.PlaintextRoutes = make(map[string]struct{}, len(routes))
+		}
+		for _, route := range routes {
+			o.PlaintextRoutes[strings.TrimSpace(route)] = struct{}{}
+		}
+	}
+}
+
+// WithPlaintextResponseRoutes yanıtı şifrelenmeyecek rotaları ekler. İstek
+// şifre çözme ve doğrulama kurallarına tabi olmaya devam eder.
+func WithPlaintextResponseRoutes(routes ...string) Option {
+	return func(o *Options) {
+		if o.PlaintextResponseRoutes == nil {
+			o.PlaintextResponseRoutes = make(map[string]struct{}, len(routes))
+		}
+		for _, route := range routes {
+			o.PlaintextResponseRoutes[strings.TrimSpace(route)] = struct{}{}
+		}
+	}
+}
+
+// WithStreamThreshold bu boyutu (byte) aşan yanıtların parçalı akış olarak
+// şifrelenmesini sağlar. İstemcinin X-Encrypted: stream yanıtlarını çözebilmesi gerekir.
+func WithStreamThreshold(n int) Option {
+	return func(o *Options) { o.StreamThreshold = n }
+}
+
+// WithStreamSegmentSize akış parçalarının düz metin boyutunu belirler
+func WithStreamSegmentSize(n int) Option {
+	return func(o *Options) { o.StreamSegmentSize = n }
+}
+
+// WithResponseSigner şifreli yanıtların imzalanmasını açar. İmza ve anahtar
+// kimliği X-Signature ve X-Signature-Key-ID başlıklarında döner. İmza açıkken
+// yanıtlar parçalı akış olarak gönderilmez.
+func WithResponseSigner(s *crypto.ResponseSigner) Option {
+	return func(o *Options) { o.Signer = s }
+}
+
+// WithLogger güvenlik hatalarının (token, oturum, şifre çözme, yanıt
+// şifreleme) yazılacağı logger'ı belirler
+func WithLogger(l *slog.Logger) Option {
+	return func(o *Options) { o.Logger = l }
+}
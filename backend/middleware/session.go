@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"secure-server/backend/pkg/crypto"
+	"secure-server/backend/pkg/session"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionResponse oturum uç noktalarının döndürdüğü bilgilerdir
+type sessionResponse struct {
+	SessionID         string `json:"session_id"`
+	ExpiresIn         int    `json:"expires_in"`
+	AbsoluteExpiresIn int    `json:"absolute_expires_in"`
+}
+
+func newSessionResponse(s session.Session, now time.Time) sessionResponse {
+	return sessionResponse{
+		SessionID:         s.ID,
+		ExpiresIn:         int(s.ExpiresAt().Sub(now) / time.Second),
+		AbsoluteExpiresIn: int(s.AbsoluteExpiresAt.Sub(now) / time.Second),
+	}
+}
+
+// SessionCreateHandler doğrulanmış token'ın subject'ine bağlı yeni bir oturum
+// açar. Henüz oturum ve anahtar olmadığından düz metin çalışır; rota
+// EncryptionMiddleware'ın izin listesinde olmalıdır.
+func SessionCreateHandler(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts...)
+
+	return func(c *gin.Context) {
+		newRequestID(c)
+
+		if o.Sessions == nil {
+			logSecurityError(c, o, "Session Registry Not Configured", nil)
+			abortWithError(c, o, nil, "", http.StatusInternalServerError, CodeInternal, http.StatusText(http.StatusInternalServerError))
+			return
+		}
+
+		_, claims, err := verifyBearer(c, o)
+		if err != nil {
+			logSecurityError(c, o, "Session Auth Failed", err)
+			abortWithError(c, o, nil, "", http.StatusUnauthorized, CodeUnauthorized, o.Messages.Unauthorized)
+			return
+		}
+
+		s, err := o.Sessions.Create(claims.Subject)
+		if err != nil {
+			logSecurityError(c, o, "Session Create Failed", err, slog.String("subject", claims.Subject))
+			if errors.Is(err, session.ErrMissingSubject) {
+				abortWithError(c, o, nil, "", http.StatusUnauthorized, CodeUnauthorized, o.Messages.Unauthorized)
+				return
+			}
+			abortWithError(c, o, nil, "", http.StatusServiceUnavailable, CodeInternal, http.StatusText(http.StatusServiceUnavailable))
+			return
+		}
+
+		c.JSON(http.StatusOK, newSessionResponse(s, o.now()))
+	}
+}
+
+// SessionRefreshHandler mevcut oturumun boşta kalma süresini yeniler ve kalan
+// süreleri döndürür. EncryptionMiddleware arkasında çalışır.
+func SessionRefreshHandler(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts...)
+
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok || o.Sessions == nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		s, err := o.Sessions.Validate(c.GetHeader(o.HeaderSessionID), claims.Subject)
+		if err != nil {
+			logSecurityError(c, o, "Session Refresh Failed", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: o.Messages.SessionInvalid, Code: CodeSessionInvalid, RequestID: GetRequestID(c)})
+			return
+		}
+
+		c.JSON(http.StatusOK, newSessionResponse(s, o.now()))
+	}
+}
+
+// SessionRevokeHandler mevcut oturumu iptal eder ve oturumun tüm anahtarlarını
+// siler; sonraki istekler EncryptionMiddleware tarafından hemen reddedilir.
+// Yanıt hâlâ eldeki anahtarla şifrelenir. EncryptionMiddleware arkasında çalışır.
+func SessionRevokeHandler(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts...)
+
+	return func(c *gin.Context) {
+		if _, ok := GetClaims(c); !ok || o.Sessions == nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		// EncryptionMiddleware oturumun bu kullanıcıya ait olduğunu doğruladı
+		sessionID := c.GetHeader(o.HeaderSessionID)
+		o.Sessions.Revoke(sessionID)
+		crypto.PurgeSession(sessionID)
+
+		c.JSON(http.StatusOK, gin.H{"revoked": true})
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"secure-server/backend/pkg/crypto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Yanıt imzası başlıkları. İmza açıkken tüm şifreli yanıtlar tek parça
+// gönderilir ve bu başlıkları taşır.
+const (
+	HeaderSignature      = "X-Signature"
+	HeaderSignatureKeyID = "X-Signature-Key-ID"
+)
+
+// signingKeysResponse imza public key'lerinin JWKS biçimidir
+type signingKeysResponse struct {
+	Keys []crypto.SigningJWK `json:"keys"`
+}
+
+// SigningKeysHandler yanıt imzalarını doğrulamak için gereken public key'leri
+// JWKS olarak yayınlar. Kimlik gerektirmez ve düz metin çalışır; rota
+// EncryptionMiddleware'ın izin listesinde olmalıdır. İmza kapalıysa liste boştur.
+func SigningKeysHandler(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts...)
+
+	return func(c *gin.Context) {
+		resp := signingKeysResponse{Keys: []crypto.SigningJWK{}}
+		if o.Signer != nil {
+			resp.Keys = append(resp.Keys, o.Signer.JWK())
+		}
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"secure-server/backend/pkg/crypto"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSignedResponseNotStreamed imza açıkken eşiği aşan ve Flush çağıran
+// yanıtın tek parça gönderildiğini ve imzanın düz metni kapsadığını doğrular
+func TestSignedResponseNotStreamed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	signer, err := crypto.GenerateResponseSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &crypto.SessionKey{ID: "sig.0", Key: bytes.Repeat([]byte{0x26}, 32)}
+	binding := crypto.ResponseBinding(http.MethodGet, "/api/export", "s")
+	o := newOptions(WithResponseSigner(signer), WithStreamThreshold(16))
+
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	w := &encryptedResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}, o: o, key: key, binding: binding}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.WriteString(`{"rows":"` + strings.Repeat("x", 64) + `"}`); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	if err := handleResponseEncryption(c, o, w); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rec.Header().Get(HeaderEncrypted); got != "true" {
+		t.Fatalf("X-Encrypted %q, beklenen tek parça yanıt", got)
+	}
+	var encrypted string
+	if err := json.Unmarshal(rec.Body.Bytes(), &encrypted); err != nil {
+		t.Fatalf("şifreli yanıt JSON string değil: %s", rec.Body.Bytes())
+	}
+	plaintext, err := crypto.DecryptResponseWithKey(encrypted, key, binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature := rec.Header().Get(HeaderSignature)
+	if err := crypto.VerifyResponseSignature(signer.PublicKey(), rec.Header().Get(HeaderSignatureKeyID), plaintext, signature, binding, http.StatusOK); err != nil {
+		t.Fatalf("imza doğrulanamadı: %v", err)
+	}
+	if err := crypto.VerifyResponseSignature(signer.PublicKey(), signer.KeyID(), append(plaintext, ' '), signature, binding, http.StatusOK); err == nil {
+		t.Fatal("farklı düz metinle imza doğrulandı")
+	}
+}
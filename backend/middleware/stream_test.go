@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"secure-server/backend/pkg/crypto"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	streamKey     = &crypto.SessionKey{ID: "stream.0", Key: bytes.Repeat([]byte{0x31}, 32)}
+	streamBinding = crypto.ResponseBinding(http.MethodGet, "/api/export", "s")
+)
+
+// newStreamTestWriter middleware'ın kurduğu yanıt yazıcısını test kaydedicisi üzerinde oluşturur
+func newStreamTestWriter(opts ...Option) (*encryptedResponseWriter, *gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	w := &encryptedResponseWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}, o: newOptions(opts...), key: streamKey, binding: streamBinding}
+	return w, c, rec
+}
+
+// readResponseStream şifreli yanıt akışını çözer ve contentTypeLen | contentType
+// önekini ayırır
+func readResponseStream(t *testing.T, rec *httptest.ResponseRecorder) (contentType, body string) {
+	t.Helper()
+	if got := rec.Header().Get(HeaderEncrypted); got != EncryptedStreamValue {
+		t.Fatalf("X-Encrypted %q, beklenen %q", got, EncryptedStreamValue)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/octet-stream" {
+		t.Fatalf("Content-Type %q, beklenen application/octet-stream", got)
+	}
+
+	stream, err := crypto.OpenStream(bytes.NewReader(rec.Body.Bytes()), streamKey, streamBinding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plaintext) == 0 || len(plaintext) < 1+int(plaintext[0]) {
+		t.Fatalf("akış Content-Type öneki eksik: %q", plaintext)
+	}
+	n := 1 + int(plaintext[0])
+	return string(plaintext[1:n]), string(plaintext[n:])
+}
+
+// TestResponseStreamThreshold eşiği aşan yanıtın parçalı akışa geçtiğini ve
+// eşik altındaki yanıtın tek parça şifrelendiğini doğrular
+func TestResponseStreamThreshold(t *testing.T) {
+	body := `{"rows":"` + strings.Repeat("x", 200) + `"}`
+
+	w, c, rec := newStreamTestWriter(WithStreamThreshold(64), WithStreamSegmentSize(32))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	for _, part := range []string{body[:40], body[40:]} {
+		if _, err := w.WriteString(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := handleResponseEncryption(c, w.o, w); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("durum %d, beklenen %d", rec.Code, http.StatusCreated)
+	}
+	contentType, got := readResponseStream(t, rec)
+	if contentType != "application/json" || got != body {
+		t.Fatalf("akış (%q, %q), beklenen (application/json, %q)", contentType, got, body)
+	}
+
+	// Eşik altındaki yanıt tamponlanır
+	w, c, rec = newStreamTestWriter(WithStreamThreshold(len(body) + 1))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteString(body)
+	if err := handleResponseEncryption(c, w.o, w); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Header().Get(HeaderEncrypted); got != "true" {
+		t.Fatalf("X-Encrypted %q, beklenen tek parça yanıt", got)
+	}
+}
+
+// TestResponseStreamFlush Flush'ın eşik olmadan akışa geçtiğini, tamponu
+// hemen gönderdiğini ve sonraki yazmaların aynı akışa eklendiğini doğrular
+func TestResponseStreamFlush(t *testing.T) {
+	w, c, rec := newStreamTestWriter()
+	w.WriteString("data: bir\n\n")
+	w.Flush()
+
+	if !rec.Flushed {
+		t.Fatal("Flush alttaki yazıcıya iletilmedi")
+	}
+	if rec.Body.Len() == 0 {
+		t.Fatal("Flush tamponu göndermedi")
+	}
+
+	w.WriteString("data: iki\n\n")
+	if err := handleResponseEncryption(c, w.o, w); err != nil {
+		t.Fatal(err)
+	}
+
+	// Content-Type verilmezse ilk tampondan tahmin edilir
+	contentType, got := readResponseStream(t, rec)
+	if contentType != "text/plain; charset=utf-8" || got != "data: bir\n\ndata: iki\n\n" {
+		t.Fatalf("akış (%q, %q)", contentType, got)
+	}
+}
+
+// TestResponseStreamStartFailure akışa geçilemezse tamponun korunduğunu,
+// sonraki yazmaların reddedildiğini ve hatanın yanıt şifreleme hatası olarak
+// döndüğünü doğrular
+func TestResponseStreamStartFailure(t *testing.T) {
+	w, c, rec := newStreamTestWriter(WithStreamThreshold(8))
+	w.Header().Set("Content-Type", "text/plain; "+strings.Repeat("x", 255))
+
+	if _, err := w.WriteString("ilk parça tamponda kalır"); err == nil {
+		t.Fatal("uzun Content-Type ile akışa geçildi")
+	}
+	if w.body.String() != "ilk parça tamponda kalır" {
+		t.Fatalf("tampon kayboldu: %q", w.body.String())
+	}
+	if _, err := w.WriteString("devam"); err == nil {
+		t.Fatal("hatadan sonra yazma kabul edildi")
+	}
+	w.Flush()
+
+	if err := handleResponseEncryption(c, w.o, w); err == nil {
+		t.Fatal("akış hatası yanıt şifreleme hatası olarak dönmedi")
+	}
+	if w.ResponseWriter.Written() || rec.Body.Len() != 0 {
+		t.Fatalf("başarısız akış yanıt yazdı: %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderServerTime EncryptionMiddleware'dan geçen her yanıtta dönen sunucu
+// saatidir (Unix milisaniye, _timestamp ile aynı birim). Saati sapan istemciler
+// farkı hesaplayıp _timestamp'ı sunucu saatine göre üretir.
+const HeaderServerTime = "X-Server-Time"
+
+// timeResponse GET /time yanıtıdır
+type timeResponse struct {
+	ServerTime int64 `json:"server_time"`
+}
+
+// setServerTime yanıta X-Server-Time başlığını ekler
+func setServerTime(c *gin.Context, o *Options) {
+	c.Header(HeaderServerTime, strconv.FormatInt(o.now().UnixMilli(), 10))
+}
+
+// TimeHandler sunucu saatini döndürür; istemci ilk şifreli istekten önce saat
+// farkını buradan öğrenebilir. Kimlik gerektirmez ve düz metin çalışır; rota
+// EncryptionMiddleware'ın izin listesinde olmalıdır.
+func TimeHandler(opts ...Option) gin.HandlerFunc {
+	o := newOptions(opts...)
+
+	return func(c *gin.Context) {
+		now := o.now().UnixMilli()
+		c.Header(HeaderServerTime, strconv.FormatInt(now, 10))
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, timeResponse{ServerTime: now})
+	}
+}
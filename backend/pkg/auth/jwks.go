@@ -0,0 +1,352 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeyProvider token başlığındaki kid ve alg değerine göre doğrulama anahtarı sağlar.
+// Dönen anahtar tipi: HS256 için []byte, RS256 için *rsa.PublicKey,
+// ES256 için *ecdsa.PublicKey, EdDSA için ed25519.PublicKey.
+type KeyProvider interface {
+	Key(kid, alg string) (interface{}, error)
+}
+
+// jwk tek bir JSON Web Key kaydıdır (RFC 7517)
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// EC / OKP
+	X string `json:"x"`
+	Y string `json:"y"`
+
+	// oct (simetrik)
+	K string `json:"k"`
+}
+
+type verificationKey struct {
+	alg string
+	key interface{}
+}
+
+// KeySet kid -> anahtar eşlemesi tutan statik bir KeyProvider'dır
+type KeySet struct {
+	sync.RWMutex
+	keys map[string]verificationKey
+}
+
+// NewKeySet boş bir anahtar kümesi oluşturur
+func NewKeySet() *KeySet {
+	return &KeySet{keys: make(map[string]verificationKey)}
+}
+
+// NewHMACKeySet tek bir HS256 paylaşılan anahtarı içeren küme oluşturur
+func NewHMACKeySet(secret []byte) *KeySet {
+	ks := NewKeySet()
+	ks.Add("", AlgHS256, secret)
+	return ks
+}
+
+// Add kümeye bir anahtar ekler. kid boş olabilir; bu durumda anahtar
+// kid taşımayan token'lar için kullanılır.
+func (ks *KeySet) Add(kid, alg string, key interface{}) {
+	ks.Lock()
+	defer ks.Unlock()
+	ks.keys[kid] = verificationKey{alg: alg, key: key}
+}
+
+// Len kümedeki anahtar sayısını döndürür
+func (ks *KeySet) Len() int {
+	ks.RLock()
+	defer ks.RUnlock()
+	return len(ks.keys)
+}
+
+// Key KeyProvider arayüzünü uygular
+func (ks *KeySet) Key(kid, alg string) (interface{}, error) {
+	ks.RLock()
+	defer ks.RUnlock()
+
+	vk, exists := ks.keys[kid]
+	if !exists && kid == "" && len(ks.keys) == 1 {
+		// kid taşımayan token, tek anahtarlı kümede o anahtarla doğrulanır
+		for _, only := range ks.keys {
+			vk, exists = only, true
+		}
+	}
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+
+	// Anahtar belirli bir algoritmaya bağlıysa token başka bir algoritma kullanamaz
+	if vk.alg != "" && vk.alg != alg {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	return vk.key, nil
+}
+
+// ParseJWKS JWKS JSON belgesini bir KeySet'e dönüştürür. Desteklenmeyen veya
+// okunamayan anahtarlar (örn. başka bir eğri, kısa RSA) atlanır; kümede hiç
+// kullanılabilir anahtar kalmazsa hata döner.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("JWKS parse hatası: %w", err)
+	}
+
+	ks := NewKeySet()
+	for _, k := range doc.Keys {
+		// Yalnızca imza doğrulama anahtarları
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Tek bir desteklenmeyen anahtar tüm kümeyi (ve rotasyonu) bozmamalı
+		alg, key, err := k.toKey()
+		if err != nil {
+			continue
+		}
+		ks.Add(k.Kid, alg, key)
+	}
+
+	if ks.Len() == 0 {
+		return nil, errors.New("JWKS içinde kullanılabilir anahtar yok")
+	}
+	return ks, nil
+}
+
+// LoadJWKSFile yerel bir dosyadan JWKS yükler
+func LoadJWKSFile(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("JWKS dosyası okunamadı: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+// FetchJWKS bir HTTP uç noktasından JWKS indirir
+func FetchJWKS(client *http.Client, url string) (*KeySet, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("JWKS indirilemedi: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS uç noktası beklenmeyen durum döndürdü: %d", resp.StatusCode)
+	}
+
+	// Aşırı büyük yanıtlara karşı sınır (1 MB)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("JWKS yanıtı okunamadı: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+// DefaultJWKSMinInterval iki JWKS indirme denemesi arasındaki en kısa süredir
+const DefaultJWKSMinInterval = 30 * time.Second
+
+// RemoteKeySet JWKS'i bir URL'den çeker ve periyodik olarak yeniler.
+// Bilinmeyen bir kid geldiğinde (anahtar rotasyonu) de yeniden indirilir.
+// İndirme kilit dışında yapılır, eşzamanlı yenilemeler tek isteğe indirgenir
+// ve denemeler arasında en az minInterval beklenir. Yenileme başarısız olursa
+// eldeki küme kullanılmaya devam eder.
+type RemoteKeySet struct {
+	sync.Mutex
+	url         string
+	client      *http.Client
+	refresh     time.Duration
+	minInterval time.Duration
+
+	current *KeySet
+	// fetchedAt son indirme denemesinin zamanı, lastErr sonucudur
+	fetchedAt time.Time
+	lastErr   error
+	// inflight devam eden indirmedir; eşzamanlı çağrılar onun sonucunu bekler
+	inflight *jwksFetch
+}
+
+// jwksFetch devam eden bir JWKS indirmesidir
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewRemoteKeySet uzak bir JWKS kaynağı oluşturur; ilk indirme ilk kullanımda yapılır
+func NewRemoteKeySet(url string, refresh time.Duration) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:         url,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		refresh:     refresh,
+		minInterval: DefaultJWKSMinInterval,
+	}
+}
+
+// Key KeyProvider arayüzünü uygular
+func (r *RemoteKeySet) Key(kid, alg string) (interface{}, error) {
+	ks, fetchedAt := r.snapshot()
+	if ks == nil || time.Since(fetchedAt) > r.refresh {
+		if err := r.fetch(); err != nil && ks == nil {
+			return nil, err
+		}
+		ks, _ = r.snapshot()
+	}
+	if ks == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	key, err := ks.Key(kid, alg)
+	if errors.Is(err, ErrKeyNotFound) {
+		// Bilinmeyen kid anahtar rotasyonu olabilir; minInterval içinde tekrar indirilmez
+		if r.fetch() == nil {
+			ks, _ = r.snapshot()
+			return ks.Key(kid, alg)
+		}
+	}
+	return key, err
+}
+
+// snapshot güncel anahtar kümesini ve son indirme denemesinin zamanını döndürür
+func (r *RemoteKeySet) snapshot() (*KeySet, time.Time) {
+	r.Lock()
+	defer r.Unlock()
+	return r.current, r.fetchedAt
+}
+
+// fetch JWKS'i yeniden indirir. Devam eden bir indirme varsa onun sonucunu
+// bekler; son denemeden bu yana minInterval geçmediyse indirmeden son
+// denemenin sonucunu döndürür.
+func (r *RemoteKeySet) fetch() error {
+	r.Lock()
+	if f := r.inflight; f != nil {
+		r.Unlock()
+		<-f.done
+		return f.err
+	}
+	if !r.fetchedAt.IsZero() && time.Since(r.fetchedAt) < r.minInterval {
+		err := r.lastErr
+		r.Unlock()
+		return err
+	}
+	f := &jwksFetch{done: make(chan struct{})}
+	r.inflight = f
+	r.Unlock()
+
+	ks, err := FetchJWKS(r.client, r.url)
+
+	r.Lock()
+	// Başarısız denemeler de zaman damgasını günceller (uç noktayı boğmamak için)
+	r.fetchedAt = time.Now()
+	r.lastErr = err
+	if err == nil {
+		r.current = ks
+	}
+	r.inflight = nil
+	r.Unlock()
+
+	f.err = err
+	close(f.done)
+	return err
+}
+
+// toKey JWK kaydını Go anahtar tipine dönüştürür
+func (k jwk) toKey() (string, interface{}, error) {
+	switch k.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return "", nil, errors.New("geçersiz simetrik anahtar")
+		}
+		return orDefault(k.Alg, AlgHS256), secret, nil
+
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return "", nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return "", nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 {
+			return "", nil, errors.New("geçersiz RSA üssü")
+		}
+		// 2048 bit altı RSA anahtarları kabul edilmez
+		if n.BitLen() < 2048 {
+			return "", nil, errors.New("RSA anahtarı çok kısa")
+		}
+		return orDefault(k.Alg, AlgRS256), &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return "", nil, fmt.Errorf("desteklenmeyen eğri: %s", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return "", nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return "", nil, err
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !pub.Curve.IsOnCurve(x, y) {
+			return "", nil, errors.New("EC noktası eğri üzerinde değil")
+		}
+		return orDefault(k.Alg, AlgES256), pub, nil
+
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return "", nil, fmt.Errorf("desteklenmeyen eğri: %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return "", nil, errors.New("geçersiz Ed25519 anahtarı")
+		}
+		return orDefault(k.Alg, AlgEdDSA), ed25519.PublicKey(x), nil
+	}
+
+	return "", nil, fmt.Errorf("desteklenmeyen anahtar tipi: %s", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("geçersiz base64url tamsayı")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func orDefault(val, def string) string {
+	if val == "" {
+		return def
+	}
+	return val
+}
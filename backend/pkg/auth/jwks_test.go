@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// okpJWK Ed25519 public key'inin JWKS kaydıdır
+func okpJWK(kid string, pub ed25519.PublicKey) map[string]string {
+	return map[string]string{"kty": "OKP", "crv": "Ed25519", "kid": kid, "x": base64.RawURLEncoding.EncodeToString(pub)}
+}
+
+func newEd25519Key(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func TestParseJWKSSkipsUnsupportedKeys(t *testing.T) {
+	pub, _ := newEd25519Key(t)
+	unsupported := []map[string]string{
+		{"kty": "EC", "crv": "P-384", "kid": "p384", "x": "AQ", "y": "AQ"},
+		{"kty": "RSA", "kid": "short", "n": "AQAB", "e": "AQAB"},
+		{"kty": "OKP", "crv": "X25519", "kid": "x25519", "x": "AQ"},
+		{"kty": "oct", "kid": "empty"},
+	}
+
+	doc, _ := json.Marshal(map[string]interface{}{"keys": append(unsupported, okpJWK("ed", pub))})
+	ks, err := ParseJWKS(doc)
+	if err != nil {
+		t.Fatalf("desteklenmeyen anahtarlar tüm kümeyi bozdu: %v", err)
+	}
+	if ks.Len() != 1 {
+		t.Fatalf("%d anahtar, beklenen 1", ks.Len())
+	}
+	if _, err := ks.Key("ed", AlgEdDSA); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, _ = json.Marshal(map[string]interface{}{"keys": unsupported})
+	if _, err := ParseJWKS(doc); err == nil {
+		t.Fatal("kullanılabilir anahtarı olmayan küme kabul edildi")
+	}
+}
+
+// jwksServer anahtarları değiştirilebilen ve istekleri sayan bir JWKS uç noktasıdır
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    []map[string]string
+	failing bool
+	hits    atomic.Int32
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := &jwksServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(failing bool, keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+	s.keys = keys
+}
+
+// age son indirme denemesini d kadar geriye alır
+func (r *RemoteKeySet) age(d time.Duration) {
+	r.Lock()
+	defer r.Unlock()
+	r.fetchedAt = r.fetchedAt.Add(-d)
+}
+
+func TestRemoteKeySetRefresh(t *testing.T) {
+	oldPub, _ := newEd25519Key(t)
+	newPub, _ := newEd25519Key(t)
+	srv := newJWKSServer(t)
+	srv.set(false, okpJWK("old", oldPub))
+	r := NewRemoteKeySet(srv.URL, time.Hour)
+
+	expect := func(kid string, want error, hits int32) {
+		t.Helper()
+		if _, err := r.Key(kid, AlgEdDSA); !errors.Is(err, want) {
+			t.Fatalf("kid %s: hata %v, beklenen %v", kid, err, want)
+		}
+		if got := srv.hits.Load(); got != hits {
+			t.Fatalf("kid %s: %d indirme, beklenen %d", kid, got, hits)
+		}
+	}
+
+	expect("old", nil, 1)
+
+	// Rotasyon: bilinmeyen kid minInterval dolmadan yeniden indirme yaptırmaz
+	srv.set(false, okpJWK("old", oldPub), okpJWK("new", newPub))
+	expect("new", ErrKeyNotFound, 1)
+	r.age(DefaultJWKSMinInterval)
+	expect("new", nil, 2)
+
+	// Uç nokta hata verirse süresi dolan küme kullanılmaya devam eder
+	srv.set(true)
+	r.age(time.Hour)
+	expect("new", nil, 3)
+	expect("unknown", ErrKeyNotFound, 3)
+
+	// Uç nokta düzelince periyodik yenileme kaldırılan anahtarı düşürür
+	srv.set(false, okpJWK("new", newPub))
+	r.age(time.Hour)
+	expect("old", ErrKeyNotFound, 4)
+}
+
+func TestRemoteKeySetInitialFailure(t *testing.T) {
+	srv := newJWKSServer(t)
+	srv.set(true)
+	r := NewRemoteKeySet(srv.URL, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if _, err := r.Key("", AlgEdDSA); err == nil || errors.Is(err, ErrKeyNotFound) {
+			t.Fatalf("deneme %d: hata %v, beklenen indirme hatası", i+1, err)
+		}
+	}
+	if got := srv.hits.Load(); got != 1 {
+		t.Fatalf("%d indirme, beklenen 1 (minInterval)", got)
+	}
+}
+
+// TestRemoteKeySetConcurrentFetch eşzamanlı çağrıların tek bir indirmeyi
+// beklediğini ve indirme sürerken kilidin tutulmadığını doğrular
+func TestRemoteKeySetConcurrentFetch(t *testing.T) {
+	pub, priv := newEd25519Key(t)
+	release := make(chan struct{})
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{okpJWK("ed", pub)}})
+	}))
+	t.Cleanup(srv.Close)
+
+	keys := NewRemoteKeySet(srv.URL, time.Hour)
+	verifier := NewJWTVerifier(keys, VerifierConfig{})
+	token := signToken(t, AlgEdDSA, "ed", priv, validClaims())
+
+	const workers = 16
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			_, err := verifier.Verify(token)
+			errs <- err
+		}()
+	}
+
+	for hits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// İndirme sürerken kilit serbesttir
+	locked := make(chan struct{})
+	go func() {
+		keys.snapshot()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("indirme sırasında kilit tutuluyor")
+	}
+	close(release)
+
+	for i := 0; i < workers; i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("%d indirme, beklenen 1", got)
+	}
+}
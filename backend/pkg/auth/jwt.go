@@ -0,0 +1,336 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	ucrypto "secure-server/backend/pkg/crypto"
+	"strings"
+	"time"
+)
+
+// Desteklenen imza algoritmaları
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Doğrulama hataları - errors.Is ile kontrol edilebilir
+var (
+	ErrMalformedToken       = errors.New("token formatı geçersiz")
+	ErrUnsupportedAlgorithm = errors.New("desteklenmeyen imza algoritması")
+	ErrKeyNotFound          = errors.New("doğrulama anahtarı bulunamadı")
+	ErrInvalidSignature     = errors.New("token imzası geçersiz")
+	ErrTokenExpired         = errors.New("token süresi dolmuş")
+	ErrTokenNotYetValid     = errors.New("token henüz geçerli değil")
+	ErrInvalidIssuer        = errors.New("token yayıncısı (iss) geçersiz")
+	ErrInvalidAudience      = errors.New("token hedef kitlesi (aud) geçersiz")
+)
+
+// Claims doğrulanmış bir JWT'nin içeriğini taşır
+type Claims struct {
+	Issuer    string
+	Subject   string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	IssuedAt  time.Time
+	ID        string
+
+	// Raw tüm claim'leri (özel alanlar dahil) içerir
+	Raw map[string]interface{}
+}
+
+// Verifier bir bearer token'ı doğrular ve claim'lerini döndürür
+type Verifier interface {
+	Verify(token string) (*Claims, error)
+}
+
+// VerifierConfig JWT doğrulama politikasını tanımlar
+type VerifierConfig struct {
+	// Issuer boş değilse "iss" claim'i bununla birebir eşleşmelidir
+	Issuer string
+	// Audience boş değilse "aud" claim'i bu değeri içermelidir
+	Audience string
+	// Leeway exp/nbf/iat kontrollerinde tolere edilen saat farkı
+	Leeway time.Duration
+	// Algorithms kabul edilen algoritmalar; boşsa tüm desteklenenler kabul edilir
+	Algorithms []string
+	// Clock exp/nbf/iat kontrollerinin saatidir; nil ise crypto paketinin
+	// saati (crypto.SetClock, varsayılan sistem saati) kullanılır
+	Clock ucrypto.Clock
+}
+
+// JWTVerifier imzalı JWT'leri bir KeyProvider üzerinden doğrular
+type JWTVerifier struct {
+	keys   KeyProvider
+	config VerifierConfig
+}
+
+// NewJWTVerifier yeni bir JWT doğrulayıcı oluşturur
+func NewJWTVerifier(keys KeyProvider, config VerifierConfig) *JWTVerifier {
+	return &JWTVerifier{keys: keys, config: config}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Verify token'ın imzasını ve zaman/yayıncı/hedef claim'lerini doğrular
+func (v *JWTVerifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return nil, ErrMalformedToken
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	// "none" ve bilinmeyen algoritmalar burada elenir (imzasız token kabul edilmez)
+	if !v.algorithmAllowed(header.Alg) {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) == 0 {
+		return nil, ErrMalformedToken
+	}
+
+	key, err := v.keys.Key(header.Kid, header.Alg)
+	if err != nil {
+		return nil, err
+	}
+
+	signingInput := []byte(parts[0] + "." + parts[1])
+	if err := verifySignature(header.Alg, key, signingInput, signature); err != nil {
+		return nil, err
+	}
+
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	claims, err := parseClaims(payloadJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// algorithmAllowed algoritmanın hem desteklendiğini hem de yapılandırmada izinli olduğunu kontrol eder
+func (v *JWTVerifier) algorithmAllowed(alg string) bool {
+	switch alg {
+	case AlgHS256, AlgRS256, AlgES256, AlgEdDSA:
+	default:
+		return false
+	}
+
+	if len(v.config.Algorithms) == 0 {
+		return true
+	}
+	for _, allowed := range v.config.Algorithms {
+		if allowed == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// now doğrulamada kullanılan şimdiki zamanı döndürür
+func (v *JWTVerifier) now() time.Time {
+	if v.config.Clock != nil {
+		return v.config.Clock.Now()
+	}
+	return ucrypto.Now()
+}
+
+// validateClaims exp, nbf, iat, iss ve aud kontrollerini yapar
+func (v *JWTVerifier) validateClaims(claims *Claims) error {
+	now := v.now()
+	leeway := v.config.Leeway
+
+	// Süresiz token kabul edilmez
+	if claims.ExpiresAt.IsZero() || !now.Before(claims.ExpiresAt.Add(leeway)) {
+		return ErrTokenExpired
+	}
+
+	if !claims.NotBefore.IsZero() && now.Add(leeway).Before(claims.NotBefore) {
+		return ErrTokenNotYetValid
+	}
+
+	if !claims.IssuedAt.IsZero() && now.Add(leeway).Before(claims.IssuedAt) {
+		return ErrTokenNotYetValid
+	}
+
+	if v.config.Issuer != "" && claims.Issuer != v.config.Issuer {
+		return ErrInvalidIssuer
+	}
+
+	if v.config.Audience != "" {
+		found := false
+		for _, aud := range claims.Audience {
+			if aud == v.config.Audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrInvalidAudience
+		}
+	}
+
+	return nil
+}
+
+// verifySignature algoritmaya göre imzayı doğrular. Anahtar tipi algoritmayla
+// uyuşmuyorsa (örn. RSA public key ile HS256) token reddedilir.
+func verifySignature(alg string, key interface{}, signingInput, signature []byte) error {
+	digest := sha256.Sum256(signingInput)
+
+	switch alg {
+	case AlgHS256:
+		secret, ok := key.([]byte)
+		if !ok || len(secret) == 0 {
+			return ErrKeyNotFound
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signingInput)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+
+	case AlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrKeyNotFound
+		}
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+
+	case AlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrKeyNotFound
+		}
+		// JWS ES256 imzası sabit uzunlukta r||s (2x32 byte) formatındadır
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(pub, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+
+	case AlgEdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrKeyNotFound
+		}
+		if !ed25519.Verify(pub, signingInput, signature) {
+			return ErrInvalidSignature
+		}
+
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+
+	return nil
+}
+
+// parseClaims JWT payload'unu Claims yapısına dönüştürür
+func parseClaims(payloadJSON []byte) (*Claims, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(payloadJSON, &raw); err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	claims := &Claims{Raw: raw}
+	var err error
+
+	if claims.Issuer, err = stringClaim(raw, "iss"); err != nil {
+		return nil, err
+	}
+	if claims.Subject, err = stringClaim(raw, "sub"); err != nil {
+		return nil, err
+	}
+	if claims.ID, err = stringClaim(raw, "jti"); err != nil {
+		return nil, err
+	}
+	if claims.ExpiresAt, err = timeClaim(raw, "exp"); err != nil {
+		return nil, err
+	}
+	if claims.NotBefore, err = timeClaim(raw, "nbf"); err != nil {
+		return nil, err
+	}
+	if claims.IssuedAt, err = timeClaim(raw, "iat"); err != nil {
+		return nil, err
+	}
+
+	// "aud" tek bir string veya string dizisi olabilir (RFC 7519 4.1.3)
+	switch aud := raw["aud"].(type) {
+	case nil:
+	case string:
+		claims.Audience = []string{aud}
+	case []interface{}:
+		for _, item := range aud {
+			s, ok := item.(string)
+			if !ok {
+				return nil, ErrMalformedToken
+			}
+			claims.Audience = append(claims.Audience, s)
+		}
+	default:
+		return nil, ErrMalformedToken
+	}
+
+	return claims, nil
+}
+
+func stringClaim(raw map[string]interface{}, name string) (string, error) {
+	val, exists := raw[name]
+	if !exists {
+		return "", nil
+	}
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s claim'i string değil", ErrMalformedToken, name)
+	}
+	return s, nil
+}
+
+func timeClaim(raw map[string]interface{}, name string) (time.Time, error) {
+	val, exists := raw[name]
+	if !exists {
+		return time.Time{}, nil
+	}
+	seconds, ok := val.(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %s claim'i sayı değil", ErrMalformedToken, name)
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), nil
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	ucrypto "secure-server/backend/pkg/crypto"
+	"testing"
+	"time"
+)
+
+var testHMACSecret = []byte("jwt-test-secret")
+
+// signToken claims'i verilen algoritma ve anahtarla imzalı bir JWT'ye dönüştürür.
+// key HS256 için []byte, RS256 için *rsa.PrivateKey, EdDSA için
+// ed25519.PrivateKey olmalıdır; diğer algoritmalar imzasız kalır.
+func signToken(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(input))
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(input))
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// validClaims bir saat geçerli, alice'e ait claim'ler döndürür
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestVerifyAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saldırgan public key'i bilir ve onu HMAC sırrı olarak kullanır
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bound := NewKeySet()
+	bound.Add("rsa", AlgRS256, &rsaKey.PublicKey)
+	unbound := NewKeySet()
+	unbound.Add("rsa", "", &rsaKey.PublicKey)
+
+	cases := []struct {
+		name   string
+		keys   *KeySet
+		config VerifierConfig
+		token  string
+		want   error
+	}{
+		{"geçerli RS256", bound, VerifierConfig{}, signToken(t, AlgRS256, "rsa", rsaKey, validClaims()), nil},
+		{"RSA anahtarıyla HS256", bound, VerifierConfig{}, signToken(t, AlgHS256, "rsa", publicDER, validClaims()), ErrUnsupportedAlgorithm},
+		{"algoritmaya bağlı olmayan RSA anahtarıyla HS256", unbound, VerifierConfig{}, signToken(t, AlgHS256, "rsa", publicDER, validClaims()), ErrKeyNotFound},
+		{"none", bound, VerifierConfig{}, signToken(t, "none", "rsa", nil, validClaims()), ErrUnsupportedAlgorithm},
+		{"none imzalı", bound, VerifierConfig{}, signToken(t, "none", "rsa", publicDER, validClaims()) + "QQ", ErrUnsupportedAlgorithm},
+		{"izin verilmeyen algoritma", bound, VerifierConfig{Algorithms: []string{AlgHS256}}, signToken(t, AlgRS256, "rsa", rsaKey, validClaims()), ErrUnsupportedAlgorithm},
+		{"bilinmeyen kid", bound, VerifierConfig{}, signToken(t, AlgRS256, "other", rsaKey, validClaims()), ErrKeyNotFound},
+	}
+	for _, tc := range cases {
+		_, err := NewJWTVerifier(tc.keys, tc.config).Verify(tc.token)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: hata %v, beklenen %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestVerifyTimeClaims(t *testing.T) {
+	now := time.Now()
+	at := func(d time.Duration) int64 { return now.Add(d).Unix() }
+
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+		leeway time.Duration
+		want   error
+	}{
+		{"geçerli", map[string]interface{}{"exp": at(time.Hour), "nbf": at(-time.Minute), "iat": at(-time.Minute)}, 0, nil},
+		{"exp yok", map[string]interface{}{"sub": "alice"}, 0, ErrTokenExpired},
+		{"süresi dolmuş", map[string]interface{}{"exp": at(-time.Minute)}, 0, ErrTokenExpired},
+		{"pay içinde süresi dolmuş", map[string]interface{}{"exp": at(-time.Minute)}, 2 * time.Minute, nil},
+		{"pay dışında süresi dolmuş", map[string]interface{}{"exp": at(-3 * time.Minute)}, 2 * time.Minute, ErrTokenExpired},
+		{"nbf gelecekte", map[string]interface{}{"exp": at(time.Hour), "nbf": at(time.Minute)}, 0, ErrTokenNotYetValid},
+		{"pay içinde nbf", map[string]interface{}{"exp": at(time.Hour), "nbf": at(time.Minute)}, 2 * time.Minute, nil},
+		{"iat gelecekte", map[string]interface{}{"exp": at(time.Hour), "iat": at(time.Minute)}, 0, ErrTokenNotYetValid},
+		{"sayı olmayan exp", map[string]interface{}{"exp": "yarın"}, 0, ErrMalformedToken},
+	}
+	for _, tc := range cases {
+		v := NewJWTVerifier(NewHMACKeySet(testHMACSecret), VerifierConfig{Leeway: tc.leeway})
+		if _, err := v.Verify(signToken(t, AlgHS256, "", testHMACSecret, tc.claims)); !errors.Is(err, tc.want) {
+			t.Errorf("%s: hata %v, beklenen %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+// TestVerifyClock exp/nbf kontrollerinin VerifierConfig.Clock'a, verilmemişse
+// crypto paketinin saatine göre yapıldığını doğrular
+func TestVerifyClock(t *testing.T) {
+	issued := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	token := signToken(t, AlgHS256, "", testHMACSecret, map[string]interface{}{
+		"exp": issued.Add(time.Hour).Unix(),
+		"nbf": issued.Unix(),
+	})
+	at := func(d time.Duration) ucrypto.Clock {
+		return ucrypto.ClockFunc(func() time.Time { return issued.Add(d) })
+	}
+
+	cases := []struct {
+		name  string
+		clock ucrypto.Clock
+		want  error
+	}{
+		{"geçerlilik içinde", at(30 * time.Minute), nil},
+		{"nbf öncesi", at(-time.Minute), ErrTokenNotYetValid},
+		{"exp sonrası", at(time.Hour), ErrTokenExpired},
+		{"sistem saati", nil, ErrTokenExpired},
+	}
+	for _, tc := range cases {
+		v := NewJWTVerifier(NewHMACKeySet(testHMACSecret), VerifierConfig{Clock: tc.clock})
+		if _, err := v.Verify(token); !errors.Is(err, tc.want) {
+			t.Errorf("%s: hata %v, beklenen %v", tc.name, err, tc.want)
+		}
+	}
+
+	// Clock verilmezse crypto.SetClock ile ayarlanan saat kullanılır
+	ucrypto.SetClock(at(30 * time.Minute))
+	t.Cleanup(func() { ucrypto.SetClock(nil) })
+	if _, err := NewJWTVerifier(NewHMACKeySet(testHMACSecret), VerifierConfig{}).Verify(token); err != nil {
+		t.Fatalf("paket saati kullanılmadı: %v", err)
+	}
+}
+
+func TestVerifyIssuerAudience(t *testing.T) {
+	config := VerifierConfig{Issuer: "https://issuer.example", Audience: "uctanuca"}
+	claims := func(iss string, aud interface{}) map[string]interface{} {
+		c := validClaims()
+		c["iss"] = iss
+		if aud != nil {
+			c["aud"] = aud
+		}
+		return c
+	}
+
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+		want   error
+	}{
+		{"tek hedef", claims("https://issuer.example", "uctanuca"), nil},
+		{"hedef dizisi", claims("https://issuer.example", []string{"other", "uctanuca"}), nil},
+		{"yanlış yayıncı", claims("https://evil.example", "uctanuca"), ErrInvalidIssuer},
+		{"yanlış hedef", claims("https://issuer.example", "other"), ErrInvalidAudience},
+		{"hedef yok", claims("https://issuer.example", nil), ErrInvalidAudience},
+		{"string olmayan hedef", claims("https://issuer.example", 42), ErrMalformedToken},
+	}
+	v := NewJWTVerifier(NewHMACKeySet(testHMACSecret), config)
+	for _, tc := range cases {
+		if _, err := v.Verify(signToken(t, AlgHS256, "", testHMACSecret, tc.claims)); !errors.Is(err, tc.want) {
+			t.Errorf("%s: hata %v, beklenen %v", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+// Package client uçtan uca şifreli API için bir Go istemcisidir. Tarayıcıdaki
+// axios interceptor'ının (frontend/src/api/axiosConfig.js) yaptığını yapar:
+// oturum açar, el sıkışma ile anahtar kurar, gövde ve query parametrelerini
+// şifreler, şifreli yanıtları ve hataları çözer.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"secure-server/backend/pkg/crypto"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Başlık adları (sunucudaki middleware ile aynı olmalı)
+const (
+	HeaderSessionID    = "X-Session-ID"
+	HeaderAuth         = "Authorization"
+	HeaderEncrypted    = "X-Encrypted"
+	HeaderKeyNextEpoch = "X-Key-Next-Epoch"
+	HeaderRequestID    = "X-Request-ID"
+)
+
+// Client şifreli API'ye istek gönderir. Eşzamanlı kullanıma uygundur; oturum
+// ve el sıkışma ilk istekte kurulur ve istekler arasında paylaşılır.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+
+	// legacyKeys açıksa el sıkışma yapılmaz, anahtar token'dan türetilir
+	legacyKeys bool
+	// algorithms el sıkışmada tercih sırasıyla sunulan AEAD algoritmalarıdır
+	algorithms []string
+
+	mu        sync.Mutex
+	token     string
+	sessionID string
+	keys      *sessionKeys
+
+	// clockOffset sunucu saati ile yerel saat arasındaki fark (ns), bkz. roundTrip
+	clockOffset atomic.Int64
+}
+
+// Option Client üzerinde tek bir ayarı değiştirir
+type Option func(*Client)
+
+// WithHTTPClient istekler için kullanılacak http.Client'ı belirler
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithToken isteklerde kullanılacak bearer token'ı belirler
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithSessionID sunucudan daha önce alınmış bir oturumu kullanır; verilmezse
+// ilk istekte POST /session ile yeni oturum açılır
+func WithSessionID(sessionID string) Option {
+	return func(c *Client) { c.sessionID = sessionID }
+}
+
+// WithLegacyKeyDerivation el sıkışma yerine token'dan türetilen anahtarı
+// kullanır. Sunucuda da eski anahtar modu açık olmalıdır.
+func WithLegacyKeyDerivation(enabled bool) Option {
+	return func(c *Client) { c.legacyKeys = enabled }
+}
+
+// WithAlgorithms el sıkışmada sunulan AEAD algoritmalarını tercih sırasıyla
+// belirler (örn. "xchacha20-poly1305"). Verilmezse crypto.DefaultAlgorithms
+// sunulur; sunucu bunlardan kabul ettiği ilkini seçer.
+func WithAlgorithms(names ...string) Option {
+	return func(c *Client) { c.algorithms = names }
+}
+
+// New baseURL (örn. "https://localhost:8080/api") için yeni bir istemci oluşturur
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("geçersiz temel URL: %w", err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, errors.New("temel URL şema ve sunucu içermeli")
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		algorithms: crypto.DefaultAlgorithms,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// SetToken token'ı değiştirir. Oturum ve anahtarlar token'a bağlı olduğundan
+// sıfırlanır; sonraki istek yenilerini kurar.
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = token
+	c.sessionID = ""
+	c.keys = nil
+}
+
+// SessionID kullanılan oturum ID'sini döndürür (henüz oturum yoksa boş)
+func (c *Client) SessionID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionID
+}
+
+// Get query parametrelerini şifreleyerek GET isteği gönderir ve yanıtı out'a çözer.
+// query ve out nil olabilir.
+func (c *Client) Get(ctx context.Context, path string, query, out interface{}) error {
+	return c.call(ctx, http.MethodGet, path, query, nil, out)
+}
+
+// Post gövdeyi şifreleyerek POST isteği gönderir ve yanıtı out'a çözer
+func (c *Client) Post(ctx context.Context, path string, body, out interface{}) error {
+	return c.call(ctx, http.MethodPost, path, nil, body, out)
+}
+
+// Put gövdeyi şifreleyerek PUT isteği gönderir ve yanıtı out'a çözer
+func (c *Client) Put(ctx context.Context, path string, body, out interface{}) error {
+	return c.call(ctx, http.MethodPut, path, nil, body, out)
+}
+
+// Patch gövdeyi şifreleyerek PATCH isteği gönderir ve yanıtı out'a çözer
+func (c *Client) Patch(ctx context.Context, path string, body, out interface{}) error {
+	return c.call(ctx, http.MethodPatch, path, nil, body, out)
+}
+
+// Delete gövdeyi şifreleyerek DELETE isteği gönderir ve yanıtı out'a çözer
+func (c *Client) Delete(ctx context.Context, path string, body, out interface{}) error {
+	return c.call(ctx, http.MethodDelete, path, nil, body, out)
+}
+
+func (c *Client) call(ctx context.Context, method, path string, query, body, out interface{}) error {
+	resp, err := c.Do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	return resp.JSON(out)
+}
+
+// Do isteği gönderir. query şifrelenip "encrypted" parametresi olarak, body
+// şifrelenip text/plain gövde olarak gönderilir; ikisi de JSON nesnesine
+// dönüşebilen değerler olmalıdır. Sunucu aynı istekte yalnızca birini çözdüğünden
+// ikisi birlikte verilemez. 4xx/5xx yanıtlar *APIError olarak döner.
+//
+// Sunucu el sıkışma veya yeni oturum gerektirdiğini bildirirse istek bir kez
+// yeni anahtarla, saat farkını bildirirse (clock_skew) düzeltilmiş saatle
+// tekrar gönderilir.
+func (c *Client) Do(ctx context.Context, method, path string, query, body interface{}) (*Response, error) {
+	if query != nil && body != nil {
+		return nil, errors.New("query parametreleri ve gövde aynı istekte şifrelenemez")
+	}
+
+	resp, err := c.do(ctx, method, path, query, body)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && c.resetFor(apiErr.Code) {
+		return c.do(ctx, method, path, query, body)
+	}
+	return resp, err
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query, body interface{}) (*Response, error) {
+	target := c.resolve(path)
+
+	// Token yoksa istek şifrelenmeden gönderilir (axios interceptor'ı ile aynı)
+	token, sessionID, keys, err := c.ensureKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.send(ctx, method, target, query, body, token, sessionID, keys)
+}
+
+// send isteği oluşturur, gerekiyorsa şifreler, gönderir ve yanıtı çözer
+func (c *Client) send(ctx context.Context, method string, target *url.URL, query, body interface{}, token, sessionID string, keys *sessionKeys) (*Response, error) {
+	encrypted := token != ""
+	binding := crypto.RequestBinding(method, target.Path, sessionID)
+
+	var reqBody io.Reader
+	contentType := ""
+
+	if query != nil {
+		params, err := toObject(query)
+		if err != nil {
+			return nil, err
+		}
+
+		values := target.Query()
+		if encrypted {
+			sk, err := keys.current()
+			if err != nil {
+				return nil, err
+			}
+			if params, err = withReplayFields(params, c.now(), keys.nextSequence()); err != nil {
+				return nil, err
+			}
+			encryptedQuery, err := crypto.EncryptQueryParamsWithKey(params, sk, binding)
+			if err != nil {
+				return nil, err
+			}
+			values.Set("encrypted", encryptedQuery)
+		} else {
+			for name, value := range params {
+				values.Set(name, fmt.Sprint(value))
+			}
+		}
+		target.RawQuery = values.Encode()
+	}
+
+	if body != nil {
+		payload, err := toObject(body)
+		if err != nil {
+			return nil, err
+		}
+
+		if encrypted {
+			sk, err := keys.current()
+			if err != nil {
+				return nil, err
+			}
+			if payload, err = withReplayFields(payload, c.now(), keys.nextSequence()); err != nil {
+				return nil, err
+			}
+			encryptedBody, err := crypto.EncryptDataWithKey(payload, sk, binding)
+			if err != nil {
+				return nil, err
+			}
+			reqBody = strings.NewReader(encryptedBody)
+			contentType = "text/plain"
+		} else {
+			data, _ := json.Marshal(payload)
+			reqBody = bytes.NewReader(data)
+			contentType = "application/json"
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if encrypted {
+		req.Header.Set(HeaderAuth, "Bearer "+token)
+		req.Header.Set(HeaderSessionID, sessionID)
+		if body != nil {
+			req.Header.Set(HeaderEncrypted, "true")
+		}
+	}
+
+	httpResp, err := c.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	// Sunucu anahtar rotasyonu duyurduysa sonraki istekler yeni epoch'la şifrelenir
+	if keys != nil {
+		keys.advance(httpResp.Header.Get(HeaderKeyNextEpoch))
+	}
+
+	resp, err := readResponse(httpResp, keys, crypto.ResponseBinding(method, target.Path, sessionID))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp, resp.apiError()
+	}
+	return resp, nil
+}
+
+// resolve path'i temel URL'ye göre çözer ("/data" -> "https://host/api/data")
+func (c *Client) resolve(path string) *url.URL {
+	target := *c.baseURL
+	rawPath, rawQuery, _ := strings.Cut(path, "?")
+	target.Path = c.baseURL.Path + "/" + strings.TrimPrefix(rawPath, "/")
+	target.RawQuery = rawQuery
+	return &target
+}
+
+// toObject değeri JSON nesnesine dönüştürür; sunucu yalnızca nesne çözer
+func toObject(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("JSON marshal hatası: %w", err)
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return nil, errors.New("şifrelenecek veri bir JSON nesnesi olmalı")
+	}
+	return object, nil
+}
+
+// withReplayFields replay koruması için _timestamp (ms), _nonce ve _seq ekler.
+// now sunucu saatine göre düzeltilmiş zamandır. Sunucu yapılandırmasına göre
+// ya zaman damgası ve nonce ya da sıra numarası kontrol edilir.
+func withReplayFields(payload map[string]interface{}, now time.Time, seq uint64) (map[string]interface{}, error) {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("nonce oluşturma hatası: %w", err)
+	}
+
+	payload["_timestamp"] = now.UnixMilli()
+	payload["_nonce"] = hex.EncodeToString(nonce)
+	payload["_seq"] = seq
+	return payload, nil
+}
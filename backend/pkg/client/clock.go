@@ -0,0 +1,38 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HeaderServerTime sunucunun her yanıtta döndürdüğü saattir (Unix milisaniye)
+const HeaderServerTime = "X-Server-Time"
+
+// ClockOffset sunucu saati ile yerel saat arasındaki son ölçülen farkı
+// döndürür (sunucu - yerel). _timestamp yerel saate bu fark eklenerek üretilir.
+func (c *Client) ClockOffset() time.Duration {
+	return time.Duration(c.clockOffset.Load())
+}
+
+// now _timestamp için sunucu saatine göre düzeltilmiş şimdiki zamanı döndürür
+func (c *Client) now() time.Time {
+	return time.Now().Add(c.ClockOffset())
+}
+
+// roundTrip isteği gönderir ve yanıttaki X-Server-Time başlığından saat farkını
+// günceller. Sunucu saati isteğin gidiş-dönüş süresinin ortasına denk sayılır.
+func (c *Client) roundTrip(req *http.Request) (*http.Response, error) {
+	sent := time.Now()
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	received := time.Now()
+
+	if ms, err := strconv.ParseInt(httpResp.Header.Get(HeaderServerTime), 10, 64); err == nil && ms > 0 {
+		local := sent.Add(received.Sub(sent) / 2)
+		c.clockOffset.Store(int64(time.UnixMilli(ms).Sub(local)))
+	}
+	return httpResp, nil
+}
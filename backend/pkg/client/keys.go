@@ -0,0 +1,292 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"secure-server/backend/pkg/crypto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// keyRenewMargin anahtarın süresi dolmadan bu kadar önce yeni el sıkışma yapılır
+const keyRenewMargin = 30 * time.Second
+
+// sessionKeys el sıkışma ile kurulan oturum sırrını ve geçerli epoch'u tutar.
+// Eski anahtar modunda yalnızca token'dan türetilen anahtar kullanılır.
+type sessionKeys struct {
+	mu sync.Mutex
+
+	sessionID string
+	legacy    *crypto.SessionKey
+
+	secret    []byte
+	baseID    string
+	epoch     uint32
+	algorithm byte
+	expiresAt time.Time
+
+	// sequence bu el sıkışmayla gönderilen son sıra numarasıdır (_seq)
+	sequence uint64
+}
+
+// expired anahtarın yenilenmesi gerekip gerekmediğini döndürür
+func (k *sessionKeys) expired() bool {
+	return k.legacy == nil && !time.Now().Add(keyRenewMargin).Before(k.expiresAt)
+}
+
+// current isteklerin şifreleneceği anahtarı döndürür
+func (k *sessionKeys) current() (*crypto.SessionKey, error) {
+	if k.legacy != nil {
+		return k.legacy, nil
+	}
+
+	k.mu.Lock()
+	epoch := k.epoch
+	k.mu.Unlock()
+	return k.epochKey(epoch)
+}
+
+// nextSequence sonraki sıra numarasını döndürür. Sunucu sıra numarası modunda
+// değilse _seq yok sayılır; yeni el sıkışma sayacı sıfırlar.
+func (k *sessionKeys) nextSequence() uint64 {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.sequence++
+	return k.sequence
+}
+
+// epochKey epoch anahtarını oturum sırrından türetir
+func (k *sessionKeys) epochKey(epoch uint32) (*crypto.SessionKey, error) {
+	key, err := crypto.DeriveEpochKey(k.secret, k.sessionID, epoch)
+	if err != nil {
+		return nil, err
+	}
+	return &crypto.SessionKey{ID: crypto.EpochKeyID(k.baseID, epoch), Key: key, Epoch: epoch, Algorithm: k.algorithm, ExpiresAt: k.expiresAt}, nil
+}
+
+// forKeyID yanıt zarfındaki anahtar kimliğine karşılık gelen anahtarı döndürür.
+// Sunucu rotasyonu zorladıysa yanıt daha yeni bir epoch'la gelir ve o epoch'a geçilir.
+func (k *sessionKeys) forKeyID(keyID string) (*crypto.SessionKey, error) {
+	if k.legacy != nil {
+		return k.legacy, nil
+	}
+
+	rawEpoch, ok := strings.CutPrefix(keyID, k.baseID+".")
+	if !ok {
+		return nil, fmt.Errorf("%w: anahtar kimliği eşleşmiyor", crypto.ErrAuthFailed)
+	}
+	epoch, err := strconv.ParseUint(rawEpoch, 10, 32)
+	if err != nil || crypto.EpochKeyID(k.baseID, uint32(epoch)) != keyID {
+		return nil, fmt.Errorf("%w: anahtar kimliği eşleşmiyor", crypto.ErrAuthFailed)
+	}
+
+	k.adopt(uint32(epoch))
+	return k.epochKey(uint32(epoch))
+}
+
+// advance X-Key-Next-Epoch başlığıyla duyurulan epoch'a geçer
+func (k *sessionKeys) advance(nextEpoch string) {
+	if nextEpoch == "" || k.legacy != nil {
+		return
+	}
+	if epoch, err := strconv.ParseUint(nextEpoch, 10, 32); err == nil {
+		k.adopt(uint32(epoch))
+	}
+}
+
+// adopt daha yeni bir epoch'a geçer; eski epoch'a dönülmez
+func (k *sessionKeys) adopt(epoch uint32) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if epoch > k.epoch {
+		k.epoch = epoch
+	}
+}
+
+// ensureKeys gerekirse oturum açar ve el sıkışma yapar. Token yoksa boş döner
+// ve istek şifrelenmeden gönderilir. Eşzamanlı istekler tek kurulumu bekler.
+func (c *Client) ensureKeys(ctx context.Context) (token, sessionID string, keys *sessionKeys, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == "" {
+		return "", "", nil, nil
+	}
+
+	if c.sessionID == "" {
+		sessionID, err := c.createSession(ctx, c.token)
+		if err != nil {
+			return "", "", nil, err
+		}
+		c.sessionID = sessionID
+		c.keys = nil
+	}
+
+	if c.keys == nil || c.keys.expired() {
+		if c.legacyKeys {
+			key, err := crypto.DeriveKeys(c.token, c.sessionID)
+			if err != nil {
+				return "", "", nil, err
+			}
+			// Eski istemcilerin anahtar kimliği yoktur
+			c.keys = &sessionKeys{sessionID: c.sessionID, legacy: &crypto.SessionKey{Key: key}}
+		} else {
+			keys, err := c.handshake(ctx, c.token, c.sessionID)
+			if err != nil {
+				return "", "", nil, err
+			}
+			c.keys = keys
+		}
+	}
+
+	return c.token, c.sessionID, c.keys, nil
+}
+
+// resetFor sunucunun hata koduna göre oturumu veya anahtarı sıfırlar ve
+// isteğin tekrar denenip denenmeyeceğini döndürür
+func (c *Client) resetFor(code string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch code {
+	case "handshake_required":
+		c.keys = nil
+		return true
+	case "session_invalid":
+		c.sessionID = ""
+		c.keys = nil
+		return true
+	case "clock_skew":
+		// Saat farkı hata yanıtının X-Server-Time başlığından güncellendi
+		return true
+	}
+	return false
+}
+
+// RevokeSession sunucudaki oturumu iptal eder ve istemcideki oturum ile
+// anahtarları siler. Sonraki istek yeni oturum açar.
+func (c *Client) RevokeSession(ctx context.Context) error {
+	if c.SessionID() == "" {
+		return nil
+	}
+
+	_, err := c.do(ctx, http.MethodPost, "/session/revoke", nil, nil)
+
+	c.mu.Lock()
+	c.sessionID = ""
+	c.keys = nil
+	c.mu.Unlock()
+	return err
+}
+
+// sessionResponse POST /session yanıtıdır
+type sessionResponse struct {
+	SessionID string `json:"session_id"`
+}
+
+// createSession token'ın kullanıcısına bağlı yeni bir oturum açar (düz metin)
+func (c *Client) createSession(ctx context.Context, token string) (string, error) {
+	var resp sessionResponse
+	if err := c.postPlain(ctx, "/session", token, "", nil, &resp); err != nil {
+		return "", fmt.Errorf("oturum açılamadı: %w", err)
+	}
+	if resp.SessionID == "" {
+		return "", errors.New("oturum açılamadı: sunucu oturum ID'si döndürmedi")
+	}
+	return resp.SessionID, nil
+}
+
+// handshakeRequest POST /handshake isteğidir
+type handshakeRequest struct {
+	ClientPublicKey string   `json:"client_public_key"`
+	Algorithms      []string `json:"algorithms"`
+}
+
+// handshake sunucuyla X25519 el sıkışması yapar (düz metin)
+func (c *Client) handshake(ctx context.Context, token, sessionID string) (*sessionKeys, error) {
+	clientPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("geçici anahtar üretme hatası: %w", err)
+	}
+
+	offered := c.algorithms
+	if len(offered) == 0 {
+		offered = []string{"aes-256-gcm"}
+	}
+	req := handshakeRequest{
+		ClientPublicKey: base64.StdEncoding.EncodeToString(clientPriv.PublicKey().Bytes()),
+		Algorithms:      offered,
+	}
+	var result crypto.HandshakeResult
+	if err := c.postPlain(ctx, "/handshake", token, sessionID, req, &result); err != nil {
+		return nil, fmt.Errorf("el sıkışma başarısız: %w", err)
+	}
+
+	// Algoritma bildirmeyen eski sunucular AES-256-GCM kullanır. Sunucu
+	// sunulmayan bir algoritma seçemez.
+	if result.Algorithm == "" {
+		result.Algorithm = "aes-256-gcm"
+	}
+	alg, err := crypto.NegotiateAlgorithm([]string{result.Algorithm}, offered)
+	if err != nil {
+		return nil, fmt.Errorf("el sıkışma başarısız: %w", err)
+	}
+
+	secret, err := crypto.CompleteHandshake(clientPriv, result.ServerPublicKey, token, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("el sıkışma başarısız: %w", err)
+	}
+
+	return &sessionKeys{
+		sessionID: sessionID,
+		secret:    secret,
+		baseID:    result.KeyID,
+		epoch:     result.Epoch,
+		algorithm: alg.ID,
+		expiresAt: time.Now().Add(time.Duration(result.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// postPlain oturum ve el sıkışma uç noktalarına şifresiz JSON isteği gönderir
+func (c *Client) postPlain(ctx context.Context, path, token, sessionID string, body, out interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.resolve(path).String(), &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderAuth, "Bearer "+token)
+	if sessionID != "" {
+		req.Header.Set(HeaderSessionID, sessionID)
+	}
+
+	httpResp, err := c.roundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	resp, err := readResponse(httpResp, nil, crypto.Binding{})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.apiError()
+	}
+	return resp.JSON(out)
+}
@@ -0,0 +1,223 @@
+package client
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"secure-server/backend/pkg/crypto"
+)
+
+const (
+	// maxResponseSize çözülmek üzere belleğe alınan en büyük yanıt boyutudur
+	maxResponseSize = 64 << 20
+	// streamValue X-Encrypted başlığının parçalı akış değeridir
+	streamValue = "stream"
+)
+
+// Response çözülmüş sunucu yanıtıdır. Body, sunucunun şifrelemeden önce
+// ürettiği gövdedir; ContentType onun orijinal içerik tipidir.
+type Response struct {
+	StatusCode  int
+	Header      http.Header
+	ContentType string
+	Body        []byte
+	// Encrypted yanıtın şifreli gelip gelmediğini belirtir
+	Encrypted bool
+}
+
+// JSON gövdeyi out'a çözer
+func (r *Response) JSON(out interface{}) error {
+	if err := json.Unmarshal(r.Body, out); err != nil {
+		return fmt.Errorf("yanıt JSON çözme hatası: %w", err)
+	}
+	return nil
+}
+
+// APIError sunucunun döndürdüğü hata yanıtıdır ({error, code, request_id})
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API hatası %d (%s, istek %s): %s", e.StatusCode, e.Code, e.RequestID, e.Message)
+}
+
+// apiError hata yanıtını APIError'a dönüştürür
+func (r *Response) apiError() *APIError {
+	apiErr := &APIError{StatusCode: r.StatusCode, RequestID: r.Header.Get(HeaderRequestID)}
+
+	var body struct {
+		Error     string `json:"error"`
+		Code      string `json:"code"`
+		RequestID string `json:"request_id"`
+	}
+	if json.Unmarshal(r.Body, &body) == nil {
+		apiErr.Code = body.Code
+		apiErr.Message = body.Error
+		if body.RequestID != "" {
+			apiErr.RequestID = body.RequestID
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(r.StatusCode)
+	}
+	return apiErr
+}
+
+// readResponse yanıtı okur ve X-Encrypted başlığına göre çözer. b yanıtın
+// bağlamıdır (ResponseBinding); keys nil ise şifreli yanıt kabul edilmez.
+func readResponse(httpResp *http.Response, keys *sessionKeys, b crypto.Binding) (*Response, error) {
+	resp := &Response{
+		StatusCode:  httpResp.StatusCode,
+		Header:      httpResp.Header,
+		ContentType: httpResp.Header.Get("Content-Type"),
+	}
+
+	encrypted := httpResp.Header.Get(HeaderEncrypted)
+	if (encrypted == "true" || encrypted == streamValue) && keys == nil {
+		return nil, errors.New("şifreli yanıt için oturum anahtarı yok")
+	}
+
+	switch encrypted {
+	case "true":
+		data, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+		if err != nil {
+			return nil, err
+		}
+		if err := resp.open(data, keys, b); err != nil {
+			return nil, fmt.Errorf("yanıt çözme hatası: %w", err)
+		}
+
+	case streamValue:
+		if err := resp.openStream(httpResp.Body, keys, b); err != nil {
+			return nil, fmt.Errorf("yanıt akışı çözme hatası: %w", err)
+		}
+
+	default:
+		data, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = data
+	}
+
+	return resp, nil
+}
+
+// open tek parça şifreli yanıtı ("base64" JSON string) çözer
+func (r *Response) open(data []byte, keys *sessionKeys, b crypto.Binding) error {
+	var encryptedBase64 string
+	if err := json.Unmarshal(data, &encryptedBase64); err != nil {
+		return fmt.Errorf("%w: yanıt JSON string değil", crypto.ErrBadEncoding)
+	}
+
+	envelope, err := base64.StdEncoding.DecodeString(encryptedBase64)
+	if err != nil {
+		return fmt.Errorf("%w: base64 decode başarısız", crypto.ErrBadEncoding)
+	}
+	env, err := crypto.ParseEnvelope(envelope)
+	if err != nil {
+		return err
+	}
+
+	sk, err := keys.forKeyID(env.KeyID)
+	if err != nil {
+		return err
+	}
+	plaintext, err := crypto.DecryptResponseWithKey(encryptedBase64, sk, b)
+	if err != nil {
+		return err
+	}
+
+	r.Encrypted = true
+	return r.unwrap(plaintext)
+}
+
+// openStream parçalı şifreli yanıtı çözer. Akışın düz metni orijinal
+// Content-Type ile başlar: contentTypeLen(1) | contentType | body
+func (r *Response) openStream(body io.Reader, keys *sessionKeys, b crypto.Binding) error {
+	br := bufio.NewReader(body)
+	keyID, err := peekStreamKeyID(br)
+	if err != nil {
+		return err
+	}
+
+	sk, err := keys.forKeyID(keyID)
+	if err != nil {
+		return err
+	}
+	stream, err := crypto.OpenStream(br, sk, b)
+	if err != nil {
+		return err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(stream, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return fmt.Errorf("%w: akış içerik tipi eksik", crypto.ErrBadEncoding)
+	}
+
+	r.Encrypted = true
+	r.ContentType = string(data[1 : 1+int(data[0])])
+	r.Body = data[1+int(data[0]):]
+	return nil
+}
+
+// peekStreamKeyID akış başlığındaki anahtar kimliğini okumadan döndürür:
+// version(1) | algorithm(1) | keyIdLen(1) | keyId(n)
+func peekStreamKeyID(br *bufio.Reader) (string, error) {
+	fixed, err := br.Peek(3)
+	if err != nil || fixed[0] != crypto.EnvelopeStreamV1 {
+		return "", fmt.Errorf("%w: akış başlığı okunamadı", crypto.ErrBadEncoding)
+	}
+
+	header, err := br.Peek(3 + int(fixed[2]))
+	if err != nil {
+		return "", fmt.Errorf("%w: akış başlığı okunamadı", crypto.ErrBadEncoding)
+	}
+	return string(header[3:]), nil
+}
+
+// wrappedResponse sunucunun JSON nesnesi olmayan yanıtları sarmaladığı biçimdir
+type wrappedResponse struct {
+	ContentType string          `json:"_content_type"`
+	Body        json.RawMessage `json:"_body"`
+	BodyBase64  string          `json:"_body_base64"`
+}
+
+// unwrap çözülmüş JSON'u orijinal gövdeye ve içerik tipine dönüştürür
+func (r *Response) unwrap(plaintext []byte) error {
+	var object map[string]json.RawMessage
+	if json.Unmarshal(plaintext, &object) != nil || object["_content_type"] == nil {
+		r.ContentType = "application/json"
+		r.Body = plaintext
+		return nil
+	}
+
+	var wrapped wrappedResponse
+	if err := json.Unmarshal(plaintext, &wrapped); err != nil {
+		return fmt.Errorf("%w: %v", crypto.ErrMalformedJSON, err)
+	}
+
+	r.ContentType = wrapped.ContentType
+	if wrapped.Body != nil {
+		r.Body = wrapped.Body
+		return nil
+	}
+
+	body, err := base64.StdEncoding.DecodeString(wrapped.BodyBase64)
+	if err != nil {
+		return fmt.Errorf("%w: _body_base64 decode başarısız", crypto.ErrBadEncoding)
+	}
+	r.Body = body
+	return nil
+}
@@ -0,0 +1,154 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Algorithm zarf ve akışlarda kullanılabilen bir AEAD algoritmasıdır. ID
+// zarfın algoritma baytına yazılır; Name el sıkışmada pazarlık için kullanılır.
+// Tüm algoritmalar 32 baytlık oturum anahtarıyla çalışır.
+type Algorithm struct {
+	ID        byte
+	Name      string
+	NonceSize int
+	New       func(key []byte) (cipher.AEAD, error)
+}
+
+var (
+	algorithmsMu sync.RWMutex
+	algorithms   = map[byte]Algorithm{}
+)
+
+func init() {
+	for _, alg := range []Algorithm{
+		{ID: AlgAES256GCM, Name: "aes-256-gcm", NonceSize: gcmNonceSize, New: newAESGCM},
+		{ID: AlgChaCha20Poly1305, Name: "chacha20-poly1305", NonceSize: chacha20poly1305.NonceSize, New: newChaCha20Poly1305},
+		{ID: AlgXChaCha20Poly1305, Name: "xchacha20-poly1305", NonceSize: chacha20poly1305.NonceSizeX, New: newXChaCha20Poly1305},
+		{ID: AlgAES256GCMSIV, Name: "aes-256-gcm-siv", NonceSize: gcmSIVNonceSize, New: newAESGCMSIV},
+	} {
+		if err := RegisterAlgorithm(alg); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// DefaultAlgorithms sunucunun varsayılan olarak kabul ettiği algoritmalardır
+var DefaultAlgorithms = []string{"aes-256-gcm", "chacha20-poly1305", "xchacha20-poly1305", "aes-256-gcm-siv"}
+
+// RegisterAlgorithm kayıt defterine yeni bir AEAD ekler. ID ve ad benzersiz
+// olmalıdır; akış parça nonce'u için nonce en az 12 bayt olmalıdır.
+func RegisterAlgorithm(alg Algorithm) error {
+	if alg.ID == 0 || alg.Name == "" || alg.New == nil {
+		return errors.New("algoritma kimliği, adı ve oluşturucusu gerekli")
+	}
+	if alg.NonceSize < gcmNonceSize {
+		return fmt.Errorf("algoritma %s: nonce en az %d bayt olmalı", alg.Name, gcmNonceSize)
+	}
+
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+
+	for _, existing := range algorithms {
+		if existing.ID == alg.ID || existing.Name == alg.Name {
+			return fmt.Errorf("algoritma zaten kayıtlı: %s (%d)", existing.Name, existing.ID)
+		}
+	}
+	algorithms[alg.ID] = alg
+	return nil
+}
+
+// LookupAlgorithm zarftaki algoritma baytına karşılık gelen algoritmayı döndürür
+func LookupAlgorithm(id byte) (Algorithm, error) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+
+	alg, ok := algorithms[id]
+	if !ok {
+		return Algorithm{}, fmt.Errorf("%w: desteklenmeyen algoritma: %d", ErrBadEncoding, id)
+	}
+	return alg, nil
+}
+
+// AlgorithmByName el sıkışmadaki algoritma adına karşılık gelen algoritmayı döndürür
+func AlgorithmByName(name string) (Algorithm, bool) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+
+	for _, alg := range algorithms {
+		if alg.Name == name {
+			return alg, true
+		}
+	}
+	return Algorithm{}, false
+}
+
+// NegotiateAlgorithm istemcinin tercih sırasıyla sunduğu algoritmalardan
+// sunucunun kabul ettiği ilkini seçer. İstemci hiç algoritma sunmadıysa (eski
+// istemciler) ve sunucu kabul ediyorsa AES-256-GCM seçilir.
+func NegotiateAlgorithm(offered, allowed []string) (Algorithm, error) {
+	if len(offered) == 0 {
+		offered = []string{"aes-256-gcm"}
+	}
+
+	for _, name := range offered {
+		for _, candidate := range allowed {
+			if name != candidate {
+				continue
+			}
+			if alg, ok := AlgorithmByName(name); ok {
+				return alg, nil
+			}
+		}
+	}
+	return Algorithm{}, fmt.Errorf("ortak şifreleme algoritması yok (istemci: %v)", offered)
+}
+
+// algorithm anahtarın kullandığı algoritmayı döndürür. El sıkışmada
+// seçilmemiş anahtarlar AES-256-GCM ile şifreler.
+func (sk *SessionKey) algorithm() (Algorithm, error) {
+	if sk.Algorithm == 0 {
+		return LookupAlgorithm(AlgAES256GCM)
+	}
+	return LookupAlgorithm(sk.Algorithm)
+}
+
+// aeadFor zarftaki algoritmayla anahtarın AEAD'ini oluşturur. Her anahtar
+// yalnızca kendi algoritmasıyla kullanılabilir: el sıkışmada seçilen veya
+// algoritması belirtilmemiş (token'dan türetilen) anahtarlarda AES-256-GCM.
+// Aynı anahtarın farklı algoritmalarla kullanılması reddedilir.
+func (sk *SessionKey) aeadFor(id byte) (cipher.AEAD, error) {
+	alg, err := LookupAlgorithm(id)
+	if err != nil {
+		return nil, err
+	}
+
+	own, err := sk.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	if own.ID != alg.ID {
+		return nil, fmt.Errorf("%w: algoritma oturumda seçilenle eşleşmiyor", ErrAuthFailed)
+	}
+	return alg.New(sk.Key)
+}
+
+func newChaCha20Poly1305(key []byte) (cipher.AEAD, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, errors.New("ChaCha20-Poly1305 oluşturma başarısız")
+	}
+	return aead, nil
+}
+
+func newXChaCha20Poly1305(key []byte) (cipher.AEAD, error) {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, errors.New("XChaCha20-Poly1305 oluşturma başarısız")
+	}
+	return aead, nil
+}
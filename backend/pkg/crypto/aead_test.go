@@ -0,0 +1,172 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// algorithmKey fuzzKey ile aynı anahtarı verilen algoritmaya bağlar
+func algorithmKey(t *testing.T, name string) *SessionKey {
+	t.Helper()
+	alg, ok := AlgorithmByName(name)
+	if !ok {
+		t.Fatalf("algoritma kayıtlı değil: %s", name)
+	}
+	return &SessionKey{ID: fuzzKey.ID, Key: fuzzKey.Key, Algorithm: alg.ID}
+}
+
+func TestAlgorithmRoundTrip(t *testing.T) {
+	codec := &Codec{ReplayCache: NewMemoryReplayCache(100, 0)}
+	b := RequestBinding("POST", "/api/data", "alg-session")
+
+	for i, name := range DefaultAlgorithms {
+		sk := algorithmKey(t, name)
+		alg, _ := LookupAlgorithm(sk.Algorithm)
+
+		payload := map[string]interface{}{"_timestamp": time.Now().UnixMilli(), "_nonce": name, "i": i}
+		encrypted, err := codec.EncryptData(payload, sk, b)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		raw, err := base64.StdEncoding.DecodeString(encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		env, err := ParseEnvelope(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if env.Algorithm != alg.ID || len(env.Nonce) != alg.NonceSize {
+			t.Errorf("%s: zarf algoritması %d, nonce %d bayt", name, env.Algorithm, len(env.Nonce))
+		}
+
+		if _, err := codec.DecryptData(encrypted, sk, b); err != nil {
+			t.Errorf("%s: çözme hatası: %v", name, err)
+		}
+
+		// Algoritması seçilmemiş anahtar yalnızca AES-256-GCM zarflarını çözer
+		payload["_nonce"] = name + "-legacy"
+		encrypted, err = codec.EncryptData(payload, sk, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = codec.DecryptData(encrypted, fuzzKey, b)
+		if alg.ID == AlgAES256GCM && err != nil {
+			t.Errorf("%s: algoritmasız anahtarla çözme hatası: %v", name, err)
+		}
+		if alg.ID != AlgAES256GCM && !errors.Is(err, ErrAuthFailed) {
+			t.Errorf("%s: algoritmasız anahtar: hata %v, beklenen %v", name, err, ErrAuthFailed)
+		}
+	}
+}
+
+func TestAlgorithmStreamRoundTrip(t *testing.T) {
+	b := RequestBinding("PUT", "/api/upload", "alg-session")
+	plaintext := bytes.Repeat([]byte("akış verisi "), 20)
+
+	for _, name := range DefaultAlgorithms {
+		sk := algorithmKey(t, name)
+		alg, _ := LookupAlgorithm(sk.Algorithm)
+
+		var buf bytes.Buffer
+		w, err := NewStreamWriter(&buf, sk, b, 32)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := w.Write(plaintext); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := OpenStream(&buf, sk, b)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if h := r.Header(); h.Algorithm != alg.ID || len(h.NoncePrefix) != alg.NonceSize-streamNonceSuffixSize {
+			t.Errorf("%s: başlık algoritması %d, nonce ön eki %d bayt", name, h.Algorithm, len(h.NoncePrefix))
+		}
+		got, err := io.ReadAll(r)
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("%s: akış çözülemedi: %v", name, err)
+		}
+	}
+}
+
+// TestAlgorithmMismatch oturumda seçilen algoritma dışındaki zarfların ve
+// algoritma baytı değiştirilmiş zarfların reddedildiğini doğrular
+func TestAlgorithmMismatch(t *testing.T) {
+	codec := &Codec{ReplayCache: NewMemoryReplayCache(100, 0)}
+	b := RequestBinding("POST", "/api/data", "alg-session")
+	chacha := algorithmKey(t, "chacha20-poly1305")
+
+	encrypted, err := codec.EncryptData(map[string]interface{}{"_timestamp": time.Now().UnixMilli(), "_nonce": "x"}, chacha, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := codec.DecryptData(encrypted, algorithmKey(t, "aes-256-gcm"), b); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("başka algoritmaya bağlı anahtar: hata %v, beklenen %v", err, ErrAuthFailed)
+	}
+
+	// Aynı nonce boyutlu algoritmaya geçiş AAD'deki başlık nedeniyle doğrulanamaz
+	raw, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[1] = AlgAES256GCMSIV
+	if _, err := codec.DecryptData(base64.StdEncoding.EncodeToString(raw), fuzzKey, b); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("değiştirilmiş algoritma baytı: hata %v, beklenen %v", err, ErrAuthFailed)
+	}
+
+	raw[1] = 0x7f
+	if _, err := codec.DecryptData(base64.StdEncoding.EncodeToString(raw), fuzzKey, b); !errors.Is(err, ErrBadEncoding) {
+		t.Errorf("bilinmeyen algoritma: hata %v, beklenen %v", err, ErrBadEncoding)
+	}
+}
+
+func TestNegotiateAlgorithm(t *testing.T) {
+	cases := []struct {
+		offered, allowed []string
+		want             string
+	}{
+		{nil, DefaultAlgorithms, "aes-256-gcm"},
+		{[]string{"xchacha20-poly1305", "aes-256-gcm"}, DefaultAlgorithms, "xchacha20-poly1305"},
+		{[]string{"bilinmeyen", "aes-256-gcm-siv"}, DefaultAlgorithms, "aes-256-gcm-siv"},
+		{[]string{"aes-256-gcm", "chacha20-poly1305"}, []string{"chacha20-poly1305"}, "chacha20-poly1305"},
+		{[]string{"aes-256-gcm"}, []string{"xchacha20-poly1305"}, ""},
+		{nil, []string{"xchacha20-poly1305"}, ""},
+		{[]string{"bilinmeyen"}, []string{"bilinmeyen"}, ""},
+	}
+	for _, tc := range cases {
+		alg, err := NegotiateAlgorithm(tc.offered, tc.allowed)
+		if tc.want == "" {
+			if err == nil {
+				t.Errorf("%v / %v: %s seçildi, hata bekleniyordu", tc.offered, tc.allowed, alg.Name)
+			}
+			continue
+		}
+		if err != nil || alg.Name != tc.want {
+			t.Errorf("%v / %v: %s (%v), beklenen %s", tc.offered, tc.allowed, alg.Name, err, tc.want)
+		}
+	}
+}
+
+func TestRegisterAlgorithm(t *testing.T) {
+	cases := []Algorithm{
+		{ID: AlgAES256GCM, Name: "yeni", NonceSize: 12, New: newAESGCM},
+		{ID: 0x70, Name: "aes-256-gcm", NonceSize: 12, New: newAESGCM},
+		{ID: 0x70, Name: "kısa-nonce", NonceSize: 8, New: newAESGCM},
+		{ID: 0x70, Name: "oluşturucusuz", NonceSize: 12},
+	}
+	for _, alg := range cases {
+		if err := RegisterAlgorithm(alg); err == nil {
+			t.Errorf("%s kaydedildi, hata bekleniyordu", alg.Name)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+// Direction mesajın istemciden sunucuya mı yoksa sunucudan istemciye mi gittiğini belirtir
+type Direction string
+
+const (
+	DirectionRequest  Direction = "request"
+	DirectionResponse Direction = "response"
+)
+
+// bindingLabel AAD'nin başındaki sabit etikettir (istemciyle birebir aynı olmalı)
+const bindingLabel = "uctanuca/aad/v1"
+
+// Binding şifreli mesajı ait olduğu HTTP isteğine bağlar. Alanlar AES-GCM'e ek
+// doğrulanmış veri (AAD) olarak verilir; böylece PUT /api/data için yakalanan
+// bir gövde DELETE /api/data'ya veya bir yanıt isteğe karşı tekrar oynatılamaz.
+type Binding struct {
+	Direction Direction
+	Method    string
+	Path      string
+	SessionID string
+}
+
+// RequestBinding istemciden gelen mesaj için bağlam oluşturur
+func RequestBinding(method, path, sessionID string) Binding {
+	return Binding{Direction: DirectionRequest, Method: method, Path: path, SessionID: sessionID}
+}
+
+// ResponseBinding sunucunun yanıtı için bağlam oluşturur
+func ResponseBinding(method, path, sessionID string) Binding {
+	return Binding{Direction: DirectionResponse, Method: method, Path: path, SessionID: sessionID}
+}
+
+// AAD bağlamı belirsizliksiz bir byte dizisine dönüştürür. Her alan 4 byte
+// big-endian uzunluk ön ekiyle yazılır:
+//
+//	label | direction | METHOD | path | sessionId
+func (b Binding) AAD() []byte {
+	fields := []string{bindingLabel, string(b.Direction), strings.ToUpper(b.Method), b.Path, b.SessionID}
+
+	size := 0
+	for _, f := range fields {
+		size += 4 + len(f)
+	}
+
+	out := make([]byte, 0, size)
+	for _, f := range fields {
+		out = binary.BigEndian.AppendUint32(out, uint32(len(f)))
+		out = append(out, f...)
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package crypto
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock zaman kaynağıdır. Replay penceresi, saat kayması, anahtar ömrü ve
+// rotasyon kontrolleri zamanı bu arayüzden okur; testler sabit veya ileri
+// sarılabilen bir saat, bilinen sapması olan ortamlar kaydırılmış bir saat verebilir.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc sıradan bir fonksiyonu Clock olarak kullanır
+type ClockFunc func() time.Time
+
+// Now Clock arayüzünü uygular
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock işletim sisteminin saatidir (varsayılan)
+var SystemClock Clock = ClockFunc(time.Now)
+
+var (
+	clockMu     sync.RWMutex
+	globalClock = SystemClock
+)
+
+// SetClock paket genelinde kullanılan saati değiştirir: anahtar önbelleği,
+// el sıkışma anahtarlarının ömrü, epoch rotasyonu, replay önbelleği ve Clock
+// alanı boş olan Codec'ler. nil verilirse sistem saatine dönülür.
+func SetClock(c Clock) {
+	if c == nil {
+		c = SystemClock
+	}
+
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	globalClock = c
+}
+
+// Now paket saatine (SetClock) göre şimdiki zamanı döndürür
+func Now() time.Time {
+	clockMu.RLock()
+	c := globalClock
+	clockMu.RUnlock()
+	return c.Now()
+}
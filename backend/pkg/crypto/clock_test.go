@@ -0,0 +1,131 @@
+package crypto
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock testlerde elle ileri sarılan saattir
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// useFakeClock paket saatini test süresince sahte saatle değiştirir
+func useFakeClock(t *testing.T) *fakeClock {
+	t.Helper()
+	clock := newFakeClock()
+	SetClock(clock)
+	t.Cleanup(func() { SetClock(nil) })
+	return clock
+}
+
+// TestCodecClockWindowBoundaries replay penceresi ve saat kaymasının sınır
+// değerlerini sabit saatle doğrular
+func TestCodecClockWindowBoundaries(t *testing.T) {
+	clock := newFakeClock()
+	codec := &Codec{ReplayCache: NewMemoryReplayCache(16, 0), ReplayWindow: time.Minute, ClockSkew: time.Second, Clock: clock}
+	b := RequestBinding("POST", "/api/data", "clock-session")
+
+	cases := []struct {
+		offset time.Duration
+		want   error
+	}{
+		{-time.Minute, nil},
+		{-time.Minute - time.Millisecond, ErrExpired},
+		{time.Second, nil},
+		{time.Second + time.Millisecond, ErrClockSkew},
+	}
+	for _, tc := range cases {
+		payload := map[string]interface{}{"_timestamp": clock.Now().Add(tc.offset).UnixMilli(), "_nonce": "n" + tc.offset.String()}
+		encrypted, err := codec.EncryptData(payload, fuzzKey, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := codec.DecryptData(encrypted, fuzzKey, b); !errors.Is(err, tc.want) {
+			t.Errorf("offset %s: hata %v, beklenen %v", tc.offset, err, tc.want)
+		}
+	}
+}
+
+// TestSetClockKeyExpiry el sıkışma anahtarı ve önbellek ömrünün paket saatine
+// göre dolduğunu doğrular
+func TestSetClockKeyExpiry(t *testing.T) {
+	clock := useFakeClock(t)
+
+	clientPriv, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientPub := base64.StdEncoding.EncodeToString(clientPriv.PublicKey().Bytes())
+	if _, err := PerformHandshake(clientPub, "clock-token", "clock-session", "", time.Hour, RotationPolicy{}, AlgAES256GCM); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DeleteSessionKey("clock-session") })
+
+	cache := NewLRUKeyCache(4, time.Hour)
+	cache.Put("clock-token", "clock-session", fuzzKey.Key)
+
+	clock.Advance(time.Hour - time.Second)
+	if _, err := LookupSessionKey("clock-token", "clock-session"); err != nil {
+		t.Fatalf("süresi dolmamış anahtar bulunamadı: %v", err)
+	}
+	if _, ok := cache.Get("clock-token", "clock-session"); !ok {
+		t.Fatal("süresi dolmamış önbellek kaydı bulunamadı")
+	}
+
+	clock.Advance(time.Second)
+	if _, err := LookupSessionKey("clock-token", "clock-session"); err == nil {
+		t.Fatal("süresi dolan anahtar hâlâ kabul ediliyor")
+	}
+	if _, ok := cache.Get("clock-token", "clock-session"); ok {
+		t.Fatal("süresi dolan önbellek kaydı hâlâ dönüyor")
+	}
+}
+
+// TestSetClockRotation epoch rotasyonunun paket saatiyle tetiklendiğini doğrular
+func TestSetClockRotation(t *testing.T) {
+	clock := useFakeClock(t)
+
+	secret := make([]byte, 32)
+	ring, err := newKeyRing(secret, "clock", "clock-session", RotationPolicy{Interval: time.Minute, GracePeriod: time.Second}, AlgAES256GCM, clock.Now(), clock.Now().Add(time.Hour), [32]byte{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, announced := ring.nextEpoch(); announced {
+		t.Fatal("süre dolmadan sonraki epoch duyuruldu")
+	}
+
+	clock.Advance(time.Minute)
+	ring.currentKey()
+	if epoch, announced := ring.nextEpoch(); !announced || epoch != 1 {
+		t.Fatalf("sonraki epoch %d (duyuruldu=%v), beklenen 1", epoch, announced)
+	}
+
+	// Duyuruya uyulmazsa GracePeriod sonunda epoch zorla ilerler
+	clock.Advance(time.Second)
+	if current := ring.currentKey(); current.Epoch != 1 {
+		t.Fatalf("geçerli epoch %d, beklenen 1", current.Epoch)
+	}
+}
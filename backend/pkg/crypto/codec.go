@@ -0,0 +1,225 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Codec şifreleme/çözme politikasını taşır. Sıfır değerli alanlar paket
+// varsayılanlarını kullanır; paket düzeyindeki EncryptDataWithKey,
+// DecryptDataWithKey vb. fonksiyonlar varsayılan Codec ile çalışır.
+type Codec struct {
+	// ReplayWindow geçmişe dönük kabul edilen en eski _timestamp (varsayılan ReplayWindow)
+	ReplayWindow time.Duration
+	// ClockSkew geleceğe dönük tolere edilen saat farkı (varsayılan MaxClockSkew)
+	ClockSkew time.Duration
+	// ReplayCache nonce tekrarını izleyen depo (varsayılan SetReplayCache ile verilen)
+	ReplayCache ReplayCache
+	// AcceptLegacyFormat sürümsüz nonce||ciphertext formatının kabul
+	// politikasıdır. Sıfır değerde SetAcceptLegacyFormat ayarı izlenir;
+	// LegacyFormatAccept veya LegacyFormatReject bu ayarın önüne geçer.
+	AcceptLegacyFormat LegacyFormat
+	// Rand zarf nonce'larının okunduğu rastgelelik kaynağıdır (varsayılan
+	// crypto/rand.Reader). Yalnızca test vektörleri gibi tekrarlanabilir çıktı
+	// gereken yerlerde değiştirilmelidir; aynı nonce iki kez kullanılmamalıdır.
+	Rand io.Reader
+	// Clock _timestamp kontrolünde kullanılan saattir (varsayılan SetClock ile verilen)
+	Clock Clock
+	// Sequences nil değilse gövde ve query payload'ları _timestamp/_nonce
+	// yerine oturum başına artan _seq ile replay'e karşı korunur; zaman damgası
+	// kontrol edilmez. Akışlarda sıra numarası başlıktaki sequence alanıdır.
+	Sequences SequenceStore
+}
+
+var defaultCodec = &Codec{}
+
+func (c *Codec) replayWindow() time.Duration {
+	if c.ReplayWindow > 0 {
+		return c.ReplayWindow
+	}
+	return ReplayWindow
+}
+
+func (c *Codec) clockSkew() time.Duration {
+	if c.ClockSkew > 0 {
+		return c.ClockSkew
+	}
+	return MaxClockSkew
+}
+
+func (c *Codec) replayCache() ReplayCache {
+	if c.ReplayCache != nil {
+		return c.ReplayCache
+	}
+	return currentReplayCache()
+}
+
+func (c *Codec) acceptLegacy() bool {
+	switch c.AcceptLegacyFormat {
+	case LegacyFormatAccept:
+		return true
+	case LegacyFormatReject:
+		return false
+	}
+	return acceptLegacyFormat.Load()
+}
+
+func (c *Codec) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock.Now()
+	}
+	return Now()
+}
+
+// nonceExpiry nonce'un replay önbelleğinde tutulacağı son zamandır. Önbellek
+// kayıtları paket saatiyle (SetClock) temizlendiğinden süre de o saatle
+// hesaplanır; Codec.Clock kaydırılmış olsa bile nonce pencere boyunca tutulur.
+func (c *Codec) nonceExpiry() time.Time {
+	return Now().Add(c.replayWindow() + c.clockSkew())
+}
+
+// sequenceExpiry sıra numarası penceresinin tutulacağı son zamandır. Anahtarın
+// süresi dolduktan sonra mesajlar zaten çözülemediğinden pencere anahtarla
+// birlikte düşer; süresiz (token'dan türetilen) anahtarlarda DefaultKeyLifetime kullanılır.
+func (c *Codec) sequenceExpiry(sk *SessionKey) time.Time {
+	if !sk.ExpiresAt.IsZero() {
+		return sk.ExpiresAt
+	}
+	return Now().Add(DefaultKeyLifetime)
+}
+
+func (c *Codec) random() io.Reader {
+	if c.Rand != nil {
+		return c.Rand
+	}
+	return rand.Reader
+}
+
+// EncryptData veriyi oturum anahtarıyla v1 zarfı olarak şifreler ve base64
+// string olarak döndürür. b istek bağlamıdır (AAD).
+func (c *Codec) EncryptData(payload interface{}, sk *SessionKey, b Binding) (string, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("JSON marshal hatası: %w", err)
+	}
+
+	envelope, err := sealEnvelope(jsonData, sk, b, c.random())
+	if err != nil {
+		return "", fmt.Errorf("şifreleme hatası: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(envelope), nil
+}
+
+// DecryptData base64 şifreli veriyi oturum anahtarıyla çözer. b istek
+// bağlamıdır (AAD); timestamp ve nonce (veya Sequences açıksa sıra numarası)
+// kontrolleri b.SessionID kapsamında yapılır.
+func (c *Codec) DecryptData(encryptedBase64 string, sk *SessionKey, b Binding) (map[string]interface{}, error) {
+	encryptedData, err := base64.StdEncoding.DecodeString(encryptedBase64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: base64 decode başarısız", ErrBadEncoding)
+	}
+
+	// Zarf sürümüne göre çöz (v1 veya izin verilmişse eski format)
+	plaintext, err := openEnvelope(encryptedData, sk, b, c.acceptLegacy())
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(plaintext, &result); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedJSON, err)
+	}
+
+	// Sıra numarası modu: _seq oturumun kayan penceresinde yeni olmalı (ErrReplay)
+	if c.Sequences != nil {
+		if err := validateSequence(result, sequenceScope(sk, b.SessionID), c.Sequences, c.sequenceExpiry(sk)); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// Replay attack koruması - timestamp kontrolü (ErrExpired, ErrClockSkew)
+	if err := validateTimestamp(result, c.now(), c.replayWindow(), c.clockSkew()); err != nil {
+		return nil, err
+	}
+
+	// Replay attack koruması - aynı nonce ikinci kez kabul edilmez (ErrReplay)
+	expiresAt := c.nonceExpiry()
+	if err := validateNonce(result, b.SessionID, c.replayCache(), expiresAt); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DecryptResponse sunucu yanıtını çözer ve düz metin JSON'u döndürür. Yanıtlar
+// _timestamp/_nonce taşımadığından replay kontrolü yapılmaz; istemci tarafında
+// kullanılır. b yanıt bağlamıdır (ResponseBinding).
+func (c *Codec) DecryptResponse(encryptedBase64 string, sk *SessionKey, b Binding) ([]byte, error) {
+	encryptedData, err := base64.StdEncoding.DecodeString(encryptedBase64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: base64 decode başarısız", ErrBadEncoding)
+	}
+
+	return openEnvelope(encryptedData, sk, b, c.acceptLegacy())
+}
+
+// EncryptQueryParams query parametrelerini şifreler ve URL güvenli base64 döndürür
+func (c *Codec) EncryptQueryParams(params map[string]interface{}, sk *SessionKey, b Binding) (string, error) {
+	encryptedData, err := c.EncryptData(params, sk, b)
+	if err != nil {
+		return "", fmt.Errorf("query parametre şifreleme hatası: %w", err)
+	}
+
+	return convertStandardToUrlSafe(encryptedData), nil
+}
+
+// DecryptQueryParams URL güvenli base64 şifreli query parametrelerini çözer
+func (c *Codec) DecryptQueryParams(encryptedQuery string, sk *SessionKey, b Binding) (map[string]interface{}, error) {
+	standardBase64 := convertUrlSafeToStandard(encryptedQuery)
+
+	// Hata türü (ErrAuthFailed, ErrReplay vb.) errors.Is için korunur
+	decryptedParams, err := c.DecryptData(standardBase64, sk, b)
+	if err != nil {
+		return nil, fmt.Errorf("query parametre çözme/doğrulama başarısız: %w", err)
+	}
+
+	return decryptedParams, nil
+}
+
+// OpenStream şifreli akışı (EnvelopeStreamV1) açar ve akış başlığındaki zaman
+// damgası ile nonce ön ekini (veya Sequences açıksa sıra numarasını)
+// DecryptData ile aynı replay kurallarıyla doğrular.
+func (c *Codec) OpenStream(r io.Reader, sk *SessionKey, b Binding) (*StreamReader, error) {
+	stream, err := OpenStream(r, sk, b)
+	if err != nil {
+		return nil, err
+	}
+
+	header := stream.Header()
+	if c.Sequences != nil {
+		if header.Sequence == 0 {
+			return nil, fmt.Errorf("%w: akış sıra numarası eksik", ErrBadEncoding)
+		}
+		if err := acceptSequence(header.Sequence, sequenceScope(sk, b.SessionID), c.Sequences, c.sequenceExpiry(sk)); err != nil {
+			return nil, err
+		}
+		return stream, nil
+	}
+
+	if err := checkRequestTime(header.Timestamp, c.now(), c.replayWindow(), c.clockSkew()); err != nil {
+		return nil, err
+	}
+
+	expiresAt := c.nonceExpiry()
+	if !c.replayCache().Remember(b.SessionID, header.Nonce(), expiresAt) {
+		return nil, fmt.Errorf("%w: akış nonce'u", ErrReplay)
+	}
+
+	return stream, nil
+}
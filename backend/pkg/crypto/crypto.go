@@ -1,17 +1,11 @@
 package crypto
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"regexp"
-	"sync"
 	"time"
 
 	"golang.org/x/crypto/hkdf"
@@ -29,206 +23,122 @@ var (
 	standardMatcher2 = regexp.MustCompile(`\_`) // _ karakterini eşler
 )
 
-// KeyCache yapısı - Thread-safe için sync.RWMutex eklendi
-type KeyCache struct {
-	sync.RWMutex
-	keys    map[string]cachedKey
-	maxSize int
-}
-
-type cachedKey struct {
-	key       []byte
-	timestamp time.Time
-}
-
-var globalKeyCache = NewKeyCache(1000)
-
-// NewKeyCache yeni bir anahtar önbelleği oluşturur
-func NewKeyCache(maxSize int) *KeyCache {
-	return &KeyCache{
-		keys:    make(map[string]cachedKey),
-		maxSize: maxSize,
-	}
-}
+// DefaultKeyLifetime türetilen ve el sıkışma ile kurulan anahtarların varsayılan ömrüdür
+const DefaultKeyLifetime = time.Hour
 
-// DeriveKeys JWT token ve session ID kullanarak AES-256 anahtarı türetir
+// DeriveKeys JWT token ve session ID kullanarak AES-256 anahtarı türetir.
+// Sonuç anahtar önbelleğinde (bkz. SetKeyCache) tutulur. Dönen dilim
+// çağıranın kopyasıdır; önbellek onu sıfırlamaz, çağıran iş bitince ZeroKey
+// ile sıfırlamalıdır.
 func DeriveKeys(token, sessionId string) ([]byte, error) {
 	if token == "" || sessionId == "" {
 		return nil, errors.New("anahtar türetme için token ve session ID gerekli")
 	}
 
-	cacheKey := fmt.Sprintf("%s|%s", token, sessionId)
-
-	// 1. Kontrol: Read Lock ile hızlıca kontrol
-	globalKeyCache.RLock()
-	if cached, exists := globalKeyCache.keys[cacheKey]; exists {
-		if time.Since(cached.timestamp) < time.Hour { // Anahtar ömrü 1 saat
-			globalKeyCache.RUnlock()
-			return cached.key, nil
-		}
-		// Süresi dolmuş, Read Lock'ı serbest bırak
-		globalKeyCache.RUnlock()
-		// Yazma kilidini al, eski anahtarı silme ve yeni anahtar hesaplama için devam et
-	} else {
-		globalKeyCache.RUnlock()
+	cache := currentKeyCache()
+	if key, ok := cache.Get(token, sessionId); ok {
+		return key, nil
 	}
 
-	// 2. Kontrol (Double-Check Locking) ve Yazma İşlemi: Write Lock ile senkronizasyon
-	globalKeyCache.Lock()
-	defer globalKeyCache.Unlock()
-
-	// Yeniden kontrol: Başka bir goroutine bizim Lock'ımızı beklerken anahtarı eklemiş olabilir
-	if cached, exists := globalKeyCache.keys[cacheKey]; exists {
-		if time.Since(cached.timestamp) < time.Hour {
-			// Cache'e yeni eklenmiş, doğrudan dön
-			return cached.key, nil
-		}
-		// Eski kayıt tekrar süresi dolmuşsa silinir
-		delete(globalKeyCache.keys, cacheKey)
-	}
-
-	// Anahtar türetme işlemi
+	// Anahtar türetme işlemi. Eşzamanlı iki istek aynı anahtarı türetebilir;
+	// sonuç deterministik olduğundan bu zararsızdır.
 	masterKey := []byte(token + sessionId)
+	defer ZeroKey(masterKey)
 	hkdfReader := hkdf.New(sha256.New, masterKey, nil, nil)
 	derivedKey := make([]byte, 32)
 	if _, err := io.ReadFull(hkdfReader, derivedKey); err != nil {
 		return nil, fmt.Errorf("HKDF anahtar türetme hatası: %v", err)
 	}
 
-	// Cache boyut kontrolü ve temizleme
-	if len(globalKeyCache.keys) >= globalKeyCache.maxSize {
-		// En eski kaydı bulup sil
-		var oldestKey string
-		var oldestTime time.Time
-		for key, cached := range globalKeyCache.keys {
-			if oldestTime.IsZero() || cached.timestamp.Before(oldestTime) {
-				oldestTime = cached.timestamp
-				oldestKey = key
-			}
-		}
-		if oldestKey != "" {
-			delete(globalKeyCache.keys, oldestKey)
-		}
-	}
-
-	globalKeyCache.keys[cacheKey] = cachedKey{
-		key:       derivedKey,
-		timestamp: time.Now(),
-	}
-
-	return derivedKey, nil
+	// Önbellek kendi kopyasını tutar; çağırana ayrı bir kopya verilir ve
+	// yerel kopya sıfırlanır
+	cache.Put(token, sessionId, derivedKey)
+	key := cloneKey(derivedKey)
+	ZeroKey(derivedKey)
+	return key, nil
 }
 
-// DecryptData base64 şifreli veriyi çözer
-func DecryptData(encryptedBase64, token, sessionId string) (map[string]interface{}, error) {
-	key, err := DeriveKeys(token, sessionId)
+// DecryptData base64 şifreli veriyi token ve b.SessionID'den türetilen anahtarla çözer (eski istemciler)
+func DecryptData(encryptedBase64, token string, b Binding) (map[string]interface{}, error) {
+	key, err := DeriveKeys(token, b.SessionID)
 	if err != nil {
 		return nil, fmt.Errorf("anahtar türetme hatası: %w", err)
 	}
+	defer ZeroKey(key)
 
-	encryptedData, err := base64.StdEncoding.DecodeString(encryptedBase64)
-	if err != nil {
-		return nil, errors.New("base64 decode başarısız")
-	}
-
-	if len(encryptedData) < 13 {
-		return nil, errors.New("şifreli veri çok kısa")
-	}
-
-	nonce := encryptedData[:12]
-	ciphertext := encryptedData[12:]
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, errors.New("AES şifre oluşturma başarısız")
-	}
-
-	aesgcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, errors.New("GCM modu oluşturma başarısız")
-	}
-
-	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, nil)
-	if err != nil {
-		// Hata detayını gizle (Oracle Attack Koruması)
-		return nil, errors.New("şifre çözme veya doğrulama başarısız")
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(plaintext, &result); err != nil {
-		return nil, errors.New("JSON parse başarısız")
-	}
+	return DecryptDataWithKey(encryptedBase64, &SessionKey{Key: key}, b)
+}
 
-	// Replay attack koruması - timestamp kontrolü
-	if err := validateTimestamp(result); err != nil {
-		// Hata detayını gizle (Oracle Attack Koruması)
-		return nil, errors.New("timestamp doğrulama başarısız")
-	}
+// DecryptDataWithKey base64 şifreli veriyi verilen oturum anahtarıyla varsayılan politikayla çözer.
+// b istek bağlamıdır (AAD); timestamp ve nonce kontrolleri b.SessionID kapsamında yapılır.
+func DecryptDataWithKey(encryptedBase64 string, sk *SessionKey, b Binding) (map[string]interface{}, error) {
+	return defaultCodec.DecryptData(encryptedBase64, sk, b)
+}
 
-	return result, nil
+// DecryptResponseWithKey sunucu yanıtını verilen oturum anahtarıyla varsayılan
+// politikayla çözer (istemciler için, replay kontrolü yapılmaz)
+func DecryptResponseWithKey(encryptedBase64 string, sk *SessionKey, b Binding) ([]byte, error) {
+	return defaultCodec.DecryptResponse(encryptedBase64, sk, b)
 }
 
-// validateTimestamp replay attack koruması için timestamp kontrolü
-func validateTimestamp(data map[string]interface{}) error {
+// validateTimestamp replay attack koruması için timestamp kontrolü. now
+// sunucunun saatidir (Codec.Clock).
+func validateTimestamp(data map[string]interface{}, now time.Time, window, skew time.Duration) error {
 	timestampVal, exists := data["_timestamp"]
 	if !exists {
 		// Detaylı hata verme
-		return errors.New("güvenlik damgası (timestamp) eksik")
+		return fmt.Errorf("%w: güvenlik damgası (timestamp) eksik", ErrMalformedJSON)
 	}
 
 	timestamp, ok := timestampVal.(float64)
 	if !ok {
-		return errors.New("geçersiz güvenlik damgası formatı")
+		return fmt.Errorf("%w: geçersiz güvenlik damgası formatı", ErrMalformedJSON)
 	}
 
 	requestTime := time.Unix(0, int64(timestamp)*int64(time.Millisecond))
-	now := time.Now()
+	return checkRequestTime(requestTime, now, window, skew)
+}
 
-	// 5 dakikadan eski istekleri reddet (Replay Attack Koruması)
-	if now.Sub(requestTime) > 5*time.Minute {
-		return errors.New("istek zaman aşımına uğradı")
+// checkRequestTime isteğin zamanının kabul penceresi içinde olduğunu doğrular
+func checkRequestTime(requestTime, now time.Time, window, skew time.Duration) error {
+	// Pencereden (varsayılan 5 dakika) eski istekleri reddet (Replay Attack Koruması)
+	if now.Sub(requestTime) > window {
+		return fmt.Errorf("%w: istek %s önce oluşturulmuş", ErrExpired, now.Sub(requestTime).Round(time.Second))
 	}
 
-	// 5 saniyeden yeni istekleri reddet (Clock Skew Koruması)
-	if requestTime.Sub(now) > 5*time.Second {
-		return errors.New("istemci saati çok ileri")
+	// İzin verilen kaymadan (varsayılan 5 saniye) ileri istekleri reddet (Clock Skew Koruması)
+	if requestTime.Sub(now) > skew {
+		return fmt.Errorf("%w: %s ileri", ErrClockSkew, requestTime.Sub(now).Round(time.Millisecond))
 	}
 
 	return nil
 }
 
-// EncryptData veriyi şifreler ve base64 string olarak döndürür
-func EncryptData(payload interface{}, token, sessionId string) (string, error) {
-	key, err := DeriveKeys(token, sessionId)
+// EncryptData veriyi token ve b.SessionID'den türetilen anahtarla şifreler ve base64 string olarak döndürür (eski istemciler)
+func EncryptData(payload interface{}, token string, b Binding) (string, error) {
+	key, err := DeriveKeys(token, b.SessionID)
 	if err != nil {
 		return "", fmt.Errorf("anahtar türetme hatası: %w", err)
 	}
+	defer ZeroKey(key)
 
-	jsonData, err := json.Marshal(payload)
-	if err != nil {
-		return "", fmt.Errorf("JSON marshal hatası: %w", err)
-	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", fmt.Errorf("AES şifre oluşturma hatası: %w", err)
-	}
-
-	aesgcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return "", fmt.Errorf("GCM modu oluşturma hatası: %w", err)
-	}
+	return EncryptDataWithKey(payload, &SessionKey{Key: key}, b)
+}
 
-	nonce := make([]byte, 12)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", fmt.Errorf("nonce oluşturma hatası: %w", err)
-	}
+// EncryptDataWithKey veriyi verilen oturum anahtarıyla v1 zarfı olarak şifreler
+// ve base64 string olarak döndürür. b istek bağlamıdır (AAD).
+func EncryptDataWithKey(payload interface{}, sk *SessionKey, b Binding) (string, error) {
+	return defaultCodec.EncryptData(payload, sk, b)
+}
 
-	// GCM ile şifrele, Tag otomatik olarak eklenir
-	encryptedData := aesgcm.Seal(nil, nonce, jsonData, nil)
-	finalData := append(nonce, encryptedData...)
+// convertStandardToUrlSafe standart base64'ü padding'siz URL güvenli base64'e çevirir
+func convertStandardToUrlSafe(standard string) string {
+	urlSafe := standard
+	urlSafe = urlSafeBase64Matcher.ReplaceAllString(urlSafe, "-")
+	urlSafe = urlSafeBase64Matcher2.ReplaceAllString(urlSafe, "_")
 
-	return base64.StdEncoding.EncodeToString(finalData), nil
+	// Padding (=) işaretlerini kaldır
+	return noPaddingMatcher.ReplaceAllString(urlSafe, "")
 }
 
 // convertUrlSafeToStandard URL güvenli base64'ü standart base64'e dönüştürür
@@ -247,32 +157,34 @@ func convertUrlSafeToStandard(urlSafeBase64 string) string {
 	return standard
 }
 
-// DecryptQueryParams şifreli query parametrelerini çözer
-func DecryptQueryParams(encryptedQuery, token, sessionId string) (map[string]interface{}, error) {
-	standardBase64 := convertUrlSafeToStandard(encryptedQuery)
-
-	// DecryptData artık genel hata döndürdüğü için loglamayı burada yapmayız.
-	decryptedParams, err := DecryptData(standardBase64, token, sessionId)
+// DecryptQueryParams şifreli query parametrelerini token'dan türetilen anahtarla çözer (eski istemciler)
+func DecryptQueryParams(encryptedQuery, token string, b Binding) (map[string]interface{}, error) {
+	key, err := DeriveKeys(token, b.SessionID)
 	if err != nil {
-		// Hata detayını gizle ve generic bir hata mesajı döndür.
-		return nil, errors.New("query parametre çözme/doğrulama başarısız")
+		return nil, fmt.Errorf("anahtar türetme hatası: %w", err)
 	}
+	defer ZeroKey(key)
+
+	return DecryptQueryParamsWithKey(encryptedQuery, &SessionKey{Key: key}, b)
+}
 
-	return decryptedParams, nil
+// DecryptQueryParamsWithKey şifreli query parametrelerini verilen oturum anahtarıyla çözer
+func DecryptQueryParamsWithKey(encryptedQuery string, sk *SessionKey, b Binding) (map[string]interface{}, error) {
+	return defaultCodec.DecryptQueryParams(encryptedQuery, sk, b)
 }
 
-// EncryptQueryParams query parametrelerini şifreler
-func EncryptQueryParams(params map[string]interface{}, token, sessionId string) (string, error) {
-	encryptedData, err := EncryptData(params, token, sessionId)
+// EncryptQueryParams query parametrelerini token'dan türetilen anahtarla şifreler (eski istemciler)
+func EncryptQueryParams(params map[string]interface{}, token string, b Binding) (string, error) {
+	key, err := DeriveKeys(token, b.SessionID)
 	if err != nil {
-		return "", fmt.Errorf("query parametre şifreleme hatası: %w", err)
+		return "", fmt.Errorf("anahtar türetme hatası: %w", err)
 	}
+	defer ZeroKey(key)
 
-	// URL güvenli Base64 formatına çevir
-	urlSafeBase64 := encryptedData
-	urlSafeBase64 = urlSafeBase64Matcher.ReplaceAllString(urlSafeBase64, "-")
-	urlSafeBase64 = urlSafeBase64Matcher2.ReplaceAllString(urlSafeBase64, "_")
+	return EncryptQueryParamsWithKey(params, &SessionKey{Key: key}, b)
+}
 
-	// Padding (=) işaretlerini kaldır
-	return noPaddingMatcher.ReplaceAllString(urlSafeBase64, ""), nil
+// EncryptQueryParamsWithKey query parametrelerini verilen oturum anahtarıyla şifreler
+func EncryptQueryParamsWithKey(params map[string]interface{}, sk *SessionKey, b Binding) (string, error) {
+	return defaultCodec.EncryptQueryParams(params, sk, b)
 }
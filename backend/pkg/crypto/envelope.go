@@ -0,0 +1,233 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// Şifreli veri zarfı (envelope) sürümleri ve algoritma kimlikleri.
+// Ayrıntılı format: memory-bank/wireFormat.md
+const (
+	EnvelopeV1 byte = 0x01
+
+	AlgAES256GCM         byte = 0x01
+	AlgChaCha20Poly1305  byte = 0x02
+	AlgXChaCha20Poly1305 byte = 0x03
+	AlgAES256GCMSIV      byte = 0x04
+)
+
+const gcmNonceSize = 12
+
+// acceptLegacyFormat açıksa sürüm baytı olmayan eski nonce||ciphertext formatı
+// kendi politikası olmayan (LegacyFormatDefault) Codec'lerde çözülür
+var acceptLegacyFormat atomic.Bool
+
+// LegacyFormat bir Codec'in eski (sürümsüz) formatı kabul politikasıdır.
+// Sıfır değer süreç genelindeki SetAcceptLegacyFormat ayarını izler; açıkça
+// verilen kabul veya ret bu ayarın önüne geçer.
+type LegacyFormat int8
+
+const (
+	// LegacyFormatDefault SetAcceptLegacyFormat ayarını izler
+	LegacyFormatDefault LegacyFormat = iota
+	// LegacyFormatAccept genel ayar kapalı olsa da eski formatı kabul eder
+	LegacyFormatAccept
+	// LegacyFormatReject genel ayar açık olsa da eski formatı reddeder
+	LegacyFormatReject
+)
+
+// LegacyFormatOf enabled'a göre açık kabul veya ret politikası döndürür
+func LegacyFormatOf(enabled bool) LegacyFormat {
+	if enabled {
+		return LegacyFormatAccept
+	}
+	return LegacyFormatReject
+}
+
+// SetAcceptLegacyFormat eski (sürümsüz) şifreli veri formatının kabulünü süreç
+// genelinde açar/kapatır. Codec.AcceptLegacyFormat ile politikası verilen
+// Codec'ler bu ayardan etkilenmez.
+func SetAcceptLegacyFormat(enabled bool) {
+	acceptLegacyFormat.Store(enabled)
+}
+
+// Envelope sürümlü şifreli veri zarfıdır:
+//
+//	version(1) | algorithm(1) | keyIdLen(1) | keyId(n) | nonce | ciphertext+tag
+type Envelope struct {
+	Version    byte
+	Algorithm  byte
+	KeyID      string
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// Header zarfın şifrelenmeyen başlık kısmını döndürür. Başlık AEAD'e ek veri
+// (AAD) olarak bağlanır; böylece sürüm, algoritma veya anahtar kimliği
+// değiştirilirse doğrulama başarısız olur.
+func (e *Envelope) Header() []byte {
+	header := make([]byte, 0, 3+len(e.KeyID))
+	header = append(header, e.Version, e.Algorithm, byte(len(e.KeyID)))
+	return append(header, e.KeyID...)
+}
+
+// Marshal zarfı byte dizisine dönüştürür
+func (e *Envelope) Marshal() []byte {
+	header := e.Header()
+	out := make([]byte, 0, len(header)+len(e.Nonce)+len(e.Ciphertext))
+	out = append(out, header...)
+	out = append(out, e.Nonce...)
+	return append(out, e.Ciphertext...)
+}
+
+// ParseEnvelope sürümlü zarfı ayrıştırır
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	if len(data) < 3 {
+		return nil, fmt.Errorf("%w: zarf çok kısa", ErrBadEncoding)
+	}
+
+	env := &Envelope{Version: data[0], Algorithm: data[1]}
+	if env.Version != EnvelopeV1 {
+		return nil, fmt.Errorf("%w: desteklenmeyen zarf sürümü: %d", ErrBadEncoding, env.Version)
+	}
+
+	alg, err := LookupAlgorithm(env.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	nonceSize := alg.NonceSize
+
+	keyIDLen := int(data[2])
+	rest := data[3:]
+	// Anahtar kimliği + nonce + en az bir byte şifreli veri + tag
+	if len(rest) < keyIDLen+nonceSize+1 {
+		return nil, fmt.Errorf("%w: zarf çok kısa", ErrBadEncoding)
+	}
+
+	env.KeyID = string(rest[:keyIDLen])
+	rest = rest[keyIDLen:]
+	env.Nonce = rest[:nonceSize]
+	env.Ciphertext = rest[nonceSize:]
+
+	return env, nil
+}
+
+func newAESGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New("AES şifre oluşturma başarısız")
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.New("GCM modu oluşturma başarısız")
+	}
+	return aesgcm, nil
+}
+
+// envelopeAAD AEAD'e verilen ek veriyi oluşturur: zarf başlığı || istek bağlamı
+func envelopeAAD(env *Envelope, b Binding) []byte {
+	return append(env.Header(), b.AAD()...)
+}
+
+// sealEnvelope düz metni oturum anahtarının algoritmasıyla şifreler ve v1
+// zarfı üretir. Nonce random'dan okunur.
+func sealEnvelope(plaintext []byte, sk *SessionKey, b Binding, random io.Reader) ([]byte, error) {
+	if len(sk.ID) > 255 {
+		return nil, errors.New("anahtar kimliği çok uzun")
+	}
+
+	alg, err := sk.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := alg.New(sk.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, alg.NonceSize)
+	if _, err := io.ReadFull(random, nonce); err != nil {
+		return nil, fmt.Errorf("nonce oluşturma hatası: %w", err)
+	}
+
+	env := &Envelope{
+		Version:   EnvelopeV1,
+		Algorithm: alg.ID,
+		KeyID:     sk.ID,
+		Nonce:     nonce,
+	}
+	// AEAD ile şifrele, Tag otomatik olarak eklenir
+	env.Ciphertext = aead.Seal(nil, nonce, plaintext, envelopeAAD(env, b))
+	sk.recordUse(len(plaintext))
+
+	return env.Marshal(), nil
+}
+
+// openEnvelope şifreli veriyi sürüm baytına göre çözer. Eski format kabulü
+// açıksa, v1 olarak çözülemeyen veri sürümsüz nonce||ciphertext olarak denenir
+// (eski verinin ilk baytı rastgele nonce olduğundan 0x01 ile çakışabilir).
+// Eski format AAD taşımadığından istek bağlamı doğrulanamaz.
+func openEnvelope(data []byte, sk *SessionKey, b Binding, acceptLegacy bool) ([]byte, error) {
+	if len(data) > 0 && data[0] == EnvelopeV1 {
+		plaintext, err := openV1(data, sk, b)
+		if err == nil || !acceptLegacy {
+			return plaintext, err
+		}
+	} else if !acceptLegacy {
+		return nil, fmt.Errorf("%w: desteklenmeyen zarf sürümü", ErrBadEncoding)
+	}
+
+	return openLegacy(data, sk)
+}
+
+func openV1(data []byte, sk *SessionKey, b Binding) ([]byte, error) {
+	env, err := ParseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rotasyon sırasında önceki veya duyurulan epoch'un anahtarı da kabul edilir
+	key, err := sk.resolve(env.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Algoritma zarftan okunur; başlık AAD'e bağlı olduğundan değiştirilemez
+	aead, err := key.aeadFor(env.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, envelopeAAD(env, b))
+	if err != nil {
+		// Hata detayını gizle (Oracle Attack Koruması)
+		return nil, ErrAuthFailed
+	}
+	key.recordUse(len(plaintext))
+	key.accepted()
+	return plaintext, nil
+}
+
+// openLegacy sürümsüz nonce(12)||ciphertext formatını çözer (yalnızca AES-256-GCM)
+func openLegacy(data []byte, sk *SessionKey) ([]byte, error) {
+	if len(data) < gcmNonceSize+1 {
+		return nil, fmt.Errorf("%w: şifreli veri çok kısa", ErrBadEncoding)
+	}
+
+	aesgcm, err := newAESGCM(sk.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aesgcm.Open(nil, data[:gcmNonceSize], data[gcmNonceSize:], nil)
+	if err != nil {
+		// Hata detayını gizle (Oracle Attack Koruması)
+		return nil, ErrAuthFailed
+	}
+	return plaintext, nil
+}
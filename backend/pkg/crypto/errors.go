@@ -0,0 +1,21 @@
+package crypto
+
+import "errors"
+
+// Şifre çözme ve doğrulama hatalarının türleri. Dönen hatalar ayrıntıyı
+// sarmalayarak taşır ve errors.Is ile sınıflandırılabilir. Ayrıntılar yalnızca
+// loglanmalı, istemciye genel bir mesaj dönülmelidir (Oracle Attack Koruması).
+var (
+	// ErrBadEncoding base64, zarf veya akış biçimi çözümlenemedi
+	ErrBadEncoding = errors.New("geçersiz şifreli veri biçimi")
+	// ErrAuthFailed AEAD doğrulaması başarısız veya anahtar kimliği eşleşmiyor
+	ErrAuthFailed = errors.New("şifre çözme veya doğrulama başarısız")
+	// ErrReplay nonce bu oturumda daha önce kullanılmış
+	ErrReplay = errors.New("nonce daha önce kullanılmış")
+	// ErrExpired _timestamp kabul penceresinden eski
+	ErrExpired = errors.New("istek zaman aşımına uğradı")
+	// ErrClockSkew _timestamp izin verilen saat kaymasından ileri
+	ErrClockSkew = errors.New("istemci saati çok ileri")
+	// ErrMalformedJSON düz metin geçerli JSON değil veya güvenlik alanları eksik/hatalı
+	ErrMalformedJSON = errors.New("geçersiz JSON yükü")
+)
@@ -0,0 +1,134 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fuzz hedefleri saldırganın kontrol ettiği girdiyi ayrıştıran fonksiyonları
+// çalıştırır. Ortak değişmezler: panik yok ve her hata sentinel hatalardan
+// birine sarılmış olmalı (middleware log etiketini bunlardan çıkarır).
+//
+//	go test ./backend/pkg/crypto -run '^$' -fuzz FuzzDecryptData -fuzztime 30s
+
+var fuzzKey = &SessionKey{ID: "fuzz.0", Key: bytes.Repeat([]byte{0x42}, 32)}
+
+var fuzzBinding = RequestBinding("POST", "/api/data", "fuzz-session")
+
+// sentinelErrors şifre çözme yolunun döndürebileceği sınıflandırılmış hatalardır
+var sentinelErrors = []error{ErrBadEncoding, ErrAuthFailed, ErrMalformedJSON, ErrExpired, ErrClockSkew, ErrReplay}
+
+func requireClassified(t *testing.T, err error) {
+	t.Helper()
+	for _, sentinel := range sentinelErrors {
+		if errors.Is(err, sentinel) {
+			return
+		}
+	}
+	t.Fatalf("sınıflandırılmamış hata: %v", err)
+}
+
+func freshPayload() map[string]interface{} {
+	return map[string]interface{}{
+		"_timestamp": time.Now().UnixMilli(),
+		"_nonce":     "0123456789abcdef",
+		"action":     "create",
+	}
+}
+
+func FuzzDecryptData(f *testing.F) {
+	valid, err := EncryptDataWithKey(freshPayload(), fuzzKey, fuzzBinding)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(valid, false)
+	f.Add(valid[:len(valid)/2], false)
+	f.Add(strings.Replace(valid, valid[10:11], "A", 1), true)
+	f.Add("", false)
+	f.Add("AQEA", true)
+	f.Add("!!!not-base64!!!", false)
+	f.Add(base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x01}, 64)), true)
+
+	f.Fuzz(func(t *testing.T, input string, legacy bool) {
+		codec := &Codec{ReplayCache: NewMemoryReplayCache(16, 0), AcceptLegacyFormat: LegacyFormatOf(legacy)}
+
+		result, err := codec.DecryptData(input, fuzzKey, fuzzBinding)
+		if err != nil {
+			requireClassified(t, err)
+			return
+		}
+		if _, ok := result["_timestamp"]; !ok {
+			t.Fatal("_timestamp olmadan kabul edildi")
+		}
+		if _, ok := result["_nonce"]; !ok {
+			t.Fatal("_nonce olmadan kabul edildi")
+		}
+
+		// Aynı veri ikinci kez kabul edilmemeli
+		if _, err := codec.DecryptData(input, fuzzKey, fuzzBinding); !errors.Is(err, ErrReplay) {
+			t.Fatalf("tekrar oynatılan veri kabul edildi: %v", err)
+		}
+	})
+}
+
+func FuzzConvertUrlSafeToStandard(f *testing.F) {
+	for _, seed := range []string{"", "-_", "+/8", "AQEA-_8", "YQ", "YWI", "YWJj", "====", "A-B_C"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		standard := convertUrlSafeToStandard(input)
+		if strings.ContainsAny(standard, "-_") {
+			t.Fatalf("URL güvenli karakter kaldı: %q", standard)
+		}
+
+		// Geçerli padding'siz URL güvenli girdi aynı byte'lara çözülmeli.
+		// encoding/base64 satır sonlarını atlar; satır sonu içeren girdi
+		// padding hesabını bozar ve ErrBadEncoding ile reddedilir.
+		want, err := base64.RawURLEncoding.DecodeString(input)
+		if err != nil || strings.ContainsAny(input, "\r\n") {
+			return
+		}
+		got, err := base64.StdEncoding.DecodeString(standard)
+		if err != nil {
+			t.Fatalf("geçerli girdi %q standart base64'e çevrilemedi: %q", input, standard)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%q: byte'lar farklı", input)
+		}
+		if back := convertStandardToUrlSafe(standard); back != input {
+			t.Fatalf("geri dönüşüm %q, beklenen %q", back, input)
+		}
+	})
+}
+
+func FuzzDecryptQueryParams(f *testing.F) {
+	queryBinding := RequestBinding("GET", "/api/data", "fuzz-session")
+	valid, err := EncryptQueryParamsWithKey(freshPayload(), fuzzKey, queryBinding)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(valid)
+	f.Add(valid + "A")
+	f.Add(valid[1:])
+	f.Add("")
+	f.Add("-_-_")
+	f.Add("AQEA=")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		codec := &Codec{ReplayCache: NewMemoryReplayCache(16, 0)}
+
+		params, err := codec.DecryptQueryParams(input, fuzzKey, queryBinding)
+		if err != nil {
+			requireClassified(t, err)
+			return
+		}
+		if _, ok := params["_nonce"]; !ok {
+			t.Fatal("_nonce olmadan kabul edildi")
+		}
+	})
+}
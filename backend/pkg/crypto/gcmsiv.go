@@ -0,0 +1,222 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// AES-GCM-SIV (RFC 8452) nonce tekrarına dayanıklı bir AEAD'dir: aynı nonce
+// iki kez kullanılırsa yalnızca aynı mesajın tekrarlandığı anlaşılır, anahtar
+// veya düz metin sızmaz. Standart kütüphanede ve x/crypto'da bulunmadığından
+// burada uygulanmıştır; RFC 8452 Ek C test vektörleriyle doğrulanır
+// (gcmsiv_test.go). POLYVAL sabit zamanlı, bit bit hesaplanır; büyük akışlar
+// için AES-GCM veya ChaCha20-Poly1305 daha hızlıdır.
+
+const (
+	gcmSIVNonceSize = 12
+	gcmSIVTagSize   = 16
+	// gcmSIVMaxInput RFC 8452'nin düz metin ve ek veri için üst sınırıdır (2^36 bayt)
+	gcmSIVMaxInput = 1 << 36
+)
+
+// gcmSIV cipher.AEAD arayüzünü AES-256-GCM-SIV ile uygular
+type gcmSIV struct {
+	// kgk mesaj başına anahtar türeten anahtardır (key-generating key)
+	kgk cipher.Block
+}
+
+// newAESGCMSIV 32 baytlık anahtarla AES-256-GCM-SIV oluşturur
+func newAESGCMSIV(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("AES-GCM-SIV için 32 baytlık anahtar gerekli")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New("AES şifre oluşturma başarısız")
+	}
+	return &gcmSIV{kgk: block}, nil
+}
+
+func (g *gcmSIV) NonceSize() int { return gcmSIVNonceSize }
+func (g *gcmSIV) Overhead() int  { return gcmSIVTagSize }
+
+// deriveKeys nonce'a özgü kimlik doğrulama ve şifreleme anahtarlarını türetir
+// (RFC 8452 bölüm 4): her biri LE32(i) || nonce bloğunun şifresinin ilk 8 baytı
+func (g *gcmSIV) deriveKeys(nonce []byte) (authKey [16]byte, encBlock cipher.Block) {
+	var in, out [16]byte
+	var encKey [32]byte
+	copy(in[4:], nonce)
+
+	for i := uint32(0); i < 6; i++ {
+		binary.LittleEndian.PutUint32(in[:4], i)
+		g.kgk.Encrypt(out[:], in[:])
+		if i < 2 {
+			copy(authKey[i*8:], out[:8])
+		} else {
+			copy(encKey[(i-2)*8:], out[:8])
+		}
+	}
+
+	encBlock, _ = aes.NewCipher(encKey[:])
+	return authKey, encBlock
+}
+
+// tag düz metin ve ek verinin etiketini hesaplar
+func (g *gcmSIV) tag(authKey [16]byte, encBlock cipher.Block, nonce, plaintext, additionalData []byte) [16]byte {
+	p := newPolyval(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:8], uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+	p.update(lengths[:])
+
+	s := p.sum()
+	for i := range nonce {
+		s[i] ^= nonce[i]
+	}
+	s[15] &= 0x7f
+
+	var tag [16]byte
+	encBlock.Encrypt(tag[:], s[:])
+	return tag
+}
+
+// ctr etiketten türetilen sayaçla (ilk 32 bit, little-endian) dst = src XOR anahtar akışı yapar
+func ctr(encBlock cipher.Block, tag [16]byte, dst, src []byte) {
+	counter := tag
+	counter[15] |= 0x80
+
+	var keystream [16]byte
+	for len(src) > 0 {
+		encBlock.Encrypt(keystream[:], counter[:])
+		binary.LittleEndian.PutUint32(counter[:4], binary.LittleEndian.Uint32(counter[:4])+1)
+
+		n := subtle.XORBytes(dst, src, keystream[:])
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+func (g *gcmSIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("crypto: AES-GCM-SIV nonce boyutu hatalı")
+	}
+	if uint64(len(plaintext)) > gcmSIVMaxInput || uint64(len(additionalData)) > gcmSIVMaxInput {
+		panic("crypto: AES-GCM-SIV girdisi çok büyük")
+	}
+
+	authKey, encBlock := g.deriveKeys(nonce)
+	tag := g.tag(authKey, encBlock, nonce, plaintext, additionalData)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmSIVTagSize)
+	ctr(encBlock, tag, out[:len(plaintext)], plaintext)
+	copy(out[len(plaintext):], tag[:])
+	return ret
+}
+
+func (g *gcmSIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("crypto: AES-GCM-SIV nonce boyutu hatalı")
+	}
+	if len(ciphertext) < gcmSIVTagSize || uint64(len(ciphertext)) > gcmSIVMaxInput+gcmSIVTagSize ||
+		uint64(len(additionalData)) > gcmSIVMaxInput {
+		return nil, errOpen
+	}
+
+	var tag [16]byte
+	copy(tag[:], ciphertext[len(ciphertext)-gcmSIVTagSize:])
+	ciphertext = ciphertext[:len(ciphertext)-gcmSIVTagSize]
+
+	authKey, encBlock := g.deriveKeys(nonce)
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	ctr(encBlock, tag, out, ciphertext)
+
+	expected := g.tag(authKey, encBlock, nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(expected[:], tag[:]) != 1 {
+		// Doğrulanmamış düz metin çağırana bırakılmaz
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+var errOpen = errors.New("crypto: mesaj doğrulaması başarısız")
+
+// sliceForAppend in'i n bayt büyütür; ret tüm dilim, out eklenen kısımdır
+func sliceForAppend(in []byte, n int) (ret, out []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		ret = in[:total]
+	} else {
+		ret = make([]byte, total)
+		copy(ret, in)
+	}
+	out = ret[len(in):]
+	return
+}
+
+// polyval RFC 8452'deki POLYVAL evrensel hash'idir. Alan elemanları
+// little-endian 128 bit tamsayılardır (x^i katsayısı i. bit); çarpım
+// dot(a, b) = a * b * x^-128 mod x^128 + x^127 + x^126 + x^121 + 1.
+type polyval struct {
+	h, s [2]uint64
+	// buf tamamlanmamış son blok (sıfırla doldurulur)
+	buf  [16]byte
+	nbuf int
+}
+
+func newPolyval(key [16]byte) *polyval {
+	return &polyval{h: [2]uint64{binary.LittleEndian.Uint64(key[:8]), binary.LittleEndian.Uint64(key[8:])}}
+}
+
+// update veriyi 16 baytlık bloklar halinde işler. Her update çağrısının sonu
+// sıfırla doldurulur (RFC 8452: ek veri ve düz metin ayrı ayrı doldurulur).
+func (p *polyval) update(data []byte) {
+	for len(data) > 0 {
+		n := copy(p.buf[p.nbuf:], data)
+		p.nbuf += n
+		data = data[n:]
+		if p.nbuf == 16 {
+			p.block()
+		}
+	}
+	if p.nbuf > 0 {
+		clear(p.buf[p.nbuf:])
+		p.block()
+	}
+}
+
+func (p *polyval) block() {
+	p.s[0] ^= binary.LittleEndian.Uint64(p.buf[:8])
+	p.s[1] ^= binary.LittleEndian.Uint64(p.buf[8:])
+	p.s = polyvalDot(p.s, p.h)
+	p.nbuf = 0
+}
+
+func (p *polyval) sum() [16]byte {
+	var out [16]byte
+	binary.LittleEndian.PutUint64(out[:8], p.s[0])
+	binary.LittleEndian.PutUint64(out[8:], p.s[1])
+	return out
+}
+
+// polyvalDot a * b * x^-128 çarpımını sabit zamanda hesaplar: a'nın her biti
+// için b eklenir ve ara sonuç x'e bölünür (Montgomery çarpımı)
+func polyvalDot(a, b [2]uint64) [2]uint64 {
+	var r [2]uint64
+	for i := 0; i < 128; i++ {
+		bit := (a[i/64] >> (i % 64)) & 1
+		mask := -bit
+		r[0] ^= b[0] & mask
+		r[1] ^= b[1] & mask
+
+		// r / x mod P: x^0 katsayısı 1 ise önce P eklenir (P / x = x^127 + x^126 + x^125 + x^120)
+		carry := -(r[0] & 1)
+		r[0] = r[0]>>1 | r[1]<<63
+		r[1] = r[1]>>1 ^ (0xe100000000000000 & carry)
+	}
+	return r
+}
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"testing"
+)
+
+// RFC 8452 Ek C test vektörleri
+func TestPolyval(t *testing.T) {
+	var key [16]byte
+	copy(key[:], mustHex(t, "25629347589242761d31f826ba4b757b"))
+
+	p := newPolyval(key)
+	p.update(mustHex(t, "4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362"))
+	sum := p.sum()
+
+	if want := mustHex(t, "f7a3b47b846119fae5b7866cf5e5b77e"); !bytes.Equal(sum[:], want) {
+		t.Fatalf("POLYVAL %x, beklenen %x", sum, want)
+	}
+}
+
+func TestAESGCMSIV(t *testing.T) {
+	key1 := mustHex(t, "0100000000000000000000000000000000000000000000000000000000000000")
+	nonce3 := mustHex(t, "030000000000000000000000")
+
+	cases := []struct {
+		name                   string
+		key, nonce, aad, plain []byte
+		want                   string
+	}{
+		{"boş", key1, nonce3, nil, nil, "07f5f4169bbf55a8400cd47ea6fd400f"},
+		{"8 bayt", key1, nonce3, nil, mustHex(t, "0100000000000000"), "c2ef328e5c71c83b843122130f7364b761e0b97427e3df28"},
+		{"12 bayt", key1, nonce3, nil, mustHex(t, "010000000000000000000000"), "9aab2aeb3faa0a34aea8e2b18ca50da9ae6559e48fd10f6e5c9ca17e"},
+		{"ek veri", key1, nonce3, mustHex(t, "01"), mustHex(t, "0200000000000000"), "1de22967237a813291213f267e3b452f02d01ae33e4ec854"},
+		// Sayaç 2^32'de sarar (RFC 8452 Ek C.3)
+		{"sayaç taşması", make([]byte, 32), make([]byte, 12), nil,
+			mustHex(t, "eb3640277c7ffd1303c7a542d02d3e4c0000000000000000"),
+			"18ce4f0b8cb4d0cac65fea8f79257b20888e53e72299e56dffffffff000000000000000000000000"},
+	}
+	for _, tc := range cases {
+		aead, err := newAESGCMSIV(tc.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sealed := aead.Seal(nil, tc.nonce, tc.plain, tc.aad)
+		if want := mustHex(t, tc.want); !bytes.Equal(sealed, want) {
+			t.Errorf("%s: şifreli metin %x, beklenen %x", tc.name, sealed, want)
+			continue
+		}
+
+		opened, err := aead.Open(nil, tc.nonce, sealed, tc.aad)
+		if err != nil || !bytes.Equal(opened, tc.plain) {
+			t.Errorf("%s: çözme %x, %v", tc.name, opened, err)
+		}
+
+		sealed[0] ^= 1
+		if _, err := aead.Open(nil, tc.nonce, sealed, tc.aad); err == nil {
+			t.Errorf("%s: değiştirilmiş şifreli metin kabul edildi", tc.name)
+		}
+	}
+}
+
+// TestAESGCMSIVNonceReuse aynı nonce ile şifrelenen farklı mesajların farklı
+// etiket ve anahtar akışı kullandığını doğrular (nonce tekrarına dayanıklılık)
+func TestAESGCMSIVNonceReuse(t *testing.T) {
+	aead, err := newAESGCMSIV(bytes.Repeat([]byte{0x42}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, gcmSIVNonceSize)
+	first, second := []byte("mesaj bir"), []byte("mesaj iki")
+
+	a := aead.Seal(nil, nonce, first, nil)
+	b := aead.Seal(nil, nonce, second, nil)
+	if bytes.Equal(a[len(first):], b[len(second):]) {
+		t.Fatal("aynı nonce ile farklı mesajların etiketi aynı")
+	}
+
+	keystream := func(ciphertext, plaintext []byte) []byte {
+		out := make([]byte, len(plaintext))
+		subtle.XORBytes(out, ciphertext, plaintext)
+		return out
+	}
+	if bytes.Equal(keystream(a, first), keystream(b, second)) {
+		t.Fatal("aynı nonce ile farklı mesajlar aynı anahtar akışını kullandı")
+	}
+}
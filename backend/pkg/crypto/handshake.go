@@ -0,0 +1,351 @@
+package crypto
+
+import (
+	"container/list"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// HandshakeInfoPrefix HKDF info alanının sabit ön ekidir (istemciyle birebir aynı olmalı)
+const HandshakeInfoPrefix = "uctanuca/handshake/v1"
+
+// ErrSessionKeyNotFound oturum için el sıkışma yapılmamış veya anahtarın süresi dolmuş
+var ErrSessionKeyNotFound = errors.New("oturum anahtarı bulunamadı")
+
+// SessionKey el sıkışma sonucunda sunucuda saklanan oturum anahtarıdır.
+// El sıkışma anahtarları epoch'lara bölünür (bkz. RotationPolicy); CreatedAt
+// ve ExpiresAt tüm epoch'lar için el sıkışmanın zamanlarıdır.
+type SessionKey struct {
+	ID    string
+	Key   []byte
+	Epoch uint32
+	// Algorithm el sıkışmada seçilen AEAD'dir (AlgAES256GCM vb.). Sıfırsa
+	// anahtar (örn. token'dan türetilen) yalnızca AES-256-GCM ile kullanılır.
+	Algorithm byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+
+	// tokenHash anahtarın bağlı olduğu JWT'nin SHA-256 özetidir
+	tokenHash [32]byte
+
+	// usage rotasyon sayaçları, ring oturumun epoch halkasıdır (rotasyonsuz anahtarlarda nil)
+	usage *keyUsage
+	ring  *keyRing
+}
+
+// DefaultMaxKeysPerSubject bir kullanıcının (JWT subject) aynı anda
+// saklanabilen el sıkışma anahtarı sayısıdır. Sınırı aşan el sıkışma o
+// kullanıcının en uzun süre kullanılmayan anahtarını atar; başka
+// kullanıcıların anahtarları etkilenmez.
+const DefaultMaxKeysPerSubject = 32
+
+// ErrSessionKeyOwner oturumun canlı anahtarı başka bir token'a bağlıyken
+// yapılan el sıkışmayı bildirir
+var ErrSessionKeyOwner = errors.New("oturum anahtarı başka bir token'a bağlı")
+
+// SessionKeyStore session ID -> oturum anahtarı eşlemesini tutar. Kapasite
+// dolduğunda en uzun süre kullanılmayan (LRU) anahtar atılır; Get, Put ve
+// tahliye O(1)'dir. Her kullanıcı en fazla maxPerSubject anahtar tutabilir.
+type SessionKeyStore struct {
+	sync.Mutex
+	keys          map[string]*list.Element
+	order         *list.List // ön: en son kullanılan
+	bySubject     map[string]*list.List
+	maxSize       int
+	maxPerSubject int
+}
+
+// storedKey depodaki kayıttır; subjectElem kullanıcının kendi LRU listesindeki yeridir
+type storedKey struct {
+	sessionID   string
+	subject     string
+	key         *SessionKey
+	subjectElem *list.Element
+}
+
+var (
+	sessionKeysMu     sync.RWMutex
+	globalSessionKeys = NewSessionKeyStore(100000, 0)
+)
+
+// NewSessionKeyStore en fazla maxSize anahtar, kullanıcı başına en fazla
+// maxPerSubject anahtar tutan bir depo oluşturur. maxPerSubject 0 ise
+// DefaultMaxKeysPerSubject kullanılır.
+func NewSessionKeyStore(maxSize, maxPerSubject int) *SessionKeyStore {
+	if maxPerSubject <= 0 {
+		maxPerSubject = DefaultMaxKeysPerSubject
+	}
+	return &SessionKeyStore{
+		keys:          make(map[string]*list.Element),
+		order:         list.New(),
+		bySubject:     make(map[string]*list.List),
+		maxSize:       maxSize,
+		maxPerSubject: maxPerSubject,
+	}
+}
+
+// Put subject kullanıcısının oturum anahtarını kaydeder ve aynı oturum için
+// önceki anahtarın yerini alır. Oturumun süresi dolmamış anahtarı farklı bir
+// token'a bağlıysa ErrSessionKeyOwner döner; aynı kullanıcının yenilenmiş
+// token'ı (aynı, boş olmayan subject) anahtarı değiştirebilir.
+func (s *SessionKeyStore) Put(sessionID, subject string, key *SessionKey) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if elem, exists := s.keys[sessionID]; exists {
+		old := elem.Value.(*storedKey)
+		sameToken := subtle.ConstantTimeCompare(old.key.tokenHash[:], key.tokenHash[:]) == 1
+		sameSubject := subject != "" && old.subject == subject
+		if !sameToken && !sameSubject && Now().Before(old.key.ExpiresAt) {
+			return ErrSessionKeyOwner
+		}
+		s.removeLocked(elem)
+	}
+
+	// Önce kullanıcının kendi sınırı, sonra genel kapasite uygulanır
+	if subjectKeys := s.bySubject[subject]; subjectKeys != nil && subjectKeys.Len() >= s.maxPerSubject {
+		s.removeLocked(subjectKeys.Back().Value.(*list.Element))
+	}
+	if s.order.Len() >= s.maxSize {
+		s.removeLocked(s.order.Back())
+	}
+
+	entry := &storedKey{sessionID: sessionID, subject: subject, key: key}
+	elem := s.order.PushFront(entry)
+	s.keys[sessionID] = elem
+
+	subjectKeys := s.bySubject[subject]
+	if subjectKeys == nil {
+		subjectKeys = list.New()
+		s.bySubject[subject] = subjectKeys
+	}
+	entry.subjectElem = subjectKeys.PushFront(elem)
+	return nil
+}
+
+// Get oturumun geçerli epoch anahtarını döndürür. Anahtar farklı bir token'a
+// bağlıysa veya süresi dolmuşsa ErrSessionKeyNotFound döner.
+func (s *SessionKeyStore) Get(token, sessionID string) (*SessionKey, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	elem, exists := s.keys[sessionID]
+	if !exists {
+		return nil, ErrSessionKeyNotFound
+	}
+	entry := elem.Value.(*storedKey)
+
+	if !Now().Before(entry.key.ExpiresAt) {
+		s.removeLocked(elem)
+		return nil, ErrSessionKeyNotFound
+	}
+
+	tokenHash := sha256.Sum256([]byte(token))
+	if subtle.ConstantTimeCompare(tokenHash[:], entry.key.tokenHash[:]) != 1 {
+		return nil, ErrSessionKeyNotFound
+	}
+
+	s.order.MoveToFront(elem)
+	s.bySubject[entry.subject].MoveToFront(entry.subjectElem)
+	return entry.key.Current(), nil
+}
+
+// Delete oturum anahtarını siler
+func (s *SessionKeyStore) Delete(sessionID string) {
+	s.Lock()
+	defer s.Unlock()
+	if elem, exists := s.keys[sessionID]; exists {
+		s.removeLocked(elem)
+	}
+}
+
+// Len depodaki anahtar sayısını döndürür
+func (s *SessionKeyStore) Len() int {
+	s.Lock()
+	defer s.Unlock()
+	return s.order.Len()
+}
+
+// removeLocked kaydı genel ve kullanıcı listelerinden siler. s tutulurken çağrılır.
+func (s *SessionKeyStore) removeLocked(elem *list.Element) {
+	entry := s.order.Remove(elem).(*storedKey)
+	delete(s.keys, entry.sessionID)
+
+	subjectKeys := s.bySubject[entry.subject]
+	subjectKeys.Remove(entry.subjectElem)
+	if subjectKeys.Len() == 0 {
+		delete(s.bySubject, entry.subject)
+	}
+}
+
+// SetSessionKeyStore el sıkışma anahtarlarının deposunu değiştirir
+func SetSessionKeyStore(store *SessionKeyStore) {
+	sessionKeysMu.Lock()
+	defer sessionKeysMu.Unlock()
+	globalSessionKeys = store
+}
+
+func currentSessionKeys() *SessionKeyStore {
+	sessionKeysMu.RLock()
+	defer sessionKeysMu.RUnlock()
+	return globalSessionKeys
+}
+
+// HandshakeResult sunucunun istemciye döndürdüğü el sıkışma bilgileridir.
+// Zarflarda taşınan anahtar kimliği EpochKeyID(KeyID, epoch) biçimindedir.
+type HandshakeResult struct {
+	ServerPublicKey string `json:"server_public_key"`
+	KeyID           string `json:"key_id"`
+	Epoch           uint32 `json:"epoch"`
+	ExpiresIn       int    `json:"expires_in"`
+	// Algorithm seçilen AEAD'in adıdır (örn. "xchacha20-poly1305")
+	Algorithm string `json:"algorithm"`
+}
+
+// PerformHandshake istemcinin X25519 public key'i ile geçici bir sunucu anahtarı
+// üzerinden ortak sır üretir, HKDF ile oturum sırrını türetir ve saklar.
+// Sır session ID'ye ve token'a bağlıdır; token tek başına anahtarı vermez.
+// Mesajlar sırdan türetilen epoch anahtarlarıyla şifrelenir ve rotation
+// politikasına göre yenilenir. lifetime sıfırsa DefaultKeyLifetime kullanılır.
+// algorithm NegotiateAlgorithm ile seçilen AEAD'dir; sıfırsa AES-256-GCM.
+// subject token'ın kullanıcısıdır (JWT sub); anahtar deposundaki kullanıcı
+// başına sınır için kullanılır, boşsa token'ın kendisi kullanıcı sayılır.
+func PerformHandshake(clientPublicKeyB64, token, sessionId, subject string, lifetime time.Duration, rotation RotationPolicy, algorithm byte) (*HandshakeResult, error) {
+	if token == "" || sessionId == "" {
+		return nil, errors.New("el sıkışma için token ve session ID gerekli")
+	}
+	if lifetime <= 0 {
+		lifetime = DefaultKeyLifetime
+	}
+	if algorithm == 0 {
+		algorithm = AlgAES256GCM
+	}
+	alg, err := LookupAlgorithm(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	clientPubBytes, err := base64.StdEncoding.DecodeString(clientPublicKeyB64)
+	if err != nil {
+		return nil, errors.New("istemci public key base64 decode başarısız")
+	}
+
+	curve := ecdh.X25519()
+	clientPub, err := curve.NewPublicKey(clientPubBytes)
+	if err != nil {
+		return nil, errors.New("geçersiz istemci public key")
+	}
+
+	serverPriv, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("geçici anahtar üretme hatası: %w", err)
+	}
+	serverPubBytes := serverPriv.PublicKey().Bytes()
+
+	// Düşük dereceli noktalar tamamen sıfır ortak sır üretir ve reddedilir
+	sharedSecret, err := serverPriv.ECDH(clientPub)
+	if err != nil {
+		return nil, errors.New("ECDH ortak sır hesaplama başarısız")
+	}
+
+	secret, err := deriveHandshakeKey(sharedSecret, token, sessionId, clientPubBytes, serverPubBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := make([]byte, 8)
+	if _, err := io.ReadFull(rand.Reader, keyID); err != nil {
+		return nil, fmt.Errorf("anahtar kimliği üretme hatası: %w", err)
+	}
+
+	now := Now()
+	tokenHash := sha256.Sum256([]byte(token))
+	ring, err := newKeyRing(secret, hex.EncodeToString(keyID), sessionId, rotation, alg.ID, now, now.Add(lifetime), tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := subject
+	if owner == "" {
+		owner = "token:" + hex.EncodeToString(tokenHash[:])
+	}
+	if err := currentSessionKeys().Put(sessionId, owner, ring.current); err != nil {
+		return nil, err
+	}
+
+	return &HandshakeResult{
+		ServerPublicKey: base64.StdEncoding.EncodeToString(serverPubBytes),
+		KeyID:           ring.baseID,
+		Epoch:           ring.current.Epoch,
+		ExpiresIn:       int(lifetime / time.Second),
+		Algorithm:       alg.Name,
+	}, nil
+}
+
+// CompleteHandshake el sıkışmayı istemci tarafında tamamlar: istemcinin geçici
+// özel anahtarı ve sunucunun public key'inden (HandshakeResult.ServerPublicKey)
+// sunucuyla aynı oturum sırrını türetir. Epoch anahtarları DeriveEpochKey ile
+// bu sırdan türetilir.
+func CompleteHandshake(clientPriv *ecdh.PrivateKey, serverPublicKeyB64, token, sessionId string) ([]byte, error) {
+	if token == "" || sessionId == "" {
+		return nil, errors.New("el sıkışma için token ve session ID gerekli")
+	}
+
+	serverPubBytes, err := base64.StdEncoding.DecodeString(serverPublicKeyB64)
+	if err != nil {
+		return nil, errors.New("sunucu public key base64 decode başarısız")
+	}
+
+	serverPub, err := ecdh.X25519().NewPublicKey(serverPubBytes)
+	if err != nil {
+		return nil, errors.New("geçersiz sunucu public key")
+	}
+
+	sharedSecret, err := clientPriv.ECDH(serverPub)
+	if err != nil {
+		return nil, errors.New("ECDH ortak sır hesaplama başarısız")
+	}
+
+	return deriveHandshakeKey(sharedSecret, token, sessionId, clientPriv.PublicKey().Bytes(), serverPubBytes)
+}
+
+// deriveHandshakeKey ortak sırdan epoch anahtarlarının kökü olan oturum sırrını türetir.
+//
+//	salt = SHA-256(token)
+//	info = "uctanuca/handshake/v1|" + sessionId + "|" + clientPub + serverPub
+func deriveHandshakeKey(sharedSecret []byte, token, sessionId string, clientPub, serverPub []byte) ([]byte, error) {
+	salt := sha256.Sum256([]byte(token))
+
+	info := make([]byte, 0, len(HandshakeInfoPrefix)+len(sessionId)+2+len(clientPub)+len(serverPub))
+	info = append(info, HandshakeInfoPrefix+"|"+sessionId+"|"...)
+	info = append(info, clientPub...)
+	info = append(info, serverPub...)
+
+	hkdfReader := hkdf.New(sha256.New, sharedSecret, salt[:], info)
+	derivedKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdfReader, derivedKey); err != nil {
+		return nil, fmt.Errorf("HKDF anahtar türetme hatası: %v", err)
+	}
+	return derivedKey, nil
+}
+
+// LookupSessionKey el sıkışma ile kurulmuş oturum anahtarını döndürür
+func LookupSessionKey(token, sessionId string) (*SessionKey, error) {
+	return currentSessionKeys().Get(token, sessionId)
+}
+
+// DeleteSessionKey oturumun el sıkışma anahtarını siler
+func DeleteSessionKey(sessionId string) {
+	currentSessionKeys().Delete(sessionId)
+}
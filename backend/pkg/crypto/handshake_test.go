@@ -0,0 +1,74 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+	"time"
+)
+
+// storeKey token'a bağlı, bir saat geçerli bir test anahtarı oluşturur
+func storeKey(token string) *SessionKey {
+	return &SessionKey{ID: token, Key: make([]byte, 32), ExpiresAt: Now().Add(time.Hour), tokenHash: sha256.Sum256([]byte(token))}
+}
+
+func TestSessionKeyStoreLimits(t *testing.T) {
+	useFakeClock(t)
+	store := NewSessionKeyStore(4, 2)
+
+	put := func(sessionID, subject string) {
+		t.Helper()
+		if err := store.Put(sessionID, subject, storeKey(subject)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	has := func(sessionID, subject string) bool {
+		_, err := store.Get(subject, sessionID)
+		return err == nil
+	}
+
+	// Kullanıcı sınırı yalnızca o kullanıcının en eski anahtarını atar
+	put("a1", "alice")
+	put("m1", "mallory")
+	put("m2", "mallory")
+	put("m3", "mallory")
+	if !has("a1", "alice") || has("m1", "mallory") || !has("m3", "mallory") {
+		t.Fatal("kullanıcı sınırı başka kullanıcının anahtarını etkiledi")
+	}
+
+	// Genel kapasitede en uzun süre kullanılmayan anahtar atılır; Get kullanımı tazeler
+	put("b1", "bob")
+	has("m2", "mallory")
+	put("c1", "carol")
+	if has("a1", "alice") || !has("m2", "mallory") || store.Len() != 4 {
+		t.Fatalf("LRU tahliyesi hatalı (%d anahtar)", store.Len())
+	}
+}
+
+func TestSessionKeyStoreOwner(t *testing.T) {
+	clock := useFakeClock(t)
+	store := NewSessionKeyStore(10, 0)
+
+	if err := store.Put("s", "alice", storeKey("alice-token")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Başka kullanıcı aynı X-Session-ID ile anahtarı değiştiremez
+	if err := store.Put("s", "mallory", storeKey("mallory-token")); !errors.Is(err, ErrSessionKeyOwner) {
+		t.Fatalf("hata %v, beklenen %v", err, ErrSessionKeyOwner)
+	}
+	if _, err := store.Get("alice-token", "s"); err != nil {
+		t.Fatal("sahibin anahtarı silindi")
+	}
+
+	// Aynı kullanıcının yenilenmiş token'ı anahtarı değiştirebilir
+	if err := store.Put("s", "alice", storeKey("alice-token-2")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Süresi dolan anahtarın yerine herkes el sıkışma yapabilir
+	clock.Advance(time.Hour)
+	if err := store.Put("s", "mallory", storeKey("mallory-token")); err != nil {
+		t.Fatalf("süresi dolan anahtar değiştirilemedi: %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package crypto
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// KeyCache token+session ID'den türetilen anahtarları tutar. Paylaşımlı veya
+// farklı politikalı bir depo kullanmak için bu arayüz uygulanıp SetKeyCache
+// ile verilebilir.
+type KeyCache interface {
+	// Get süresi dolmamış anahtarın kopyasını döndürür
+	Get(token, sessionID string) ([]byte, bool)
+	// Put anahtarı kaydeder; aynı token+session için öncekinin yerini alır
+	Put(token, sessionID string, key []byte)
+	// PurgeSession oturuma ait tüm anahtarları siler (örn. çıkışta)
+	PurgeSession(sessionID string)
+	// Stats önbellek istatistiklerini döndürür
+	Stats() CacheStats
+}
+
+// CacheStats önbellek isabet/ıskalama sayaçlarıdır
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Expired   uint64
+	Size      int
+}
+
+// LRUKeyCache sınırlı kapasiteli, en uzun süre kullanılmayanı atan (LRU)
+// KeyCache uygulamasıdır. Get, Put ve tahliye O(1)'dir; süresi dolan kayıtlar
+// okunurken veya StartSweeper ile arka planda temizlenir.
+//
+// Bellek dökümünde token sızmaması için kayıtlar token yerine süreç başına
+// rastgele anahtarla hesaplanan HMAC ile tutulur ve silinen anahtarlar sıfırlanır.
+type LRUKeyCache struct {
+	mu        sync.Mutex
+	entries   map[cacheID]*list.Element
+	order     *list.List // ön: en son kullanılan
+	bySession map[string]map[cacheID]struct{}
+	maxSize   int
+	lifetime  time.Duration
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+	expired   atomic.Uint64
+}
+
+// cacheID token ve session ID'nin anahtarlı özetidir
+type cacheID [sha256.Size]byte
+
+type keyCacheEntry struct {
+	cacheKey  cacheID
+	sessionID string
+	key       []byte
+	expiresAt time.Time
+}
+
+// cacheKeySecret önbellek kimliklerini hesaplayan, süreç başına rastgele HMAC anahtarıdır
+var cacheKeySecret = func() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic("anahtar önbelleği için rastgele sır üretilemedi: " + err.Error())
+	}
+	return secret
+}()
+
+var (
+	keyCacheMu     sync.RWMutex
+	globalKeyCache KeyCache = NewLRUKeyCache(1000, DefaultKeyLifetime)
+)
+
+// NewLRUKeyCache en fazla maxSize anahtar tutan ve her anahtarı lifetime
+// boyunca geçerli sayan bir önbellek oluşturur. lifetime sıfırsa
+// DefaultKeyLifetime kullanılır.
+func NewLRUKeyCache(maxSize int, lifetime time.Duration) *LRUKeyCache {
+	if lifetime <= 0 {
+		lifetime = DefaultKeyLifetime
+	}
+	return &LRUKeyCache{
+		entries:   make(map[cacheID]*list.Element),
+		order:     list.New(),
+		bySession: make(map[string]map[cacheID]struct{}),
+		maxSize:   maxSize,
+		lifetime:  lifetime,
+	}
+}
+
+// keyCacheKey HMAC-SHA256(sır, len(token) | token | sessionID) hesaplar
+func keyCacheKey(token, sessionID string) cacheID {
+	mac := hmac.New(sha256.New, cacheKeySecret)
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(token)))
+	mac.Write(length[:])
+	mac.Write([]byte(token))
+	mac.Write([]byte(sessionID))
+
+	var id cacheID
+	mac.Sum(id[:0])
+	return id
+}
+
+// cloneKey anahtarın kopyasını döndürür; önbellekteki kopya silinirken
+// sıfırlanacağından çağıranlar kendi kopyalarıyla çalışır
+func cloneKey(key []byte) []byte {
+	return append([]byte(nil), key...)
+}
+
+// ZeroKey anahtar baytlarını sıfırlar. DeriveKeys'ten dönen anahtarlar iş
+// bitince bununla sıfırlanmalıdır.
+func ZeroKey(key []byte) {
+	for i := range key {
+		key[i] = 0
+	}
+}
+
+// Get KeyCache arayüzünü uygular
+func (c *LRUKeyCache) Get(token, sessionID string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[keyCacheKey(token, sessionID)]
+	if !ok {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	entry := elem.Value.(*keyCacheEntry)
+	if !Now().Before(entry.expiresAt) {
+		c.removeLocked(elem)
+		c.expired.Add(1)
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.hits.Add(1)
+	return cloneKey(entry.key), true
+}
+
+// Put KeyCache arayüzünü uygular
+func (c *LRUKeyCache) Put(token, sessionID string, key []byte) {
+	cacheKey := keyCacheKey(token, sessionID)
+	expiresAt := Now().Add(c.lifetime)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[cacheKey]; ok {
+		entry := elem.Value.(*keyCacheEntry)
+		ZeroKey(entry.key)
+		entry.key = cloneKey(key)
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	// Kapasite doluysa en uzun süre kullanılmayan kaydı at
+	for c.maxSize > 0 && c.order.Len() >= c.maxSize {
+		c.removeLocked(c.order.Back())
+		c.evictions.Add(1)
+	}
+
+	c.entries[cacheKey] = c.order.PushFront(&keyCacheEntry{
+		cacheKey:  cacheKey,
+		sessionID: sessionID,
+		key:       cloneKey(key),
+		expiresAt: expiresAt,
+	})
+
+	keys, ok := c.bySession[sessionID]
+	if !ok {
+		keys = make(map[cacheID]struct{})
+		c.bySession[sessionID] = keys
+	}
+	keys[cacheKey] = struct{}{}
+}
+
+// PurgeSession KeyCache arayüzünü uygular
+func (c *LRUKeyCache) PurgeSession(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for cacheKey := range c.bySession[sessionID] {
+		if elem, ok := c.entries[cacheKey]; ok {
+			c.removeLocked(elem)
+		}
+	}
+}
+
+// Stats KeyCache arayüzünü uygular
+func (c *LRUKeyCache) Stats() CacheStats {
+	c.mu.Lock()
+	size := c.order.Len()
+	c.mu.Unlock()
+
+	return CacheStats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Expired:   c.expired.Load(),
+		Size:      size,
+	}
+}
+
+// Sweep süresi dolan tüm kayıtları siler ve silinen kayıt sayısını döndürür
+func (c *LRUKeyCache) Sweep() int {
+	now := Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for elem := c.order.Back(); elem != nil; {
+		prev := elem.Prev()
+		if !now.Before(elem.Value.(*keyCacheEntry).expiresAt) {
+			c.removeLocked(elem)
+			removed++
+		}
+		elem = prev
+	}
+
+	c.expired.Add(uint64(removed))
+	return removed
+}
+
+// StartSweeper süresi dolan kayıtları interval aralıklarla arka planda siler.
+// Dönen fonksiyon temizleyiciyi durdurur.
+func (c *LRUKeyCache) StartSweeper(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.Sweep()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// removeLocked kaydı liste, harita ve oturum indeksinden siler ve anahtarı sıfırlar
+func (c *LRUKeyCache) removeLocked(elem *list.Element) {
+	entry := c.order.Remove(elem).(*keyCacheEntry)
+	delete(c.entries, entry.cacheKey)
+	ZeroKey(entry.key)
+	entry.key = nil
+
+	if keys, ok := c.bySession[entry.sessionID]; ok {
+		delete(keys, entry.cacheKey)
+		if len(keys) == 0 {
+			delete(c.bySession, entry.sessionID)
+		}
+	}
+}
+
+// SetKeyCache DeriveKeys'in kullandığı anahtar önbelleğini değiştirir
+func SetKeyCache(kc KeyCache) {
+	keyCacheMu.Lock()
+	defer keyCacheMu.Unlock()
+	globalKeyCache = kc
+}
+
+func currentKeyCache() KeyCache {
+	keyCacheMu.RLock()
+	defer keyCacheMu.RUnlock()
+	return globalKeyCache
+}
+
+// KeyCacheStats kullanılan anahtar önbelleğinin istatistiklerini döndürür
+func KeyCacheStats() CacheStats {
+	return currentKeyCache().Stats()
+}
+
+// PurgeSession oturumun tüm anahtarlarını (türetilmiş ve el sıkışma) siler.
+// Kullanıcı çıkış yaptığında çağrılmalıdır.
+func PurgeSession(sessionID string) {
+	currentKeyCache().PurgeSession(sessionID)
+	DeleteSessionKey(sessionID)
+}
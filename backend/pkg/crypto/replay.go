@@ -0,0 +1,167 @@
+package crypto
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Varsayılan replay koruması zaman penceresi ve saat kayması toleransı
+const (
+	ReplayWindow = 5 * time.Minute
+	MaxClockSkew = 5 * time.Second
+)
+
+// maxNonceLength bellek tüketimini sınırlamak için kabul edilen en uzun nonce
+const maxNonceLength = 128
+
+// ReplayCache görülmüş (session, nonce) çiftlerini tutar. Paylaşımlı bir depo
+// (örn. Redis) kullanmak için bu arayüz uygulanıp SetReplayCache ile verilebilir.
+type ReplayCache interface {
+	// Remember nonce bu oturumda daha önce görülmediyse expiresAt'e kadar
+	// kaydeder ve true döner. Tekrar eden nonce için false döner.
+	Remember(sessionID, nonce string, expiresAt time.Time) bool
+}
+
+// DefaultReplaySessionLimit tek bir oturumun MemoryReplayCache'te aynı anda
+// tutabileceği varsayılan nonce sayısıdır. Varsayılan pencerede (5 dk) oturum
+// başına saniyede ~3 istek demektir; sınırı aşan oturumun istekleri pencere
+// kayana kadar reddedilir, diğer oturumlar etkilenmez.
+const DefaultReplaySessionLimit = 1000
+
+// MemoryReplayCache sınırlı kapasiteli, bellek içi ReplayCache uygulamasıdır.
+// Kayıtlar süre sonuna göre bir min-heap'te tutulur: Codec'ler farklı replay
+// pencereleri kullanabildiğinden eklenme sırası süre sonu sırası değildir.
+// Temizlik süresi dolan kayıt başına O(log n)'dir.
+type MemoryReplayCache struct {
+	sync.Mutex
+	entries       map[string]*replayEntry
+	expiry        replayHeap
+	perSession    map[string]int
+	maxEntries    int
+	maxPerSession int
+}
+
+type replayEntry struct {
+	key       string
+	sessionID string
+	expiresAt time.Time
+}
+
+// replayHeap süre sonu en yakın kaydı başta tutan container/heap uygulamasıdır
+type replayHeap []*replayEntry
+
+func (h replayHeap) Len() int           { return len(h) }
+func (h replayHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h replayHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *replayHeap) Push(x any)        { *h = append(*h, x.(*replayEntry)) }
+func (h *replayHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return entry
+}
+
+var (
+	replayCacheMu     sync.RWMutex
+	globalReplayCache ReplayCache = NewMemoryReplayCache(100000, 0)
+)
+
+// NewMemoryReplayCache en fazla maxEntries kayıt, oturum başına en fazla
+// maxPerSession kayıt tutan bir önbellek oluşturur. maxPerSession 0 ise
+// DefaultReplaySessionLimit kullanılır.
+func NewMemoryReplayCache(maxEntries, maxPerSession int) *MemoryReplayCache {
+	if maxPerSession <= 0 {
+		maxPerSession = DefaultReplaySessionLimit
+	}
+	return &MemoryReplayCache{
+		entries:       make(map[string]*replayEntry),
+		perSession:    make(map[string]int),
+		maxEntries:    maxEntries,
+		maxPerSession: maxPerSession,
+	}
+}
+
+// Remember ReplayCache arayüzünü uygular
+func (m *MemoryReplayCache) Remember(sessionID, nonce string, expiresAt time.Time) bool {
+	key := sessionID + "\x00" + nonce
+	now := Now()
+
+	m.Lock()
+	defer m.Unlock()
+
+	m.sweepLocked(now)
+
+	if _, exists := m.entries[key]; exists {
+		return false
+	}
+
+	// Kapasite doluysa ve süresi dolan kayıt yoksa isteği reddet.
+	// Eski kayıtları atmak, pencere içindeki nonce'ların tekrar kabul
+	// edilmesine yol açacağından güvenli tarafta kalınır. Oturum sınırı,
+	// tek bir istemcinin ortak kapasiteyi doldurup diğer oturumları
+	// kilitlemesini önler.
+	if m.perSession[sessionID] >= m.maxPerSession || len(m.expiry) >= m.maxEntries {
+		return false
+	}
+
+	entry := &replayEntry{key: key, sessionID: sessionID, expiresAt: expiresAt}
+	m.entries[key] = entry
+	m.perSession[sessionID]++
+	heap.Push(&m.expiry, entry)
+	return true
+}
+
+// Len önbellekteki kayıt sayısını döndürür
+func (m *MemoryReplayCache) Len() int {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.expiry)
+}
+
+// sweepLocked süresi dolan kayıtları süre sonu sırasıyla siler
+func (m *MemoryReplayCache) sweepLocked(now time.Time) {
+	for len(m.expiry) > 0 && !m.expiry[0].expiresAt.After(now) {
+		entry := heap.Pop(&m.expiry).(*replayEntry)
+		delete(m.entries, entry.key)
+		if m.perSession[entry.sessionID]--; m.perSession[entry.sessionID] <= 0 {
+			delete(m.perSession, entry.sessionID)
+		}
+	}
+}
+
+// SetReplayCache DecryptData'nın kullandığı replay önbelleğini değiştirir
+func SetReplayCache(rc ReplayCache) {
+	replayCacheMu.Lock()
+	defer replayCacheMu.Unlock()
+	globalReplayCache = rc
+}
+
+func currentReplayCache() ReplayCache {
+	replayCacheMu.RLock()
+	defer replayCacheMu.RUnlock()
+	return globalReplayCache
+}
+
+// validateNonce aynı oturumda aynı nonce'un ikinci kez kullanılmasını engeller.
+// Timestamp penceresi dışına çıkan istekler zaten reddedildiği için nonce'ları
+// pencere + saat kayması kadar (expiresAt) tutmak yeterlidir.
+func validateNonce(data map[string]interface{}, sessionId string, cache ReplayCache, expiresAt time.Time) error {
+	nonceVal, exists := data["_nonce"]
+	if !exists {
+		return fmt.Errorf("%w: güvenlik nonce'u eksik", ErrMalformedJSON)
+	}
+
+	nonce, ok := nonceVal.(string)
+	if !ok || nonce == "" || len(nonce) > maxNonceLength {
+		return fmt.Errorf("%w: geçersiz nonce formatı", ErrMalformedJSON)
+	}
+
+	if !cache.Remember(sessionId, nonce, expiresAt) {
+		return ErrReplay
+	}
+
+	return nil
+}
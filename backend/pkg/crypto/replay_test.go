@@ -0,0 +1,55 @@
+package crypto
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestMemoryReplayCacheSessionLimit sınırı aşan oturumun yalnızca kendi
+// isteklerinin reddedildiğini doğrular
+func TestMemoryReplayCacheSessionLimit(t *testing.T) {
+	clock := useFakeClock(t)
+	cache := NewMemoryReplayCache(10, 3)
+	expiresAt := clock.Now().Add(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if !cache.Remember("mallory", fmt.Sprint(i), expiresAt) {
+			t.Fatalf("nonce %d reddedildi", i)
+		}
+	}
+	if cache.Remember("mallory", "3", expiresAt) {
+		t.Fatal("oturum sınırı aşıldı")
+	}
+	if !cache.Remember("alice", "0", expiresAt) {
+		t.Fatal("sınırı aşan oturum başka bir oturumu kilitledi")
+	}
+
+	// Pencere kayınca oturum yeniden nonce kaydedebilir
+	clock.Advance(time.Minute)
+	if !cache.Remember("mallory", "3", clock.Now().Add(time.Minute)) || cache.Len() != 1 {
+		t.Fatalf("süresi dolan kayıtlar silinmedi (%d kayıt)", cache.Len())
+	}
+}
+
+// TestMemoryReplayCacheMixedWindows farklı pencerelerle eklenen kayıtların
+// eklenme sırasından bağımsız olarak süre sonunda silindiğini doğrular
+func TestMemoryReplayCacheMixedWindows(t *testing.T) {
+	clock := useFakeClock(t)
+	cache := NewMemoryReplayCache(2, 0)
+
+	if !cache.Remember("a", "uzun", clock.Now().Add(time.Hour)) || !cache.Remember("b", "kısa", clock.Now().Add(time.Minute)) {
+		t.Fatal("nonce reddedildi")
+	}
+	if cache.Remember("c", "dolu", clock.Now().Add(time.Minute)) {
+		t.Fatal("kapasite aşıldı")
+	}
+
+	clock.Advance(time.Minute)
+	if !cache.Remember("c", "yeni", clock.Now().Add(time.Minute)) {
+		t.Fatal("süresi dolan kısa pencereli kayıt silinmedi")
+	}
+	if cache.Remember("a", "uzun", clock.Now().Add(time.Hour)) {
+		t.Fatal("süresi dolmayan nonce tekrar kabul edildi")
+	}
+}
@@ -0,0 +1,277 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// EpochInfoPrefix epoch anahtarı türetmede HKDF info alanının sabit ön ekidir
+// (istemciyle birebir aynı olmalı)
+const EpochInfoPrefix = "uctanuca/epoch/v1"
+
+// RotationPolicy el sıkışma ile kurulan oturum anahtarlarının ne zaman
+// yenileneceğini belirler. Sıfır değerli alanlar o tetikleyiciyi kapatır;
+// sıfır değerli politika rotasyonu tamamen kapatır.
+type RotationPolicy struct {
+	// Interval bir epoch'un en uzun kullanım süresi
+	Interval time.Duration
+	// GracePeriod sonraki epoch duyurulduktan veya benimsendikten sonra eski
+	// epoch'un kabul edildiği süre
+	GracePeriod time.Duration
+	// MaxMessages bir epoch anahtarıyla yapılabilecek en fazla şifreleme/çözme sayısı
+	MaxMessages uint64
+	// MaxBytes bir epoch anahtarıyla işlenebilecek en fazla düz metin boyutu
+	MaxBytes uint64
+}
+
+// DefaultRotationPolicy middleware'ın varsayılan rotasyon politikasıdır
+var DefaultRotationPolicy = RotationPolicy{
+	Interval:    15 * time.Minute,
+	GracePeriod: 30 * time.Second,
+	MaxMessages: 1 << 24,
+	MaxBytes:    1 << 36,
+}
+
+// enabled politikada en az bir tetikleyici olup olmadığını döndürür
+func (p RotationPolicy) enabled() bool {
+	return p.Interval > 0 || p.MaxMessages > 0 || p.MaxBytes > 0
+}
+
+// due epoch'un yenilenme zamanının gelip gelmediğini döndürür
+func (p RotationPolicy) due(started time.Time, usage *keyUsage, now time.Time) bool {
+	if p.Interval > 0 && now.Sub(started) >= p.Interval {
+		return true
+	}
+	if p.MaxMessages > 0 && usage.messages.Load() >= p.MaxMessages {
+		return true
+	}
+	return p.MaxBytes > 0 && usage.bytes.Load() >= p.MaxBytes
+}
+
+// keyUsage bir epoch anahtarıyla yapılan işlemleri sayar
+type keyUsage struct {
+	messages atomic.Uint64
+	bytes    atomic.Uint64
+}
+
+// EpochKeyID epoch anahtarının zarflarda taşınan kimliğidir: baseID "." epoch
+func EpochKeyID(baseID string, epoch uint32) string {
+	return baseID + "." + strconv.FormatUint(uint64(epoch), 10)
+}
+
+// DeriveEpochKey el sıkışma sırrından epoch anahtarını türetir.
+//
+//	info = "uctanuca/epoch/v1|" + sessionId + "|" + epoch (ondalık)
+func DeriveEpochKey(secret []byte, sessionID string, epoch uint32) ([]byte, error) {
+	info := EpochInfoPrefix + "|" + sessionID + "|" + strconv.FormatUint(uint64(epoch), 10)
+
+	hkdfReader := hkdf.New(sha256.New, secret, nil, []byte(info))
+	derivedKey := make([]byte, 32)
+	if _, err := io.ReadFull(hkdfReader, derivedKey); err != nil {
+		return nil, fmt.Errorf("HKDF anahtar türetme hatası: %v", err)
+	}
+	return derivedKey, nil
+}
+
+// keyRing bir oturumun epoch anahtarlarını tutar. Rotasyon zamanı gelince
+// sonraki epoch duyurulur; istemci onu ilk kullandığında geçerli epoch olur ve
+// önceki epoch GracePeriod boyunca kabul edilmeye devam eder. İstemci duyurudan
+// sonra GracePeriod içinde geçmezse rotasyon zorla yapılır.
+type keyRing struct {
+	mu sync.Mutex
+
+	secret    []byte
+	baseID    string
+	sessionID string
+	policy    RotationPolicy
+	algorithm byte
+
+	createdAt time.Time
+	expiresAt time.Time
+	tokenHash [32]byte
+
+	current  *SessionKey
+	previous *SessionKey
+	next     *SessionKey
+
+	epochStarted  time.Time
+	previousUntil time.Time
+	announcedAt   time.Time
+}
+
+// newKeyRing el sıkışma sırrından epoch 0 anahtarıyla yeni bir halka oluşturur.
+// Halkanın tüm epoch anahtarları el sıkışmada seçilen algoritmayı kullanır.
+func newKeyRing(secret []byte, baseID, sessionID string, policy RotationPolicy, algorithm byte, createdAt, expiresAt time.Time, tokenHash [32]byte) (*keyRing, error) {
+	r := &keyRing{
+		secret:       secret,
+		baseID:       baseID,
+		sessionID:    sessionID,
+		policy:       policy,
+		algorithm:    algorithm,
+		createdAt:    createdAt,
+		expiresAt:    expiresAt,
+		tokenHash:    tokenHash,
+		epochStarted: createdAt,
+	}
+
+	current, err := r.deriveEpoch(0)
+	if err != nil {
+		return nil, err
+	}
+	r.current = current
+	return r, nil
+}
+
+// deriveEpoch halkaya bağlı epoch anahtarını oluşturur
+func (r *keyRing) deriveEpoch(epoch uint32) (*SessionKey, error) {
+	key, err := DeriveEpochKey(r.secret, r.sessionID, epoch)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionKey{
+		ID:        EpochKeyID(r.baseID, epoch),
+		Key:       key,
+		Epoch:     epoch,
+		Algorithm: r.algorithm,
+		CreatedAt: r.createdAt,
+		ExpiresAt: r.expiresAt,
+		tokenHash: r.tokenHash,
+		usage:     &keyUsage{},
+		ring:      r,
+	}, nil
+}
+
+// advanceLocked süresi dolan önceki epoch'u bırakır, zamanı gelen rotasyonu
+// duyurur ve yanıtsız kalan duyuruyu zorla uygular. r.mu tutulurken çağrılır.
+func (r *keyRing) advanceLocked(now time.Time) {
+	if r.previous != nil && !now.Before(r.previousUntil) {
+		r.previous = nil
+	}
+
+	if r.next == nil && r.policy.enabled() && r.current.Epoch < math.MaxUint32 &&
+		r.policy.due(r.epochStarted, r.current.usage, now) {
+		next, err := r.deriveEpoch(r.current.Epoch + 1)
+		if err != nil {
+			return
+		}
+		r.next = next
+		r.announcedAt = now
+	}
+
+	if r.next != nil && !now.Before(r.announcedAt.Add(r.policy.GracePeriod)) {
+		// İstemci duyuruya uymadı: eski epoch artık kabul edilmez
+		r.promoteLocked(now, false)
+	}
+}
+
+// promoteLocked duyurulan epoch'u geçerli epoch yapar
+func (r *keyRing) promoteLocked(now time.Time, keepPrevious bool) {
+	r.previous = nil
+	if keepPrevious {
+		r.previous = r.current
+		r.previousUntil = now.Add(r.policy.GracePeriod)
+	}
+	r.current = r.next
+	r.next = nil
+	r.epochStarted = now
+}
+
+// lookup zarftaki anahtar kimliğine karşılık gelen kabul edilebilir epoch anahtarını döndürür
+func (r *keyRing) lookup(keyID string) (*SessionKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.advanceLocked(Now())
+	for _, k := range []*SessionKey{r.current, r.next, r.previous} {
+		if k != nil && k.ID == keyID {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: anahtar kimliği eşleşmiyor veya epoch süresi dolmuş", ErrAuthFailed)
+}
+
+// accept doğrulanmış bir mesajın kullandığı anahtarı kaydeder. İstemci
+// duyurulan epoch'u kullandıysa o epoch geçerli epoch olur.
+func (r *keyRing) accept(k *SessionKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if k == r.next {
+		r.promoteLocked(Now(), true)
+	}
+}
+
+// currentKey rotasyon durumunu güncelleyip geçerli epoch anahtarını döndürür
+func (r *keyRing) currentKey() *SessionKey {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.advanceLocked(Now())
+	return r.current
+}
+
+// nextEpoch duyurulmuş ancak henüz benimsenmemiş epoch'u döndürür
+func (r *keyRing) nextEpoch() (uint32, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next == nil {
+		return 0, false
+	}
+	return r.next.Epoch, true
+}
+
+// Current anahtarın ait olduğu oturumun geçerli epoch anahtarını döndürür.
+// Rotasyonsuz anahtarlar (ör. token'dan türetilenler) kendilerini döndürür.
+func (sk *SessionKey) Current() *SessionKey {
+	if sk.ring == nil {
+		return sk
+	}
+	return sk.ring.currentKey()
+}
+
+// NextEpoch sunucunun duyurduğu sonraki epoch'u döndürür. İstemci bu epoch'un
+// anahtarını türetip sonraki isteklerde kullanmalıdır.
+func (sk *SessionKey) NextEpoch() (uint32, bool) {
+	if sk.ring == nil {
+		return 0, false
+	}
+	return sk.ring.nextEpoch()
+}
+
+// resolve zarftaki anahtar kimliğine göre çözmede kullanılacak anahtarı seçer
+func (sk *SessionKey) resolve(keyID string) (*SessionKey, error) {
+	if sk.ring != nil {
+		return sk.ring.lookup(keyID)
+	}
+	if keyID != sk.ID {
+		return nil, fmt.Errorf("%w: anahtar kimliği eşleşmiyor", ErrAuthFailed)
+	}
+	return sk, nil
+}
+
+// recordUse anahtarla işlenen bir mesajı rotasyon sayaçlarına ekler
+func (sk *SessionKey) recordUse(plaintextLen int) {
+	if sk.usage == nil {
+		return
+	}
+	sk.usage.messages.Add(1)
+	sk.usage.bytes.Add(uint64(plaintextLen))
+}
+
+// accepted anahtarla doğrulanan bir mesajdan sonra çağrılır; anahtar
+// duyurulan epoch'a aitse oturum o epoch'a geçer
+func (sk *SessionKey) accepted() {
+	if sk.ring != nil {
+		sk.ring.accept(sk)
+	}
+}
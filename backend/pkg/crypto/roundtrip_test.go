@@ -0,0 +1,168 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// Gidiş-dönüş özellik testleri: rastgele payload'lar için
+// DecryptData(EncryptData(x)) == x. Replay alanları (_timestamp, _nonce)
+// istemcinin yaptığı gibi şifrelemeden önce eklenir.
+
+// roundTripPayload quick.Check'in ürettiği JSON uyumlu payload'dur
+type roundTripPayload struct {
+	Strings map[string]string
+	Number  int32
+	Flag    bool
+	List    []string
+}
+
+// withReplayFields payload'u JSON nesnesine çevirir ve geçerli _timestamp ile
+// benzersiz _nonce ekler
+func (p roundTripPayload) withReplayFields(r *rand.Rand) map[string]interface{} {
+	nonce := make([]byte, 8)
+	r.Read(nonce)
+
+	payload := map[string]interface{}{
+		"_timestamp": time.Now().UnixMilli(),
+		"_nonce":     hex.EncodeToString(nonce),
+		"number":     p.Number,
+		"flag":       p.Flag,
+		"list":       p.List,
+	}
+	for k, v := range p.Strings {
+		payload["s_"+k] = v
+	}
+	return payload
+}
+
+// normalize değeri DecryptData'nın döndürdüğü biçime (JSON gidiş-dönüş) çevirir
+func normalize(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func quickConfig() *quick.Config {
+	return &quick.Config{MaxCount: 200, Rand: rand.New(rand.NewSource(1))}
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	codec := &Codec{ReplayCache: NewMemoryReplayCache(1000, 0)}
+	r := rand.New(rand.NewSource(2))
+
+	property := func(p roundTripPayload, method string, path string) bool {
+		b := RequestBinding(method, path, "roundtrip-session")
+		payload := p.withReplayFields(r)
+
+		encrypted, err := codec.EncryptData(payload, fuzzKey, b)
+		if err != nil {
+			t.Logf("şifreleme hatası: %v", err)
+			return false
+		}
+		decrypted, err := codec.DecryptData(encrypted, fuzzKey, b)
+		if err != nil {
+			t.Logf("çözme hatası: %v", err)
+			return false
+		}
+		return reflect.DeepEqual(decrypted, normalize(t, payload))
+	}
+
+	if err := quick.Check(property, quickConfig()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryParamsRoundTrip(t *testing.T) {
+	codec := &Codec{ReplayCache: NewMemoryReplayCache(1000, 0)}
+	r := rand.New(rand.NewSource(3))
+	b := RequestBinding("GET", "/api/data", "roundtrip-session")
+
+	property := func(p roundTripPayload) bool {
+		params := p.withReplayFields(r)
+
+		encrypted, err := codec.EncryptQueryParams(params, fuzzKey, b)
+		if err != nil {
+			return false
+		}
+		decrypted, err := codec.DecryptQueryParams(encrypted, fuzzKey, b)
+		if err != nil {
+			t.Logf("çözme hatası: %v", err)
+			return false
+		}
+		return reflect.DeepEqual(decrypted, normalize(t, params))
+	}
+
+	if err := quick.Check(property, quickConfig()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRoundTripBindingMismatch başka bir isteğin bağlamıyla çözmenin her
+// zaman başarısız olduğunu doğrular
+func TestRoundTripBindingMismatch(t *testing.T) {
+	codec := &Codec{ReplayCache: NewMemoryReplayCache(1000, 0)}
+	r := rand.New(rand.NewSource(4))
+
+	property := func(p roundTripPayload, path string) bool {
+		payload := p.withReplayFields(r)
+		encrypted, err := codec.EncryptData(payload, fuzzKey, RequestBinding("PUT", path, "roundtrip-session"))
+		if err != nil {
+			return false
+		}
+
+		for _, other := range []Binding{
+			RequestBinding("DELETE", path, "roundtrip-session"),
+			RequestBinding("PUT", path+"/", "roundtrip-session"),
+			RequestBinding("PUT", path, "other-session"),
+			ResponseBinding("PUT", path, "roundtrip-session"),
+		} {
+			if _, err := codec.DecryptData(encrypted, fuzzKey, other); !errors.Is(err, ErrAuthFailed) {
+				t.Logf("%+v bağlamıyla çözme: %v", other, err)
+				return false
+			}
+		}
+		return true
+	}
+
+	if err := quick.Check(property, quickConfig()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRoundTripExpiredTimestamp pencere dışındaki _timestamp'ın reddedildiğini doğrular
+func TestRoundTripExpiredTimestamp(t *testing.T) {
+	codec := &Codec{ReplayCache: NewMemoryReplayCache(16, 0), ReplayWindow: time.Minute, ClockSkew: time.Second}
+	b := RequestBinding("POST", "/api/data", "roundtrip-session")
+
+	cases := []struct {
+		offset time.Duration
+		want   error
+	}{
+		{-2 * time.Minute, ErrExpired},
+		{10 * time.Second, ErrClockSkew},
+	}
+	for _, tc := range cases {
+		payload := map[string]interface{}{"_timestamp": time.Now().Add(tc.offset).UnixMilli(), "_nonce": "n" + tc.offset.String()}
+		encrypted, err := codec.EncryptData(payload, fuzzKey, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := codec.DecryptData(encrypted, fuzzKey, b); !errors.Is(err, tc.want) {
+			t.Errorf("offset %s: hata %v, beklenen %v", tc.offset, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package crypto
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSequenceWindow en yüksek sıra numarasının bu kadar gerisindeki
+	// numaraların hâlâ kabul edildiği kayan pencere boyutudur. Eşzamanlı
+	// isteklerin sunucuya farklı sırayla ulaşmasını tolere eder.
+	DefaultSequenceWindow = 1024
+
+	// maxSequence JSON sayısının (float64) kayıpsız taşıyabildiği en büyük tamsayıdır
+	maxSequence = 1 << 53
+)
+
+// SequenceStore oturum başına artan sıra numaralarını (_seq) kayan bir kabul
+// penceresiyle izler (IPsec/DTLS anti-replay). Zaman damgası yerine sıra
+// numarası kullanıldığında tekrar saat farkından bağımsız olarak reddedilir.
+// Paylaşımlı bir depo için bu arayüz uygulanıp Codec.Sequences ile verilebilir.
+type SequenceStore interface {
+	// Accept seq bu kapsamda daha önce görülmediyse ve pencerenin gerisinde
+	// kalmadıysa kaydeder ve true döner. Kapsam en az expiresAt'e kadar tutulur.
+	Accept(scope string, seq uint64, expiresAt time.Time) bool
+}
+
+// sequenceWindow tek bir kapsamın kayan penceresidir. Bitmap 64 bitlik
+// bloklardan oluşan bir halkadır (RFC 6479): sıra numarası seq, seq>>6 numaralı
+// bloğun seq&63 numaralı bitidir. Pencere ilerlerken yalnızca geride kalan
+// bloklar temizlenir, bitler kaydırılmaz.
+type sequenceWindow struct {
+	// top görülen en yüksek sıra numarası (0: henüz yok)
+	top       uint64
+	bitmap    []uint64
+	expiresAt time.Time
+}
+
+func newSequenceWindow(size uint64) *sequenceWindow {
+	// Pencere blok sınırına hizalı olmadığından bir blok fazlası gerekir
+	return &sequenceWindow{bitmap: make([]uint64, size/64+1)}
+}
+
+// accept seq'i pencereye göre kontrol eder ve kabul edilirse kaydeder
+func (w *sequenceWindow) accept(seq, size uint64) bool {
+	if seq == 0 || seq > maxSequence {
+		return false
+	}
+
+	// Pencerenin gerisinde kalan numaralar görülüp görülmediği bilinemediğinden reddedilir
+	if seq+size <= w.top {
+		return false
+	}
+
+	blocks := uint64(len(w.bitmap))
+	if seq > w.top {
+		// Pencere ilerler: aradaki bloklar yeni numaralar için temizlenir
+		current, next := w.top>>6, seq>>6
+		for i := uint64(1); i <= next-current && i <= blocks; i++ {
+			w.bitmap[(current+i)%blocks] = 0
+		}
+		w.top = seq
+	}
+
+	block := &w.bitmap[(seq>>6)%blocks]
+	bit := uint64(1) << (seq & 63)
+	if *block&bit != 0 {
+		return false
+	}
+	*block |= bit
+	return true
+}
+
+// MemorySequenceStore sınırlı kapasiteli, bellek içi SequenceStore uygulamasıdır
+type MemorySequenceStore struct {
+	sync.Mutex
+	windows   map[string]*sequenceWindow
+	size      uint64
+	maxScopes int
+}
+
+// NewMemorySequenceStore en fazla maxScopes kapsam için windowSize boyutlu
+// pencere tutan bir depo oluşturur. windowSize 0 ise DefaultSequenceWindow kullanılır.
+func NewMemorySequenceStore(maxScopes, windowSize int) *MemorySequenceStore {
+	if windowSize <= 0 {
+		windowSize = DefaultSequenceWindow
+	}
+	return &MemorySequenceStore{
+		windows:   make(map[string]*sequenceWindow),
+		size:      uint64(windowSize),
+		maxScopes: maxScopes,
+	}
+}
+
+// Accept SequenceStore arayüzünü uygular
+func (m *MemorySequenceStore) Accept(scope string, seq uint64, expiresAt time.Time) bool {
+	m.Lock()
+	defer m.Unlock()
+
+	w, exists := m.windows[scope]
+	if !exists {
+		// Kapasite doluysa süresi dolan pencereler silinir. Hâlâ doluysa istek
+		// reddedilir; canlı bir pencereyi atmak o kapsamdaki eski numaraların
+		// tekrar kabul edilmesine yol açar.
+		if len(m.windows) >= m.maxScopes {
+			m.sweepLocked(Now())
+			if len(m.windows) >= m.maxScopes {
+				return false
+			}
+		}
+		w = newSequenceWindow(m.size)
+	}
+
+	if !w.accept(seq, m.size) {
+		return false
+	}
+	m.windows[scope] = w
+	if expiresAt.After(w.expiresAt) {
+		w.expiresAt = expiresAt
+	}
+	return true
+}
+
+// Len izlenen kapsam sayısını döndürür
+func (m *MemorySequenceStore) Len() int {
+	m.Lock()
+	defer m.Unlock()
+	return len(m.windows)
+}
+
+// sweepLocked süresi dolan pencereleri siler
+func (m *MemorySequenceStore) sweepLocked(now time.Time) {
+	for scope, w := range m.windows {
+		if !w.expiresAt.After(now) {
+			delete(m.windows, scope)
+		}
+	}
+}
+
+// sequenceScope sıra numarası penceresinin kapsamını döndürür. El sıkışma
+// anahtarlarında kapsam oturum ve el sıkışmadır: epoch rotasyonu sayacı
+// korur, yeni el sıkışma sayacı sıfırlar. Diğer anahtarlarda kapsam oturumdur.
+func sequenceScope(sk *SessionKey, sessionID string) string {
+	if sk.ring != nil {
+		return sessionID + "\x00" + sk.ring.baseID
+	}
+	return sessionID
+}
+
+// validateSequence _seq alanını oturumun kayan penceresine göre doğrular
+func validateSequence(data map[string]interface{}, scope string, store SequenceStore, expiresAt time.Time) error {
+	seqVal, exists := data["_seq"]
+	if !exists {
+		return fmt.Errorf("%w: sıra numarası (_seq) eksik", ErrMalformedJSON)
+	}
+
+	seq, ok := seqVal.(float64)
+	if !ok || seq < 1 || seq > maxSequence || seq != math.Trunc(seq) {
+		return fmt.Errorf("%w: geçersiz sıra numarası formatı", ErrMalformedJSON)
+	}
+
+	return acceptSequence(uint64(seq), scope, store, expiresAt)
+}
+
+// acceptSequence sıra numarasını kapsamın penceresine kaydeder; görülmüş veya
+// pencerenin gerisinde kalan numaralar ErrReplay ile reddedilir
+func acceptSequence(seq uint64, scope string, store SequenceStore, expiresAt time.Time) error {
+	if !store.Accept(scope, seq, expiresAt) {
+		return fmt.Errorf("%w: sıra numarası %d", ErrReplay, seq)
+	}
+	return nil
+}
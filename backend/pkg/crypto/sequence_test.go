@@ -0,0 +1,181 @@
+package crypto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSequenceWindow(t *testing.T) {
+	const size = 128
+	w := newSequenceWindow(size)
+
+	steps := []struct {
+		seq  uint64
+		want bool
+	}{
+		{1, true},
+		{1, false},
+		{3, true},
+		{2, true}, // pencere içinde sırasız gelen istek
+		{3, false},
+		{200, true},
+		{73, true},  // 200-73 < 128
+		{72, false}, // pencerenin gerisinde
+		{200, false},
+		{0, false},
+		{maxSequence + 1, false},
+		{10000, true}, // büyük sıçrama tüm bitmap'i temizler
+		{9999, true},
+		{200, false},
+		{10000 - size + 1, true},
+		{10000 - size + 1, false},
+	}
+	for i, step := range steps {
+		if got := w.accept(step.seq, size); got != step.want {
+			t.Fatalf("adım %d: seq %d kabul=%v, beklenen %v", i+1, step.seq, got, step.want)
+		}
+	}
+}
+
+// TestSequenceWindowBlockBoundaries pencere sınırının blok sınırlarına
+// denk gelmediği durumlarda her numaranın tam bir kez kabul edildiğini doğrular
+func TestSequenceWindowBlockBoundaries(t *testing.T) {
+	const size = 64
+	w := newSequenceWindow(size)
+
+	for top := uint64(1); top <= 300; top++ {
+		if !w.accept(top, size) {
+			t.Fatalf("yeni en yüksek numara %d reddedildi", top)
+		}
+		for seq := uint64(1); seq <= top; seq++ {
+			if w.accept(seq, size) {
+				t.Fatalf("top %d: seq %d ikinci kez kabul edildi", top, seq)
+			}
+		}
+	}
+}
+
+func TestMemorySequenceStore(t *testing.T) {
+	clock := useFakeClock(t)
+	store := NewMemorySequenceStore(1, 64)
+	expiresAt := clock.Now().Add(time.Minute)
+
+	if !store.Accept("a", 1, expiresAt) || store.Accept("a", 1, expiresAt) {
+		t.Fatal("tekrar eden numara kabul edildi")
+	}
+
+	// Kapasite doluyken canlı pencere atılmaz, yeni kapsam reddedilir
+	if store.Accept("b", 1, expiresAt) {
+		t.Fatal("kapasite aşıldı")
+	}
+
+	clock.Advance(time.Minute)
+	if !store.Accept("b", 1, clock.Now().Add(time.Minute)) || store.Len() != 1 {
+		t.Fatalf("süresi dolan pencere silinmedi (%d kapsam)", store.Len())
+	}
+}
+
+func TestCodecSequenceMode(t *testing.T) {
+	codec := &Codec{Sequences: NewMemorySequenceStore(16, 0)}
+	b := RequestBinding("POST", "/api/data", "seq-session")
+	queryBinding := RequestBinding("GET", "/api/data", "seq-session")
+
+	body := func(payload map[string]interface{}) error {
+		encrypted, err := codec.EncryptData(payload, fuzzKey, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = codec.DecryptData(encrypted, fuzzKey, b)
+		return err
+	}
+	query := func(payload map[string]interface{}) error {
+		encrypted, err := codec.EncryptQueryParams(payload, fuzzKey, queryBinding)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = codec.DecryptQueryParams(encrypted, fuzzKey, queryBinding)
+		return err
+	}
+	// Akışlarda sıra numarası başlıkta taşınır (gövdelerle aynı sayaç)
+	stream := func(payload map[string]interface{}) error {
+		seq, _ := payload["_seq"].(int)
+		var buf bytes.Buffer
+		w, err := NewSequencedStreamWriter(&buf, fuzzKey, b, 0, uint64(seq))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(`{"id":"1"}`))
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		_, err = codec.OpenStream(&buf, fuzzKey, b)
+		return err
+	}
+
+	cases := []struct {
+		name    string
+		decrypt func(map[string]interface{}) error
+		payload map[string]interface{}
+		want    error
+	}{
+		// Zaman damgası kontrol edilmez
+		{"gövde", body, map[string]interface{}{"_seq": 1, "_timestamp": 0}, nil},
+		{"query sırasız", query, map[string]interface{}{"_seq": 3}, nil},
+		{"gövde sırasız", body, map[string]interface{}{"_seq": 2}, nil},
+		{"query tekrar", query, map[string]interface{}{"_seq": 1}, ErrReplay},
+		{"gövde tekrar", body, map[string]interface{}{"_seq": 3}, ErrReplay},
+		{"eksik", body, map[string]interface{}{"_nonce": "x"}, ErrMalformedJSON},
+		{"kesirli", query, map[string]interface{}{"_seq": 4.5}, ErrMalformedJSON},
+		{"sıfır", body, map[string]interface{}{"_seq": 0}, ErrMalformedJSON},
+		{"akış", stream, map[string]interface{}{"_seq": 4}, nil},
+		{"akış tekrar", stream, map[string]interface{}{"_seq": 2}, ErrReplay},
+		{"akıştan sonra gövde", body, map[string]interface{}{"_seq": 4}, ErrReplay},
+		{"akış eksik", stream, map[string]interface{}{}, ErrBadEncoding},
+	}
+	for _, tc := range cases {
+		if err := tc.decrypt(tc.payload); !errors.Is(err, tc.want) {
+			t.Errorf("%s: hata %v, beklenen %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+// TestSequenceScope epoch rotasyonunun sayacı koruduğunu, yeni el sıkışmanın sıfırladığını doğrular
+func TestSequenceScope(t *testing.T) {
+	codec := &Codec{Sequences: NewMemorySequenceStore(16, 0)}
+	b := RequestBinding("POST", "/api/data", "seq-session")
+	expiresAt := time.Now().Add(time.Hour)
+
+	ring := func(baseID string) *keyRing {
+		r, err := newKeyRing(make([]byte, 32), baseID, "seq-session", RotationPolicy{GracePeriod: time.Hour}, AlgAES256GCM, time.Now(), expiresAt, [32]byte{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+	first, second := ring("first"), ring("second")
+	nextEpoch, err := first.deriveEpoch(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.next, first.announcedAt = nextEpoch, time.Now() // rotasyon duyurulmuş
+
+	cases := []struct {
+		key  *SessionKey
+		want error
+	}{
+		{first.current, nil},
+		{nextEpoch, ErrReplay},
+		{second.current, nil},
+	}
+	for i, tc := range cases {
+		encrypted, err := codec.EncryptData(map[string]interface{}{"_seq": 1}, tc.key, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := codec.DecryptData(encrypted, tc.key, b); !errors.Is(err, tc.want) {
+			t.Errorf("anahtar %d (%s): hata %v, beklenen %v", i+1, tc.key.ID, err, tc.want)
+		}
+	}
+}
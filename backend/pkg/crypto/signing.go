@@ -0,0 +1,147 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// signatureLabel imza girdisinin başındaki sabit etikettir (doğrulayıcıyla birebir aynı olmalı)
+const signatureLabel = "uctanuca/sig/v2"
+
+// ErrBadSignature yanıt imzası doğrulanamadı
+var ErrBadSignature = errors.New("yanıt imzası geçersiz")
+
+// ResponseSigner şifreli yanıtların düz metnini sunucunun Ed25519 anahtarıyla
+// imzalar. Oturum anahtarı istemcide de bulunduğundan AEAD etiketi yanıtı
+// sunucunun ürettiğini üçüncü kişilere kanıtlamaz; imza bunu sağlar ve public
+// key ile düz metin üzerinden çevrimdışı doğrulanabilir.
+type ResponseSigner struct {
+	keyID string
+	priv  ed25519.PrivateKey
+}
+
+// NewResponseSigner verilen özel anahtarla bir imzalayıcı oluşturur. Anahtar
+// kimliği public key'in SHA-256 özetinin ilk 8 byte'ıdır (hex).
+func NewResponseSigner(priv ed25519.PrivateKey) *ResponseSigner {
+	pub := priv.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(pub)
+	return &ResponseSigner{keyID: hex.EncodeToString(sum[:8]), priv: priv}
+}
+
+// GenerateResponseSigner rastgele bir anahtarla imzalayıcı oluşturur. Anahtar
+// süreçle birlikte kaybolur; kalıcı doğrulama için LoadResponseSignerFile kullanılmalıdır.
+func GenerateResponseSigner() (*ResponseSigner, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("imza anahtarı üretme hatası: %w", err)
+	}
+	return NewResponseSigner(priv), nil
+}
+
+// LoadResponseSignerFile PEM (PKCS#8) biçimindeki Ed25519 özel anahtarını yükler
+// (örn. openssl genpkey -algorithm ed25519)
+func LoadResponseSignerFile(path string) (*ResponseSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("imza anahtarı dosyası okunamadı: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("imza anahtarı PEM PRIVATE KEY bloğu değil")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("imza anahtarı ayrıştırılamadı: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("imza anahtarı Ed25519 değil")
+	}
+	return NewResponseSigner(priv), nil
+}
+
+// KeyID imza anahtarının kimliğidir
+func (s *ResponseSigner) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey imzaları doğrulamak için gereken public key'dir
+func (s *ResponseSigner) PublicKey() ed25519.PublicKey {
+	return s.priv.Public().(ed25519.PublicKey)
+}
+
+// Sign yanıtın düz metnini (şifrelenen JSON) bağlamı ve HTTP durum koduyla
+// birlikte imzalar ve imzayı base64 olarak döndürür. İmza şifreli metne değil
+// düz metnin özetine bağlanır: AES-GCM gibi AEAD'ler anahtara bağlayıcı
+// olmadığından aynı zarf farklı bir anahtarla başka bir düz metne açılabilir.
+func (s *ResponseSigner) Sign(plaintext []byte, b Binding, status int) string {
+	sig := ed25519.Sign(s.priv, signatureInput(s.keyID, plaintext, b, status))
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// VerifyResponseSignature Sign ile üretilen imzayı doğrular. plaintext yanıt
+// zarfının çözülmüş halidir; üçüncü kişi doğrulaması için istemci düz metni
+// (veya oturum anahtarını) paylaşır. Ayrıca isteğin metodu, yolu, oturum ID'si
+// ve durum kodu gerekir.
+func VerifyResponseSignature(pub ed25519.PublicKey, keyID string, plaintext []byte, signatureBase64 string, b Binding, status int) error {
+	sig, err := base64.StdEncoding.DecodeString(signatureBase64)
+	if err != nil {
+		return fmt.Errorf("%w: imza base64 decode başarısız", ErrBadEncoding)
+	}
+
+	if len(pub) != ed25519.PublicKeySize || !ed25519.Verify(pub, signatureInput(keyID, plaintext, b, status), sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// signatureInput imzalanan byte dizisini oluşturur. Etiket ve anahtar kimliği
+// 4 byte big-endian uzunluk ön ekiyle yazılır; bağlam Binding.AAD biçimindedir:
+//
+//	label | keyId | binding | status(4) | SHA-256(plaintext)
+func signatureInput(keyID string, plaintext []byte, b Binding, status int) []byte {
+	aad := b.AAD()
+	digest := sha256.Sum256(plaintext)
+
+	out := make([]byte, 0, 4+len(signatureLabel)+4+len(keyID)+len(aad)+4+len(digest))
+	out = binary.BigEndian.AppendUint32(out, uint32(len(signatureLabel)))
+	out = append(out, signatureLabel...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(keyID)))
+	out = append(out, keyID...)
+	out = append(out, aad...)
+	out = binary.BigEndian.AppendUint32(out, uint32(status))
+	return append(out, digest[:]...)
+}
+
+// SigningJWK imza public key'inin JWKS kaydıdır (RFC 8037, OKP/Ed25519)
+type SigningJWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	X   string `json:"x"`
+}
+
+// JWK imzalayıcının public key'ini JWKS kaydı olarak döndürür
+func (s *ResponseSigner) JWK() SigningJWK {
+	return SigningJWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		Kid: s.keyID,
+		Alg: "EdDSA",
+		Use: "sig",
+		X:   base64.RawURLEncoding.EncodeToString(s.PublicKey()),
+	}
+}
@@ -0,0 +1,373 @@
+package crypto
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// EnvelopeStreamV1 parçalı (STREAM) şifreli akış formatının sürüm baytıdır.
+// Ayrıntılı format: memory-bank/wireFormat.md
+const EnvelopeStreamV1 byte = 0x02
+
+// Akış parça boyutları
+const (
+	// DefaultSegmentSize bir parçadaki en fazla düz metin boyutudur
+	DefaultSegmentSize = 64 * 1024
+	// MaxSegmentSize okuyucunun kabul ettiği en büyük parça düz metin boyutudur
+	MaxSegmentSize = 1024 * 1024
+)
+
+const (
+	// streamNonceSuffixSize parça nonce'unun sayaç(4) ve son(1) kısmıdır; nonce
+	// ön eki algoritmanın nonce boyutunun geri kalanıdır (AES-GCM için 7,
+	// XChaCha20-Poly1305 için 19 bayt)
+	streamNonceSuffixSize = 5
+	// streamMaxSegments 32 bit sayaç taşmadan önce yazılabilecek parça sayısıdır
+	streamMaxSegments = 1<<32 - 1
+)
+
+// StreamHeader akışın şifrelenmeyen başlığıdır:
+//
+//	version(1) | algorithm(1) | keyIdLen(1) | keyId(n) | timestamp(8) | sequence(8) | noncePrefix(nonceSize-5)
+//
+// Başlık her parçanın AAD'sine bağlanır. Zaman damgası ve nonce ön eki
+// replay korumasında _timestamp ve _nonce alanlarının, sıra numarası ise
+// sıra numarası modunda _seq alanının yerini tutar (0: yok).
+type StreamHeader struct {
+	Version     byte
+	Algorithm   byte
+	KeyID       string
+	Timestamp   time.Time
+	Sequence    uint64
+	NoncePrefix []byte
+}
+
+// Marshal başlığı byte dizisine dönüştürür
+func (h *StreamHeader) Marshal() []byte {
+	out := make([]byte, 0, 3+len(h.KeyID)+16+len(h.NoncePrefix))
+	out = append(out, h.Version, h.Algorithm, byte(len(h.KeyID)))
+	out = append(out, h.KeyID...)
+	out = binary.BigEndian.AppendUint64(out, uint64(h.Timestamp.UnixMilli()))
+	out = binary.BigEndian.AppendUint64(out, h.Sequence)
+	return append(out, h.NoncePrefix...)
+}
+
+// Nonce replay önbelleğinde kullanılan nonce ön ekidir (hex)
+func (h *StreamHeader) Nonce() string {
+	return hex.EncodeToString(h.NoncePrefix)
+}
+
+// ReadStreamHeader akış başlığını okur. Başlık burada doğrulanmaz; AAD'ye
+// bağlı olduğundan OpenStream ilk parçayı çözerken doğrular.
+func ReadStreamHeader(r io.Reader) (*StreamHeader, error) {
+	fixed := make([]byte, 3)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, fmt.Errorf("%w: akış başlığı okunamadı", ErrBadEncoding)
+	}
+
+	h := &StreamHeader{Version: fixed[0], Algorithm: fixed[1]}
+	if h.Version != EnvelopeStreamV1 {
+		return nil, fmt.Errorf("%w: desteklenmeyen akış sürümü: %d", ErrBadEncoding, h.Version)
+	}
+	alg, err := LookupAlgorithm(h.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	rest := make([]byte, int(fixed[2])+16+alg.NonceSize-streamNonceSuffixSize)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, fmt.Errorf("%w: akış başlığı okunamadı", ErrBadEncoding)
+	}
+
+	keyIDLen := int(fixed[2])
+	h.KeyID = string(rest[:keyIDLen])
+	h.Timestamp = time.UnixMilli(int64(binary.BigEndian.Uint64(rest[keyIDLen:])))
+	h.Sequence = binary.BigEndian.Uint64(rest[keyIDLen+8:])
+	h.NoncePrefix = rest[keyIDLen+16:]
+
+	return h, nil
+}
+
+// segmentNonce parça nonce'unu oluşturur: noncePrefix | sayaç(4) | son(1)
+func segmentNonce(prefix []byte, counter uint32, final bool) []byte {
+	nonce := make([]byte, 0, len(prefix)+streamNonceSuffixSize)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// StreamWriter düz metni sabit boyutlu parçalar halinde şifreleyerek yazar.
+// Her parça length(4) | ciphertext+tag olarak yazılır; son parça nonce'taki
+// bayrakla işaretlenir, böylece akışın kesilmesi veya uzatılması fark edilir.
+// Close çağrılmadan akış geçersizdir.
+type StreamWriter struct {
+	w           io.Writer
+	key         *SessionKey
+	aead        cipher.AEAD
+	header      []byte
+	aad         []byte
+	prefix      []byte
+	counter     uint64
+	buf         []byte
+	segmentSize int
+	wroteHeader bool
+	closed      bool
+}
+
+// NewStreamWriter w'ye yazan bir şifreli akış oluşturur. segmentSize sıfırsa
+// DefaultSegmentSize kullanılır. Başlık ilk yazma veya Flush ile gönderilir.
+func NewStreamWriter(w io.Writer, sk *SessionKey, b Binding, segmentSize int) (*StreamWriter, error) {
+	return NewSequencedStreamWriter(w, sk, b, segmentSize, 0)
+}
+
+// NewSequencedStreamWriter başlığında sıra numarası taşıyan bir şifreli akış
+// oluşturur. Sıra numarası modundaki sunuculara gönderilen istek akışları için
+// kullanılır; seq, gövde ve query'lerdeki _seq ile aynı sayaçtan alınmalıdır.
+func NewSequencedStreamWriter(w io.Writer, sk *SessionKey, b Binding, segmentSize int, seq uint64) (*StreamWriter, error) {
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+	if segmentSize > MaxSegmentSize {
+		return nil, errors.New("parça boyutu çok büyük")
+	}
+	if len(sk.ID) > 255 {
+		return nil, errors.New("anahtar kimliği çok uzun")
+	}
+
+	alg, err := sk.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := alg.New(sk.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &StreamHeader{
+		Version:     EnvelopeStreamV1,
+		Algorithm:   alg.ID,
+		KeyID:       sk.ID,
+		Timestamp:   Now(),
+		Sequence:    seq,
+		NoncePrefix: make([]byte, alg.NonceSize-streamNonceSuffixSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, h.NoncePrefix); err != nil {
+		return nil, fmt.Errorf("nonce oluşturma hatası: %w", err)
+	}
+
+	header := h.Marshal()
+	return &StreamWriter{
+		w:           w,
+		key:         sk,
+		aead:        aead,
+		header:      header,
+		aad:         append(append([]byte{}, header...), b.AAD()...),
+		prefix:      h.NoncePrefix,
+		buf:         make([]byte, 0, segmentSize),
+		segmentSize: segmentSize,
+	}, nil
+}
+
+// Write io.Writer arayüzünü uygular. Dolu bir parça ancak arkasından yeni
+// veri geldiğinde yazılır; böylece son parça Close'da işaretlenebilir.
+func (s *StreamWriter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("akış kapatılmış")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		if len(s.buf) == s.segmentSize {
+			if err := s.sealSegment(false); err != nil {
+				return written, err
+			}
+		}
+
+		n := copy(s.buf[len(s.buf):s.segmentSize], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Flush bekleyen veriyi son olmayan bir parça olarak hemen yazar
+func (s *StreamWriter) Flush() error {
+	if s.closed {
+		return errors.New("akış kapatılmış")
+	}
+	if len(s.buf) == 0 {
+		return s.writeHeader()
+	}
+	return s.sealSegment(false)
+}
+
+// Close kalan veriyi son parça olarak yazar. Alttaki yazıcıyı kapatmaz.
+func (s *StreamWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.sealSegment(true)
+}
+
+func (s *StreamWriter) writeHeader() error {
+	if s.wroteHeader {
+		return nil
+	}
+	s.wroteHeader = true
+	_, err := s.w.Write(s.header)
+	return err
+}
+
+func (s *StreamWriter) sealSegment(final bool) error {
+	if s.counter >= streamMaxSegments {
+		return errors.New("akış parça sayısı sınırı aşıldı")
+	}
+	if err := s.writeHeader(); err != nil {
+		return err
+	}
+
+	nonce := segmentNonce(s.prefix, uint32(s.counter), final)
+	out := make([]byte, 4, 4+len(s.buf)+s.aead.Overhead())
+	out = s.aead.Seal(out, nonce, s.buf, s.aad)
+	binary.BigEndian.PutUint32(out[:4], uint32(len(out)-4))
+	s.key.recordUse(len(s.buf))
+
+	s.counter++
+	s.buf = s.buf[:0]
+
+	_, err := s.w.Write(out)
+	return err
+}
+
+// errTruncatedStream son parça görülmeden biten akışı bildirir; hem
+// ErrAuthFailed hem io.ErrUnexpectedEOF ile eşleşir
+var errTruncatedStream = fmt.Errorf("%w: akış son parçadan önce kesildi (%w)", ErrAuthFailed, io.ErrUnexpectedEOF)
+
+// StreamReader şifreli akışı parça parça çözer. Son parça görülmeden akış
+// biterse errTruncatedStream, son parçadan sonra veri varsa hata döner.
+type StreamReader struct {
+	r       io.Reader
+	key     *SessionKey
+	aead    cipher.AEAD
+	aad     []byte
+	header  *StreamHeader
+	counter uint64
+	buf     []byte
+	final   bool
+	err     error
+}
+
+// OpenStream akış başlığını okur ve ilk parçayı çözer. İlk parça başlığı da
+// doğruladığından dönen Header() değerine güvenilebilir.
+func OpenStream(r io.Reader, sk *SessionKey, b Binding) (*StreamReader, error) {
+	h, err := ReadStreamHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	key, err := sk.resolve(h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := key.aeadFor(h.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &StreamReader{
+		r:      r,
+		key:    key,
+		aead:   aead,
+		aad:    append(h.Marshal(), b.AAD()...),
+		header: h,
+	}
+	if err := s.openSegment(); err != nil {
+		return nil, err
+	}
+	// İlk parça doğrulandı; duyurulan epoch kullanıldıysa ona geçilir
+	key.accepted()
+	return s, nil
+}
+
+// Header doğrulanmış akış başlığını döndürür
+func (s *StreamReader) Header() *StreamHeader {
+	return s.header
+}
+
+// Read io.Reader arayüzünü uygular
+func (s *StreamReader) Read(p []byte) (int, error) {
+	for len(s.buf) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.final {
+			s.err = s.expectEOF()
+			continue
+		}
+		s.err = s.openSegment()
+	}
+
+	n := copy(p, s.buf)
+	s.buf = s.buf[n:]
+	return n, nil
+}
+
+// openSegment sıradaki parçayı okuyup çözer
+func (s *StreamReader) openSegment() error {
+	if s.counter >= streamMaxSegments {
+		return errors.New("akış parça sayısı sınırı aşıldı")
+	}
+
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(s.r, lenBuf[:]); err != nil {
+		// Son parça görülmeden biten akış kesilmiş sayılır
+		return errTruncatedStream
+	}
+
+	segLen := int(binary.BigEndian.Uint32(lenBuf[:]))
+	if segLen < s.aead.Overhead() || segLen > MaxSegmentSize+s.aead.Overhead() {
+		return fmt.Errorf("%w: geçersiz parça uzunluğu", ErrBadEncoding)
+	}
+
+	segment := make([]byte, segLen)
+	if _, err := io.ReadFull(s.r, segment); err != nil {
+		return errTruncatedStream
+	}
+
+	// Parçanın son olup olmadığı bilinmez; önce ara parça, sonra son parça dene
+	counter := uint32(s.counter)
+	plaintext, err := s.aead.Open(nil, segmentNonce(s.header.NoncePrefix, counter, false), segment, s.aad)
+	if err != nil {
+		plaintext, err = s.aead.Open(nil, segmentNonce(s.header.NoncePrefix, counter, true), segment, s.aad)
+		if err != nil {
+			// Hata detayını gizle (Oracle Attack Koruması)
+			return ErrAuthFailed
+		}
+		s.final = true
+	}
+
+	s.counter++
+	s.buf = plaintext
+	s.key.recordUse(len(plaintext))
+	return nil
+}
+
+// expectEOF son parçadan sonra ek veri olmadığını doğrular
+func (s *StreamReader) expectEOF() error {
+	var extra [1]byte
+	if _, err := io.ReadFull(s.r, extra[:]); err != io.EOF {
+		return fmt.Errorf("%w: son parçadan sonra beklenmeyen veri", ErrBadEncoding)
+	}
+	return io.EOF
+}
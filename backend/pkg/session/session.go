@@ -0,0 +1,263 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"secure-server/backend/pkg/crypto"
+	"sync"
+	"time"
+)
+
+// Varsayılan oturum süreleri
+const (
+	// DefaultIdleTimeout bu süre boyunca istek gelmeyen oturum sona erer
+	DefaultIdleTimeout = 30 * time.Minute
+	// DefaultAbsoluteTimeout oturum, kullanımdan bağımsız olarak en fazla bu kadar yaşar
+	DefaultAbsoluteTimeout = 12 * time.Hour
+)
+
+// DefaultMaxSessionsPerSubject bir kullanıcının (JWT sub) aynı anda açık
+// tutabileceği oturum sayısıdır. Sınır aşılınca kullanıcının en eski oturumu
+// kapatılır; tek bir kullanıcı kaydı doldurup diğerlerini dışarıda bırakamaz.
+const DefaultMaxSessionsPerSubject = 16
+
+// sessionIDBytes sunucunun ürettiği oturum kimliklerinin rastgele bayt sayısıdır (128 bit)
+const sessionIDBytes = 16
+
+var (
+	// ErrSessionNotFound oturum hiç oluşturulmamış, iptal edilmiş veya silinmiş
+	ErrSessionNotFound = errors.New("oturum bulunamadı")
+	// ErrSessionExpired oturumun boşta kalma veya mutlak süresi dolmuş
+	ErrSessionExpired = errors.New("oturum süresi dolmuş")
+	// ErrSubjectMismatch oturum başka bir kullanıcıya (JWT sub) ait
+	ErrSubjectMismatch = errors.New("oturum başka bir kullanıcıya ait")
+	// ErrMissingSubject token'da oturumun bağlanacağı sub claim'i yok
+	ErrMissingSubject = errors.New("token'da sub claim'i yok")
+	// ErrRegistryFull oturum sayısı sınırına ulaşıldı
+	ErrRegistryFull = errors.New("oturum sınırına ulaşıldı")
+)
+
+// Session sunucunun verdiği ve bir JWT subject'ine bağlı oturumdur
+type Session struct {
+	ID        string
+	Subject   string
+	CreatedAt time.Time
+	LastSeen  time.Time
+	// IdleExpiresAt her geçerli istekte ileri kaydırılır
+	IdleExpiresAt time.Time
+	// AbsoluteExpiresAt oturumun kesin bitiş zamanıdır
+	AbsoluteExpiresAt time.Time
+}
+
+// ExpiresAt oturumun (şu anki haliyle) sona ereceği zamanı döndürür
+func (s Session) ExpiresAt() time.Time {
+	if s.IdleExpiresAt.Before(s.AbsoluteExpiresAt) {
+		return s.IdleExpiresAt
+	}
+	return s.AbsoluteExpiresAt
+}
+
+// Store oturumları tutar. Paylaşımlı bir depo (örn. Redis) kullanmak için bu
+// arayüz uygulanabilir.
+type Store interface {
+	// Create subject için yeni, sunucu tarafından rastgele üretilmiş bir oturum açar
+	Create(subject string) (Session, error)
+	// Validate oturumun var olduğunu, süresinin dolmadığını ve subject'e ait
+	// olduğunu doğrular; geçerliyse boşta kalma süresini yeniler
+	Validate(id, subject string) (Session, error)
+	// Revoke oturumu hemen sonlandırır; oturum yoksa false döner
+	Revoke(id string) bool
+}
+
+// Registry bellek içi Store uygulamasıdır
+type Registry struct {
+	// Clock oturum sürelerinin saatidir; nil ise crypto paketinin saati
+	// (crypto.SetClock) kullanılır. Middleware WithClock ile aynı saat verilmelidir.
+	Clock crypto.Clock
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+	// bySubject kullanıcının oturum kimlikleridir (en eskisi başta)
+	bySubject       map[string][]string
+	idleTimeout     time.Duration
+	absoluteTimeout time.Duration
+	maxSessions     int
+	maxPerSubject   int
+}
+
+// NewRegistry yeni bir oturum kaydı oluşturur. Sıfır süreler varsayılanları
+// kullanır; maxSessions sıfırsa sınır yoktur. maxPerSubject sıfırsa
+// DefaultMaxSessionsPerSubject kullanılır.
+func NewRegistry(idleTimeout, absoluteTimeout time.Duration, maxSessions, maxPerSubject int) *Registry {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	if absoluteTimeout <= 0 {
+		absoluteTimeout = DefaultAbsoluteTimeout
+	}
+	if maxPerSubject <= 0 {
+		maxPerSubject = DefaultMaxSessionsPerSubject
+	}
+	return &Registry{
+		sessions:        make(map[string]*Session),
+		bySubject:       make(map[string][]string),
+		idleTimeout:     idleTimeout,
+		absoluteTimeout: absoluteTimeout,
+		maxSessions:     maxSessions,
+		maxPerSubject:   maxPerSubject,
+	}
+}
+
+func (r *Registry) now() time.Time {
+	if r.Clock != nil {
+		return r.Clock.Now()
+	}
+	return crypto.Now()
+}
+
+// Create Store arayüzünü uygular. Kullanıcının oturum sınırı doluysa en
+// eski oturumu kapatılır; genel sınır doluysa ErrRegistryFull döner.
+func (r *Registry) Create(subject string) (Session, error) {
+	if subject == "" {
+		return Session{}, ErrMissingSubject
+	}
+
+	id := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(id); err != nil {
+		return Session{}, fmt.Errorf("oturum kimliği üretme hatası: %w", err)
+	}
+
+	now := r.now()
+	s := &Session{
+		ID:                hex.EncodeToString(id),
+		Subject:           subject,
+		CreatedAt:         now,
+		LastSeen:          now,
+		IdleExpiresAt:     now.Add(r.idleTimeout),
+		AbsoluteExpiresAt: now.Add(r.absoluteTimeout),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Kullanıcı sınırı yalnızca o kullanıcının en eski oturumunu kapatır
+	if ids := r.bySubject[subject]; len(ids) >= r.maxPerSubject {
+		r.removeLocked(ids[0])
+	}
+
+	if r.maxSessions > 0 && len(r.sessions) >= r.maxSessions {
+		r.sweepLocked(now)
+		if len(r.sessions) >= r.maxSessions {
+			return Session{}, ErrRegistryFull
+		}
+	}
+
+	r.sessions[s.ID] = s
+	r.bySubject[subject] = append(r.bySubject[subject], s.ID)
+	return *s, nil
+}
+
+// Validate Store arayüzünü uygular
+func (r *Registry) Validate(id, subject string) (Session, error) {
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.sessions[id]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	if !now.Before(s.ExpiresAt()) {
+		r.removeLocked(id)
+		return Session{}, ErrSessionExpired
+	}
+	if s.Subject != subject {
+		return Session{}, ErrSubjectMismatch
+	}
+
+	s.LastSeen = now
+	s.IdleExpiresAt = now.Add(r.idleTimeout)
+	return *s, nil
+}
+
+// Revoke Store arayüzünü uygular
+func (r *Registry) Revoke(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.sessions[id]
+	r.removeLocked(id)
+	return ok
+}
+
+// Len kayıtlı oturum sayısını döndürür
+func (r *Registry) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sessions)
+}
+
+// Sweep süresi dolan oturumları siler ve silinen oturum sayısını döndürür
+func (r *Registry) Sweep() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sweepLocked(r.now())
+}
+
+func (r *Registry) sweepLocked(now time.Time) int {
+	removed := 0
+	for id, s := range r.sessions {
+		if !now.Before(s.ExpiresAt()) {
+			r.removeLocked(id)
+			removed++
+		}
+	}
+	return removed
+}
+
+// removeLocked oturumu kayıttan ve kullanıcının listesinden siler
+func (r *Registry) removeLocked(id string) {
+	s, ok := r.sessions[id]
+	if !ok {
+		return
+	}
+	delete(r.sessions, id)
+
+	ids := r.bySubject[s.Subject]
+	for i, other := range ids {
+		if other == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(r.bySubject, s.Subject)
+		return
+	}
+	r.bySubject[s.Subject] = ids
+}
+
+// StartSweeper süresi dolan oturumları interval aralıklarla arka planda siler.
+// Dönen fonksiyon temizleyiciyi durdurur.
+func (r *Registry) StartSweeper(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.Sweep()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
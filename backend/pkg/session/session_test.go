@@ -0,0 +1,167 @@
+package session
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock ileri sarılabilen test saatidir
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// newTestRegistry test saatiyle çalışan bir oturum kaydı oluşturur
+func newTestRegistry(idle, absolute time.Duration, maxSessions, maxPerSubject int) (*Registry, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	r := NewRegistry(idle, absolute, maxSessions, maxPerSubject)
+	r.Clock = clock
+	return r, clock
+}
+
+// TestRegistryClock oturum sürelerinin kayıt saatinden hesaplandığını doğrular
+func TestRegistryClock(t *testing.T) {
+	r, clock := newTestRegistry(time.Minute, time.Hour, 0, 0)
+
+	s, err := r.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.CreatedAt.Equal(clock.Now()) || !s.ExpiresAt().Equal(clock.Now().Add(time.Minute)) {
+		t.Fatalf("oturum zamanları sistem saatinden alındı: %+v", s)
+	}
+
+	clock.Advance(time.Minute)
+	if _, err := r.Validate(s.ID, "alice"); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("hata %v, beklenen %v", err, ErrSessionExpired)
+	}
+}
+
+func TestRegistryExpiry(t *testing.T) {
+	r, clock := newTestRegistry(10*time.Minute, 30*time.Minute, 0, 0)
+
+	idle, err := r.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	active, err := r.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Kullanılan oturumun boşta kalma süresi yenilenir (refresh)
+	for i := 0; i < 2; i++ {
+		clock.Advance(9 * time.Minute)
+		s, err := r.Validate(active.ID, "alice")
+		if err != nil {
+			t.Fatalf("yenileme %d: %v", i+1, err)
+		}
+		if want := clock.Now().Add(10 * time.Minute); !s.IdleExpiresAt.Equal(want) || !s.LastSeen.Equal(clock.Now()) {
+			t.Fatalf("yenileme %d: boşta kalma süresi %v, beklenen %v", i+1, s.IdleExpiresAt, want)
+		}
+	}
+	if _, err := r.Validate(idle.ID, "alice"); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("boşta kalan oturum: hata %v, beklenen %v", err, ErrSessionExpired)
+	}
+
+	// Mutlak süre yenilemeyle uzamaz
+	clock.Advance(9 * time.Minute)
+	s, err := r.Validate(active.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.ExpiresAt().Equal(active.AbsoluteExpiresAt) {
+		t.Fatalf("bitiş %v, beklenen mutlak süre %v", s.ExpiresAt(), active.AbsoluteExpiresAt)
+	}
+	clock.Advance(3 * time.Minute)
+	if _, err := r.Validate(active.ID, "alice"); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("mutlak süresi dolan oturum: hata %v, beklenen %v", err, ErrSessionExpired)
+	}
+	if r.Len() != 0 {
+		t.Fatalf("süresi dolan oturumlar silinmedi (%d oturum)", r.Len())
+	}
+}
+
+func TestRegistryRevokeAndSubject(t *testing.T) {
+	r, _ := newTestRegistry(0, 0, 0, 0)
+
+	if _, err := r.Create(""); !errors.Is(err, ErrMissingSubject) {
+		t.Fatalf("sub'sız oturum: hata %v, beklenen %v", err, ErrMissingSubject)
+	}
+
+	s, err := r.Create("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Validate(s.ID, "mallory"); !errors.Is(err, ErrSubjectMismatch) {
+		t.Fatalf("başka kullanıcı: hata %v, beklenen %v", err, ErrSubjectMismatch)
+	}
+	if _, err := r.Validate(s.ID, "alice"); err != nil {
+		t.Fatalf("başka kullanıcının denemesi oturumu bozdu: %v", err)
+	}
+
+	if !r.Revoke(s.ID) || r.Revoke(s.ID) {
+		t.Fatal("iptal sonucu hatalı")
+	}
+	if _, err := r.Validate(s.ID, "alice"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("iptal edilen oturum: hata %v, beklenen %v", err, ErrSessionNotFound)
+	}
+}
+
+// TestRegistryLimits kullanıcı sınırının yalnızca o kullanıcının en eski
+// oturumunu kapattığını, genel sınırın ise yeni oturumu reddettiğini doğrular
+func TestRegistryLimits(t *testing.T) {
+	r, clock := newTestRegistry(time.Minute, time.Hour, 4, 2)
+
+	create := func(subject string) Session {
+		t.Helper()
+		s, err := r.Create(subject)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	valid := func(s Session) bool {
+		_, err := r.Validate(s.ID, s.Subject)
+		return err == nil
+	}
+
+	alice := create("alice")
+	m1, m2, m3 := create("mallory"), create("mallory"), create("mallory")
+	if !valid(alice) || valid(m1) || !valid(m2) || !valid(m3) {
+		t.Fatal("kullanıcı sınırı yanlış oturumu kapattı")
+	}
+
+	// İptal edilen oturum kullanıcının sınırından düşer
+	r.Revoke(m2.ID)
+	m4 := create("mallory")
+	if !valid(m3) || !valid(m4) {
+		t.Fatal("iptal edilen oturum sınırdan düşmedi")
+	}
+
+	create("bob")
+	if _, err := r.Create("carol"); !errors.Is(err, ErrRegistryFull) {
+		t.Fatalf("dolu kayıt: hata %v, beklenen %v", err, ErrRegistryFull)
+	}
+
+	// Süresi dolan oturumlar yer açar
+	clock.Advance(time.Minute)
+	create("carol")
+	if r.Len() != 1 {
+		t.Fatalf("süresi dolan oturumlar silinmedi (%d oturum)", r.Len())
+	}
+}
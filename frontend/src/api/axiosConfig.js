@@ -5,8 +5,17 @@ import {
   clearKeyCache,
   encryptQueryParams,
   decryptQueryParams,
+  performHandshake,
+  hasSessionKey,
+  unwrapResponse,
+  advanceKeyEpoch,
+  updateServerClock,
 } from "../utils/crypto";
-import { getSessionId, clearSessionId } from "../utils/session";
+import {
+  getSessionId,
+  ensureSessionId,
+  clearSessionId,
+} from "../utils/session";
 
 // Sunucu yapılandırması
 const SERVER_CONFIG = {
@@ -45,23 +54,109 @@ const clearAuthData = () => {
   clearKeyCache();
 };
 
+/**
+ * İsteğin sunucuda görünen metodunu ve yolunu döndürür (AAD bağlamı).
+ * Sunucu c.Request.URL.Path kullandığı için query string dahil edilmez.
+ */
+function requestBinding(config) {
+  const url = (config.url || "").split("?")[0];
+  const path = /^https?:\/\//.test(url)
+    ? new URL(url).pathname
+    : `${SERVER_CONFIG.basePath}${url.startsWith("/") ? "" : "/"}${url}`;
+  return { method: (config.method || "get").toUpperCase(), path };
+}
+
+/**
+ * Yanıttaki X-Server-Time başlığıyla sunucu saat farkını günceller.
+ * Oturum ve el sıkışma yanıtları da başlığı taşıdığından ilk şifreli istek
+ * düzeltilmiş saatle gönderilir.
+ */
+function applyServerTime(response) {
+  const serverTime = response?.headers?.["x-server-time"];
+  if (serverTime) {
+    updateServerClock(serverTime, response.config?.sentAt);
+  }
+}
+
+// Eşzamanlı isteklerin tek bir el sıkışmayı paylaşması için
+let pendingHandshake = null;
+
+/**
+ * Oturum anahtarı yoksa sunucuyla X25519 el sıkışması yapar.
+ * İstek interceptor'larına takılmamak için ayrı bir axios çağrısı kullanılır.
+ */
+async function ensureSessionKey(token, sessionId) {
+  if (hasSessionKey(token, sessionId)) {
+    return;
+  }
+
+  if (!pendingHandshake) {
+    pendingHandshake = performHandshake(
+      token,
+      sessionId,
+      async (clientPublicKey, algorithms) => {
+        const response = await axios.post(
+          `${BASE_URL}/handshake`,
+          { client_public_key: clientPublicKey, algorithms },
+          {
+            headers: {
+              Authorization: `Bearer ${token}`,
+              "X-Session-ID": sessionId,
+            },
+          }
+        );
+        applyServerTime(response);
+        return response.data;
+      }
+    ).finally(() => {
+      pendingHandshake = null;
+    });
+  }
+
+  await pendingHandshake;
+}
+
 /**
  * Tüm HTTP metodları için request işleme
  */
 async function processRequest(config) {
   const token = getAuthToken();
-  const sessionId = getSessionId();
 
-  if (!token || !sessionId) {
+  if (!token) {
     return config;
   }
 
+  // Oturum ID'si sunucudan alınır ve token'ın kullanıcısına bağlıdır
+  const sessionId = await ensureSessionId(async () => {
+    const response = await axios.post(`${BASE_URL}/session`, null, {
+      headers: { Authorization: `Bearer ${token}` },
+    });
+    applyServerTime(response);
+    return response.data;
+  });
+
+  // clock_skew yanıtında istek şifrelenmemiş haliyle tekrar gönderilir
+  if (!config.plainRequest) {
+    config.plainRequest = {
+      url: config.url,
+      params: config.params,
+      data: config.data,
+    };
+  }
+
   // Header'lara kimlik bilgilerini ekle
   config.headers["Authorization"] = `Bearer ${token}`;
   config.headers["X-Session-ID"] = sessionId;
 
+  // Şifreleme anahtarı token'dan değil, el sıkışmadan gelir
+  await ensureSessionKey(token, sessionId);
+
   const method = config.method?.toLowerCase();
 
+  // Şifreli veri bu isteğin metoduna ve yoluna bağlanır; yanıt çözümü için saklanır
+  const binding = requestBinding(config);
+  config.encryptionBinding = binding;
+
   // GET istekleri için query parametrelerini şifrele
   if (method === "get" && config.params) {
     try {
@@ -70,7 +165,8 @@ async function processRequest(config) {
       const encryptedQuery = await encryptQueryParams(
         config.params,
         token,
-        sessionId
+        sessionId,
+        binding
       );
 
       // URL uzunluk kontrolü (Öneri: Çok uzun URL'ler için POST kullanın)
@@ -102,7 +198,12 @@ async function processRequest(config) {
     try {
       console.log(`${method.toUpperCase()} body şifreleniyor:`, config.data);
 
-      const encryptedData = await encryptData(config.data, token, sessionId);
+      const encryptedData = await encryptData(
+        config.data,
+        token,
+        sessionId,
+        binding
+      );
 
       config.transformRequest = [(data) => data];
       config.data = encryptedData;
@@ -116,9 +217,37 @@ async function processRequest(config) {
     }
   }
 
+  config.sentAt = Date.now();
   return config;
 }
 
+/**
+ * Sunucu _timestamp'ı saat farkı nedeniyle reddettiyse (clock_skew) isteği
+ * X-Server-Time ile düzeltilmiş saatle bir kez yeniden şifreleyip gönderir
+ */
+function retryWithServerTime(config) {
+  const retryConfig = {
+    ...config,
+    ...config.plainRequest,
+    transformRequest: undefined,
+    clockSkewRetried: true,
+  };
+  delete retryConfig.headers["X-Encrypted"];
+  retryConfig.headers["Content-Type"] = "application/json";
+  return api(retryConfig);
+}
+
+/**
+ * Sunucu X-Key-Next-Epoch ile anahtar rotasyonu duyurduysa sonraki istekler
+ * yeni epoch'un anahtarıyla şifrelenir
+ */
+function applyKeyRotation(response, token, sessionId) {
+  const nextEpoch = response.headers["x-key-next-epoch"];
+  if (token && sessionId && nextEpoch) {
+    advanceKeyEpoch(token, sessionId, nextEpoch);
+  }
+}
+
 /**
  * Tüm HTTP metodları için response işleme
  */
@@ -126,18 +255,35 @@ async function processResponse(response) {
   const token = getAuthToken();
   const sessionId = getSessionId();
 
+  applyServerTime(response);
+  applyKeyRotation(response, token, sessionId);
+
   if (!token || !sessionId || !response.data) {
     return response;
   }
 
+  // Parçalı akış (X-Encrypted: stream) yanıtları yalnızca Go istemcisi okur;
+  // tarayıcıya açılan rotalarda akış kapalı tutulmalıdır (wireFormat.md)
+  if (response.headers["x-encrypted"] === "stream") {
+    throw new Error("Şifreli akış yanıtları tarayıcı istemcisinde desteklenmiyor");
+  }
+
   const isEncrypted = response.headers["x-encrypted"] === "true";
+  const binding =
+    response.config.encryptionBinding || requestBinding(response.config);
 
   // Şifreli string yanıtları çöz
   if (isEncrypted && typeof response.data === "string") {
     try {
       console.log("Yanıt verisi çözülüyor...");
-      const decryptedData = await decryptData(response.data, token, sessionId);
-      response.data = decryptedData;
+      const decryptedData = await decryptData(
+        response.data,
+        token,
+        sessionId,
+        binding
+      );
+      // Dizi, metin ve dosya yanıtları sarmalanmış gelir
+      response.data = unwrapResponse(decryptedData);
     } catch (error) {
       console.error("Yanıt çözme hatası:", error);
       // Şifre çözme hatasında oturumu temizle
@@ -156,12 +302,13 @@ async function processResponse(response) {
   }
 
   // GET yanıtlarındaki şifrelenmiş query sonuçlarını çöz
-  if (response.data.encryptedQueryResult) {
+  if (response.data?.encryptedQueryResult) {
     try {
       const decryptedResult = await decryptQueryParams(
         response.data.encryptedQueryResult,
         token,
-        sessionId
+        sessionId,
+        binding
       );
 
       response.data = {
@@ -177,6 +324,37 @@ async function processResponse(response) {
   return response;
 }
 
+/**
+ * Şifreli hata yanıtını { error, code, request_id } biçimine çözer.
+ * Anahtar yoksa (401) sunucu hatayı düz JSON olarak döndürür.
+ */
+async function decryptErrorResponse(response) {
+  const token = getAuthToken();
+  const sessionId = getSessionId();
+
+  applyServerTime(response);
+  applyKeyRotation(response, token, sessionId);
+
+  if (
+    !token ||
+    !sessionId ||
+    response.headers["x-encrypted"] !== "true" ||
+    typeof response.data !== "string"
+  ) {
+    return;
+  }
+
+  try {
+    const binding =
+      response.config.encryptionBinding || requestBinding(response.config);
+    response.data = unwrapResponse(
+      await decryptData(response.data, token, sessionId, binding)
+    );
+  } catch (error) {
+    console.error("Hata yanıtı çözülemedi:", error);
+  }
+}
+
 // Request interceptor - tüm metodlar için
 api.interceptors.request.use(
   async (config) => {
@@ -219,14 +397,35 @@ api.interceptors.response.use(
       return Promise.reject(error);
     }
   },
-  (error) => {
+  async (error) => {
     console.error("Response interceptor error handler:", error);
 
     if (error.response) {
+      // Oturum anahtarı olan isteklerde hata yanıtları da şifreli gelir
+      await decryptErrorResponse(error.response);
+
       const { status, data } = error.response;
 
+      // Saat farkı düzeltildi: istek bir kez tekrar denenir
+      if (
+        data?.code === "clock_skew" &&
+        error.config?.plainRequest &&
+        !error.config.clockSkewRetried
+      ) {
+        console.warn("İstemci saati sunucuyla uyumsuz, istek tekrar deneniyor");
+        return retryWithServerTime(error.config);
+      }
+
       switch (status) {
         case 401:
+          if (data?.code === "session_invalid") {
+            // Token geçerli, oturum iptal edilmiş veya süresi dolmuş:
+            // bir sonraki istek yeni oturum ve el sıkışma yapar
+            console.error("Oturum geçersiz, yeni oturum açılacak");
+            clearSessionId();
+            clearKeyCache();
+            break;
+          }
           console.error("Yetkisiz erişim");
           clearAuthData();
           break;
@@ -235,7 +434,10 @@ api.interceptors.response.use(
           break;
         case 400:
           // Backend'den gelen genel güvenlik hatasını yakala ve oturumu temizle
-          if (data?.error?.includes("Veri güvenliği kontrolü başarısız")) {
+          if (
+            data?.code === "bad_request" &&
+            data?.error?.includes("Veri güvenliği kontrolü başarısız")
+          ) {
             console.error(
               "Backend güvenlik kontrolü (decryption/timestamp) başarısız oldu."
             );
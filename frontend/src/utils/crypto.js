@@ -1,98 +1,458 @@
 import { hkdf } from "@noble/hashes/hkdf";
 import { sha256 } from "@noble/hashes/sha256";
 import { randomBytes } from "@noble/hashes/utils";
+import { x25519 } from "@noble/curves/ed25519";
 
-// Anahtar önbelleği
+// HKDF info ön eki - backend/pkg/crypto/handshake.go ile birebir aynı olmalı
+const HANDSHAKE_INFO_PREFIX = "uctanuca/handshake/v1";
+// Epoch anahtarı info ön eki - backend/pkg/crypto/rotation.go ile birebir aynı olmalı
+const EPOCH_INFO_PREFIX = "uctanuca/epoch/v1";
+
+// Zarf (envelope) sabitleri - memory-bank/wireFormat.md
+const ENVELOPE_V1 = 0x01;
+const ALG_AES_256_GCM = 0x01;
+const GCM_NONCE_SIZE = 12;
+// El sıkışmada sunulan algoritmalar; tarayıcıda WebCrypto yalnızca AES-GCM destekler
+const SUPPORTED_ALGORITHMS = ["aes-256-gcm"];
+
+// AAD etiketi - backend/pkg/crypto/binding.go ile birebir aynı olmalı
+const BINDING_LABEL = "uctanuca/aad/v1";
+
+// Anahtar önbelleği (el sıkışma ile kurulan oturum anahtarları)
 const keyCache = new Map();
 
+// Sunucu saati ile yerel saat arasındaki fark (ms), X-Server-Time başlığından
+let serverClockOffset = 0;
+
+function bytesToBase64(bytes) {
+  return btoa(String.fromCharCode(...bytes));
+}
+
+function base64ToBytes(base64) {
+  const binaryString = atob(base64);
+  const bytes = new Uint8Array(binaryString.length);
+  for (let i = 0; i < binaryString.length; i++) {
+    bytes[i] = binaryString.charCodeAt(i);
+  }
+  return bytes;
+}
+
 /**
- * JWT Token ve Session ID kullanarak AES-256 anahtarı türetir
+ * Zarf başlığını üretir: version | algorithm | keyIdLen | keyId
  */
-async function deriveEncryptionKey(token, sessionId) {
+function envelopeHeader(algorithm, keyId) {
+  const keyIdBytes = new TextEncoder().encode(keyId || "");
+  if (keyIdBytes.length > 255) {
+    throw new Error("Anahtar kimliği çok uzun");
+  }
+  const header = new Uint8Array(3 + keyIdBytes.length);
+  header[0] = ENVELOPE_V1;
+  header[1] = algorithm;
+  header[2] = keyIdBytes.length;
+  header.set(keyIdBytes, 3);
+  return header;
+}
+
+/**
+ * Mesajı HTTP isteğine bağlayan AAD'yi üretir. Her alan 4 byte big-endian
+ * uzunluk ön ekiyle yazılır: label | direction | METHOD | path | sessionId
+ */
+export function bindingAAD(direction, { method, path }, sessionId) {
+  const encoder = new TextEncoder();
+  const fields = [
+    BINDING_LABEL,
+    direction,
+    (method || "").toUpperCase(),
+    path || "",
+    sessionId,
+  ].map((field) => encoder.encode(field));
+
+  const size = fields.reduce((total, field) => total + 4 + field.length, 0);
+  const out = new Uint8Array(size);
+  const view = new DataView(out.buffer);
+
+  let offset = 0;
+  for (const field of fields) {
+    view.setUint32(offset, field.length);
+    out.set(field, offset + 4);
+    offset += 4 + field.length;
+  }
+  return out;
+}
+
+function concatBytes(a, b) {
+  const out = new Uint8Array(a.length + b.length);
+  out.set(a);
+  out.set(b, a.length);
+  return out;
+}
+
+/**
+ * Oturum sırrından epoch anahtarını türetir:
+ * HKDF-SHA256(secret, salt yok, info = prefix|sessionId|epoch)
+ */
+export function deriveEpochKey(secret, sessionId, epoch) {
+  const info = new TextEncoder().encode(
+    `${EPOCH_INFO_PREFIX}|${sessionId}|${epoch}`
+  );
+  return hkdf(sha256, secret, undefined, info, 32);
+}
+
+/**
+ * Önbellekteki oturum için epoch anahtarını döndürür (anahtar kimliği: keyId.epoch)
+ */
+function epochKey(session, sessionId, epoch) {
+  let aesKey = session.epochKeys.get(epoch);
+  if (!aesKey) {
+    aesKey = deriveEpochKey(session.secret, sessionId, epoch);
+    session.epochKeys.set(epoch, aesKey);
+  }
+  return { aesKey, keyId: `${session.baseKeyId}.${epoch}` };
+}
+
+/**
+ * Oturumu verilen epoch'a geçirir; daha eski epoch'ların anahtarları atılır
+ */
+function adoptEpoch(session, epoch) {
+  if (epoch <= session.epoch) {
+    return;
+  }
+  session.epoch = epoch;
+  for (const known of session.epochKeys.keys()) {
+    if (known < epoch - 1) {
+      session.epochKeys.delete(known);
+    }
+  }
+}
+
+/**
+ * X25519 ortak sırrından oturum sırrını türetir:
+ * HKDF-SHA256(shared, salt = SHA-256(token), info = prefix|sessionId| + clientPub + serverPub)
+ */
+export function deriveHandshakeSecret(
+  sharedSecret,
+  token,
+  sessionId,
+  clientPublicKey,
+  serverPublicKey
+) {
+  const salt = sha256(new TextEncoder().encode(token));
+  const infoPrefix = new TextEncoder().encode(
+    `${HANDSHAKE_INFO_PREFIX}|${sessionId}|`
+  );
+  const info = new Uint8Array(
+    infoPrefix.length + clientPublicKey.length + serverPublicKey.length
+  );
+  info.set(infoPrefix);
+  info.set(clientPublicKey, infoPrefix.length);
+  info.set(serverPublicKey, infoPrefix.length + clientPublicKey.length);
+
+  return hkdf(sha256, sharedSecret, salt, info, 32);
+}
+
+/**
+ * Sunucuyla X25519 el sıkışması yapar ve oturum sırrını önbelleğe alır.
+ * sendHandshake(clientPublicKeyBase64, algorithms) sunucunun JSON yanıtını
+ * döndürmelidir; algorithms istekte "algorithms" alanı olarak gönderilir.
+ */
+export async function performHandshake(token, sessionId, sendHandshake) {
   if (!token || !sessionId) {
+    throw new Error("El sıkışma yapılamadı: Token veya Session ID eksik");
+  }
+
+  const clientPrivateKey = x25519.utils.randomPrivateKey();
+  const clientPublicKey = x25519.getPublicKey(clientPrivateKey);
+
+  const response = await sendHandshake(
+    bytesToBase64(clientPublicKey),
+    SUPPORTED_ALGORITHMS
+  );
+  // Algoritma bildirmeyen eski sunucular AES-256-GCM kullanır
+  if (
+    response.algorithm &&
+    !SUPPORTED_ALGORITHMS.includes(response.algorithm)
+  ) {
     throw new Error(
-      "Şifreleme anahtarı türetilemedi: Token veya Session ID eksik"
+      `El sıkışma başarısız: Desteklenmeyen algoritma ${response.algorithm}`
     );
   }
+  const serverPublicKey = base64ToBytes(response.server_public_key);
+
+  const sharedSecret = x25519.getSharedSecret(clientPrivateKey, serverPublicKey);
+
+  // Oturum sırrı epoch anahtarlarının köküdür; mesajlar epoch anahtarlarıyla şifrelenir
+  const secret = deriveHandshakeSecret(
+    sharedSecret,
+    token,
+    sessionId,
+    clientPublicKey,
+    serverPublicKey
+  );
 
-  const cacheKey = `${token}|${sessionId}`;
+  const result = {
+    secret,
+    baseKeyId: response.key_id,
+    epoch: response.epoch || 0,
+    epochKeys: new Map(),
+    // Bu el sıkışmayla gönderilen son sıra numarası (_seq)
+    sequence: 0,
+    derivedAt: Date.now(),
+    expiresAt: Date.now() + response.expires_in * 1000,
+  };
 
-  if (keyCache.has(cacheKey)) {
-    return keyCache.get(cacheKey);
+  keyCache.set(`${token}|${sessionId}`, result);
+  return result;
+}
+
+/**
+ * Sunucunun X-Key-Next-Epoch başlığıyla duyurduğu epoch'a geçer. Sonraki
+ * istekler yeni epoch anahtarıyla şifrelenir.
+ */
+export function advanceKeyEpoch(token, sessionId, epoch) {
+  const cached = keyCache.get(`${token}|${sessionId}`);
+  const next = Number.parseInt(epoch, 10);
+  if (cached && Number.isSafeInteger(next)) {
+    adoptEpoch(cached, next);
   }
+}
 
-  try {
-    const inputKeyMaterial = new TextEncoder().encode(token + sessionId);
-    const derivedKeyBytes = hkdf(
-      sha256,
-      inputKeyMaterial,
-      undefined,
-      undefined,
-      32
+/**
+ * Geçerli bir oturum anahtarı olup olmadığını kontrol eder
+ */
+export function hasSessionKey(token, sessionId) {
+  const cached = keyCache.get(`${token}|${sessionId}`);
+  return !!cached && cached.expiresAt > Date.now();
+}
+
+/**
+ * El sıkışma ile kurulmuş oturumu döndürür
+ */
+function getSession(token, sessionId) {
+  if (!token || !sessionId) {
+    throw new Error(
+      "Şifreleme anahtarı türetilemedi: Token veya Session ID eksik"
     );
+  }
 
-    const result = {
-      aesKey: derivedKeyBytes,
-      derivedAt: Date.now(),
-    };
+  const cached = keyCache.get(`${token}|${sessionId}`);
+  if (!cached || cached.expiresAt <= Date.now()) {
+    throw new Error("Oturum anahtarı yok: Önce el sıkışma yapılmalı");
+  }
 
-    // Basit bir önbellek temizleme mekanizması (Go'daki gibi gelişmiş değil ama işe yarar)
-    if (keyCache.size > 100) {
-      // En eski 50 kaydı sil
-      const keysToDelete = Array.from(keyCache.keys()).slice(0, 50);
-      keysToDelete.forEach((key) => keyCache.delete(key));
-    }
+  return cached;
+}
 
-    keyCache.set(cacheKey, result);
-    return result;
-  } catch (error) {
-    console.error("Anahtar türetme hatası:", error);
-    throw new Error("Şifreleme anahtarı oluşturulamadı");
+/**
+ * Geçerli epoch'un AES-256 anahtarını ve anahtar kimliğini döndürür
+ */
+async function deriveEncryptionKey(token, sessionId) {
+  const session = getSession(token, sessionId);
+  return epochKey(session, sessionId, session.epoch);
+}
+
+/**
+ * Sunucu yanıtındaki anahtar kimliğine göre çözme anahtarını döndürür. Sunucu
+ * rotasyonu zorladıysa yanıt daha yeni bir epoch'la gelir ve o epoch'a geçilir.
+ */
+function decryptionKey(token, sessionId, envelopeKeyId) {
+  const session = getSession(token, sessionId);
+  const prefix = `${session.baseKeyId}.`;
+  if (!envelopeKeyId.startsWith(prefix)) {
+    throw new Error("Anahtar kimliği eşleşmiyor");
+  }
+
+  const epoch = Number.parseInt(envelopeKeyId.slice(prefix.length), 10);
+  if (!Number.isSafeInteger(epoch) || `${prefix}${epoch}` !== envelopeKeyId) {
+    throw new Error("Anahtar kimliği eşleşmiyor");
   }
+
+  adoptEpoch(session, epoch);
+  return epochKey(session, sessionId, epoch);
+}
+
+/**
+ * Sunucunun X-Server-Time başlığından (Unix ms) saat farkını günceller.
+ * sentAt isteğin gönderildiği yerel zamandır; sunucu saati gidiş-dönüşün
+ * ortasına denk sayılır. Saati sapan cihazlarda _timestamp bu farkla üretilir.
+ */
+export function updateServerClock(serverTime, sentAt) {
+  const serverMs = Number.parseInt(serverTime, 10);
+  if (!Number.isSafeInteger(serverMs) || serverMs <= 0) {
+    return;
+  }
+
+  const receivedAt = Date.now();
+  const localMs = sentAt ? sentAt + (receivedAt - sentAt) / 2 : receivedAt;
+  serverClockOffset = Math.round(serverMs - localMs);
+}
+
+/**
+ * Sunucu saatine göre düzeltilmiş şimdiki zaman (ms)
+ */
+function serverNow() {
+  return Date.now() + serverClockOffset;
 }
 
 /**
- * Veriyi AES-GCM ile şifreler
+ * Düz metni v1 zarfı olarak şifreler: header | iv | ciphertext+tag. Başlık ve
+ * istek bağlamı ek doğrulanmış veri (AAD) olarak bağlanır.
  */
-export async function encryptData(data, token, sessionId) {
+export async function sealEnvelope(
+  aesKey,
+  keyId,
+  iv,
+  plaintext,
+  direction,
+  binding,
+  sessionId
+) {
+  const header = envelopeHeader(ALG_AES_256_GCM, keyId);
+
+  const cryptoKey = await crypto.subtle.importKey(
+    "raw",
+    aesKey,
+    { name: "AES-GCM" },
+    false,
+    ["encrypt"]
+  );
+
+  const additionalData = concatBytes(
+    header,
+    bindingAAD(direction, binding, sessionId)
+  );
+  const encryptedBuffer = await crypto.subtle.encrypt(
+    { name: "AES-GCM", iv: iv, additionalData },
+    cryptoKey,
+    plaintext
+  );
+
+  // Başlık, IV ve şifreli veriyi birleştir
+  const combined = new Uint8Array(
+    header.length + iv.length + encryptedBuffer.byteLength
+  );
+  combined.set(header);
+  combined.set(iv, header.length);
+  combined.set(new Uint8Array(encryptedBuffer), header.length + iv.length);
+  return combined;
+}
+
+/**
+ * v1 zarfını parçalarına ayırır: { header, keyId, iv, ciphertext }
+ */
+export function parseEnvelope(combined) {
+  if (combined.length < 3 || combined[0] !== ENVELOPE_V1) {
+    throw new Error("Desteklenmeyen zarf sürümü");
+  }
+  if (combined[1] !== ALG_AES_256_GCM) {
+    throw new Error("Desteklenmeyen algoritma");
+  }
+
+  const keyIdLength = combined[2];
+  const headerLength = 3 + keyIdLength;
+  if (combined.length < headerLength + GCM_NONCE_SIZE + 1) {
+    throw new Error("Şifreli veri çok kısa");
+  }
+
+  return {
+    header: combined.slice(0, headerLength),
+    keyId: new TextDecoder().decode(combined.slice(3, headerLength)),
+    iv: combined.slice(headerLength, headerLength + GCM_NONCE_SIZE),
+    ciphertext: combined.slice(headerLength + GCM_NONCE_SIZE),
+  };
+}
+
+/**
+ * parseEnvelope ile ayrılmış zarfı çözer ve düz metni döndürür
+ */
+export async function openEnvelope(
+  envelope,
+  aesKey,
+  direction,
+  binding,
+  sessionId
+) {
+  const cryptoKey = await crypto.subtle.importKey(
+    "raw",
+    aesKey,
+    { name: "AES-GCM" },
+    false,
+    ["decrypt"]
+  );
+
+  const decryptedBuffer = await crypto.subtle.decrypt(
+    {
+      name: "AES-GCM",
+      iv: envelope.iv,
+      additionalData: concatBytes(
+        envelope.header,
+        bindingAAD(direction, binding, sessionId)
+      ),
+    },
+    cryptoKey,
+    envelope.ciphertext
+  );
+  return new Uint8Array(decryptedBuffer);
+}
+
+/**
+ * Standart Base64'ü URL güvenli biçime (padding'siz) çevirir
+ */
+export function toUrlSafeBase64(standardBase64) {
+  return standardBase64
+    .replace(/\+/g, "-")
+    .replace(/\//g, "_")
+    .replace(/=/g, "");
+}
+
+/**
+ * URL güvenli Base64'ü standart Base64'e (padding'li) çevirir
+ */
+export function fromUrlSafeBase64(urlSafeBase64) {
+  return urlSafeBase64
+    .replace(/\-/g, "+")
+    .replace(/\_/g, "/")
+    .padEnd(
+      urlSafeBase64.length + ((4 - (urlSafeBase64.length % 4)) % 4),
+      "="
+    );
+}
+
+/**
+ * Veriyi AES-GCM ile şifreler. binding = { method, path } isteğin sunucuda
+ * görünen metodu ve yoludur (örn. { method: "PUT", path: "/api/data" }).
+ */
+export async function encryptData(data, token, sessionId, binding) {
   try {
-    const { aesKey } = await deriveEncryptionKey(token, sessionId);
+    const { aesKey, keyId } = await deriveEncryptionKey(token, sessionId);
+    const session = getSession(token, sessionId);
 
-    // Replay attack koruması için timestamp ve nonce ekle
+    // Replay attack koruması için timestamp, nonce ve sıra numarası ekle.
+    // Sunucu yapılandırmasına göre ya timestamp/nonce ya da _seq kontrol edilir.
     const payloadWithTimestamp = {
       ...data,
-      _timestamp: Date.now(),
+      _timestamp: serverNow(),
       _nonce: Array.from(randomBytes(8))
         .map((byte) => byte.toString(16).padStart(2, "0"))
         .join(""),
+      _seq: ++session.sequence,
     };
 
     const dataStr = JSON.stringify(payloadWithTimestamp);
     const dataBytes = new TextEncoder().encode(dataStr);
 
-    const iv = randomBytes(12);
-
-    const cryptoKey = await crypto.subtle.importKey(
-      "raw",
+    // Zarf başlığı ve istek bağlamı ek doğrulanmış veri (AAD) olarak bağlanır
+    const combined = await sealEnvelope(
       aesKey,
-      { name: "AES-GCM" },
-      false,
-      ["encrypt"]
-    );
-
-    const encryptedBuffer = await crypto.subtle.encrypt(
-      { name: "AES-GCM", iv: iv },
-      cryptoKey,
-      dataBytes
+      keyId,
+      randomBytes(GCM_NONCE_SIZE),
+      dataBytes,
+      "request",
+      binding,
+      sessionId
     );
 
-    // IV ve şifreli veriyi birleştir
-    const combined = new Uint8Array(iv.length + encryptedBuffer.byteLength);
-    combined.set(iv);
-    combined.set(new Uint8Array(encryptedBuffer), iv.length);
-
     // Base64 olarak dönüştür
-    return btoa(String.fromCharCode(...combined));
+    return bytesToBase64(combined);
   } catch (error) {
     console.error("Veri şifreleme hatası:", error);
     throw new Error("Veri şifrelenemedi: " + error.message);
@@ -100,37 +460,19 @@ export async function encryptData(data, token, sessionId) {
 }
 
 /**
- * Şifreli veriyi çözer
+ * Sunucu yanıtını çözer. binding = { method, path } yanıtın ait olduğu istektir.
  */
-export async function decryptData(encryptedBase64, token, sessionId) {
+export async function decryptData(encryptedBase64, token, sessionId, binding) {
   try {
-    const { aesKey } = await deriveEncryptionKey(token, sessionId);
-
-    const binaryString = atob(encryptedBase64);
-    const combined = new Uint8Array(binaryString.length);
-    for (let i = 0; i < binaryString.length; i++) {
-      combined[i] = binaryString.charCodeAt(i);
-    }
-
-    if (combined.length < 13) {
-      throw new Error("Şifreli veri çok kısa");
-    }
+    const envelope = parseEnvelope(base64ToBytes(encryptedBase64));
+    const { aesKey } = decryptionKey(token, sessionId, envelope.keyId);
 
-    const iv = combined.slice(0, 12);
-    const ciphertext = combined.slice(12);
-
-    const cryptoKey = await crypto.subtle.importKey(
-      "raw",
+    const decryptedBuffer = await openEnvelope(
+      envelope,
       aesKey,
-      { name: "AES-GCM" },
-      false,
-      ["decrypt"]
-    );
-
-    const decryptedBuffer = await crypto.subtle.decrypt(
-      { name: "AES-GCM", iv: iv },
-      cryptoKey,
-      ciphertext
+      "response",
+      binding,
+      sessionId
     );
 
     const decryptedStr = new TextDecoder().decode(decryptedBuffer);
@@ -142,15 +484,41 @@ export async function decryptData(encryptedBase64, token, sessionId) {
   }
 }
 
+/**
+ * Çözülmüş yanıtı orijinal biçimine döndürür. Sunucu JSON nesnesi olmayan
+ * yanıtları (dizi, skaler, dosya) { _content_type, _body | _body_base64 }
+ * olarak sarmalar; ikili içerik Blob olarak döner.
+ */
+export function unwrapResponse(decrypted) {
+  if (
+    decrypted === null ||
+    typeof decrypted !== "object" ||
+    Array.isArray(decrypted) ||
+    !("_content_type" in decrypted)
+  ) {
+    return decrypted;
+  }
+
+  if ("_body" in decrypted) {
+    return decrypted._body;
+  }
+
+  const bytes = base64ToBytes(decrypted._body_base64 || "");
+  if (decrypted._content_type.startsWith("text/")) {
+    return new TextDecoder().decode(bytes);
+  }
+  return new Blob([bytes], { type: decrypted._content_type });
+}
+
 /**
  * Query parametrelerini şifreler
  */
-export async function encryptQueryParams(params, token, sessionId) {
+export async function encryptQueryParams(params, token, sessionId, binding) {
   try {
     // Query parametrelerine de timestamp ve nonce ekle
     const paramsWithTimestamp = {
       ...params,
-      _timestamp: Date.now(),
+      _timestamp: serverNow(),
       _nonce: Array.from(randomBytes(4))
         .map((byte) => byte.toString(16).padStart(2, "0"))
         .join(""),
@@ -159,16 +527,12 @@ export async function encryptQueryParams(params, token, sessionId) {
     const encryptedData = await encryptData(
       paramsWithTimestamp,
       token,
-      sessionId
+      sessionId,
+      binding
     );
 
     // URL güvenli Base64 formatına çevir
-    const urlSafeBase64 = encryptedData
-      .replace(/\+/g, "-")
-      .replace(/\//g, "_")
-      .replace(/=/g, "");
-
-    return urlSafeBase64;
+    return toUrlSafeBase64(encryptedData);
   } catch (error) {
     console.error("Query parametre şifreleme hatası:", error);
     throw new Error("Query parametreleri şifrelenemedi: " + error.message);
@@ -178,20 +542,19 @@ export async function encryptQueryParams(params, token, sessionId) {
 /**
  * Şifrelenmiş query parametrelerini çözer
  */
-export async function decryptQueryParams(encryptedQuery, token, sessionId) {
+export async function decryptQueryParams(
+  encryptedQuery,
+  token,
+  sessionId,
+  binding
+) {
   try {
     // URL güvenli Base64'ten standart Base64'e çevir
-    const standardBase64 = encryptedQuery
-      .replace(/\-/g, "+")
-      .replace(/\_/g, "/")
-      .padEnd(
-        encryptedQuery.length + ((4 - (encryptedQuery.length % 4)) % 4),
-        "="
-      );
+    const standardBase64 = fromUrlSafeBase64(encryptedQuery);
 
     // Not: Frontend, query yanıtını çözerken timestamp/nonce kontrolü yapmaz.
     // Bu kontrol, Backend'deki Request Body/Query şifre çözme aşamasında yapılır.
-    return await decryptData(standardBase64, token, sessionId);
+    return await decryptData(standardBase64, token, sessionId, binding);
   } catch (error) {
     console.error("Query parametre çözme hatası:", error);
     throw new Error("Query parametreleri çözülemedi: " + error.message);
@@ -0,0 +1,123 @@
+// Tarayıcı istemcisini backend ile paylaşılan test vektörlerine karşı doğrular:
+// backend/pkg/crypto/testdata/vectors.json (memory-bank/wireFormat.md)
+//
+//	npm test
+import { test } from "node:test";
+import assert from "node:assert/strict";
+import { readFileSync } from "node:fs";
+import { x25519 } from "@noble/curves/ed25519";
+import {
+  bindingAAD,
+  deriveEpochKey,
+  deriveHandshakeSecret,
+  fromUrlSafeBase64,
+  openEnvelope,
+  parseEnvelope,
+  sealEnvelope,
+  toUrlSafeBase64,
+} from "./crypto.js";
+
+const vectors = JSON.parse(
+  readFileSync(
+    new URL("../../../backend/pkg/crypto/testdata/vectors.json", import.meta.url)
+  )
+);
+
+const hex = (value) => new Uint8Array(Buffer.from(value, "hex"));
+const toHex = (bytes) => Buffer.from(bytes).toString("hex");
+const base64 = (value) => new Uint8Array(Buffer.from(value, "base64"));
+const toBase64 = (bytes) => Buffer.from(bytes).toString("base64");
+
+test("el sıkışma ve epoch anahtarları (HKDF)", () => {
+  for (const v of vectors.handshake) {
+    const clientPrivateKey = hex(v.client_private_key);
+    const clientPublicKey = x25519.getPublicKey(clientPrivateKey);
+    const serverPublicKey = base64(v.server_public_key);
+    assert.equal(toBase64(clientPublicKey), v.client_public_key);
+
+    const shared = x25519.getSharedSecret(clientPrivateKey, serverPublicKey);
+    assert.equal(toHex(shared), v.shared_secret);
+
+    const secret = deriveHandshakeSecret(
+      shared,
+      v.token,
+      v.session_id,
+      clientPublicKey,
+      serverPublicKey
+    );
+    assert.equal(toHex(secret), v.secret);
+
+    for (const e of v.epochs) {
+      assert.equal(`${v.key_id}.${e.epoch}`, e.key_id);
+      assert.equal(
+        toHex(deriveEpochKey(secret, v.session_id, e.epoch)),
+        e.key,
+        `epoch ${e.epoch}`
+      );
+    }
+  }
+});
+
+test("bağlam AAD", () => {
+  for (const v of vectors.bindings) {
+    const aad = bindingAAD(
+      v.direction,
+      { method: v.method, path: v.path },
+      v.session_id
+    );
+    assert.equal(toHex(aad), v.aad, `${v.direction} ${v.method} ${v.path}`);
+  }
+});
+
+test("zarflar ve query biçimi", async () => {
+  // Tarayıcı eski (sürümsüz) formatı üretmez ve çözmez
+  for (const v of vectors.envelopes.filter((e) => e.format === "v1")) {
+    const binding = { method: v.method, path: v.path };
+    const plaintext = new TextEncoder().encode(v.plaintext);
+
+    const sealed = await sealEnvelope(
+      hex(v.key),
+      v.key_id,
+      hex(v.nonce),
+      plaintext,
+      v.direction,
+      binding,
+      v.session_id
+    );
+    assert.equal(toBase64(sealed), v.envelope, v.name);
+
+    const envelope = parseEnvelope(base64(v.envelope));
+    assert.equal(envelope.keyId, v.key_id, v.name);
+    const opened = await openEnvelope(
+      envelope,
+      hex(v.key),
+      v.direction,
+      binding,
+      v.session_id
+    );
+    assert.equal(new TextDecoder().decode(opened), v.plaintext, v.name);
+
+    assert.equal(toUrlSafeBase64(v.envelope), v.query, v.name);
+    assert.equal(fromUrlSafeBase64(v.query), v.envelope, v.name);
+
+    // Başka bir isteğe bağlanan zarf çözülemez
+    await assert.rejects(
+      openEnvelope(
+        envelope,
+        hex(v.key),
+        v.direction,
+        { ...binding, path: `${v.path}/x` },
+        v.session_id
+      ),
+      v.name
+    );
+  }
+});
+
+test("URL güvenli base64", () => {
+  for (const v of vectors.url_safe_base64) {
+    assert.equal(toBase64(hex(v.bytes)), v.standard);
+    assert.equal(toUrlSafeBase64(v.standard), v.url_safe);
+    assert.equal(fromUrlSafeBase64(v.url_safe), v.standard);
+  }
+});
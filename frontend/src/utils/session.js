@@ -1,29 +1,30 @@
-import { randomBytes } from "@noble/hashes/utils";
+const SESSION_STORAGE_KEY = "secure_session_id";
 
 /**
- * Tarayıcı oturumu boyunca geçerli benzersiz bir ID oluşturur
+ * Sunucunun verdiği oturum ID'sini döndürür; henüz oturum yoksa null
  */
 export function getSessionId() {
-  let sessionId = sessionStorage.getItem("secure_session_id");
+  return sessionStorage.getItem(SESSION_STORAGE_KEY);
+}
 
-  if (!sessionId) {
-    try {
-      // 16 byte (128 bit) kriptografik rastgele değer üret
-      const bytes = randomBytes(16);
-      sessionId = Array.from(bytes)
-        .map((byte) => byte.toString(16).padStart(2, "0"))
-        .join("");
+/**
+ * Oturum yoksa sunucudan yeni bir oturum alır. Oturum ID'si sunucuda
+ * rastgele üretilir ve token'ın kullanıcısına bağlanır (POST /api/session).
+ * createSession: () => Promise<{ session_id }>
+ */
+export async function ensureSessionId(createSession) {
+  const existing = getSessionId();
+  if (existing) {
+    return existing;
+  }
 
-      sessionStorage.setItem("secure_session_id", sessionId);
-      console.log("Yeni oturum IDsi oluşturuldu:", sessionId);
-    } catch (error) {
-      console.error("Oturum IDsi oluşturulamadı:", error);
-      sessionId =
-        Date.now().toString(36) + Math.random().toString(36).substring(2, 9);
-      sessionStorage.setItem("secure_session_id", sessionId);
-    }
+  const { session_id: sessionId } = await createSession();
+  if (!sessionId) {
+    throw new Error("Sunucu oturum IDsi döndürmedi");
   }
 
+  sessionStorage.setItem(SESSION_STORAGE_KEY, sessionId);
+  console.log("Yeni oturum açıldı:", sessionId);
   return sessionId;
 }
 
@@ -31,5 +32,5 @@ export function getSessionId() {
  * Mevcut oturum ID'sini temizler
  */
 export function clearSessionId() {
-  sessionStorage.removeItem("secure_session_id");
+  sessionStorage.removeItem(SESSION_STORAGE_KEY);
 }